@@ -13,6 +13,7 @@ import (
 	"github.com/otaviocarvalho/tramuntana/hook"
 	"github.com/otaviocarvalho/tramuntana/internal/bot"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
@@ -54,7 +55,7 @@ func main() {
 
 	hookCmd := &cobra.Command{
 		Use:   "hook",
-		Short: "Run the Claude Code SessionStart hook",
+		Short: "Run the Claude Code hooks (SessionStart, PreToolUse)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if installHook {
 				return hook.Install()
@@ -95,6 +96,15 @@ func runServe() error {
 	}
 	b.SetMonitorState(ms)
 
+	// Load token usage totals
+	usagePath := filepath.Join(cfg.TramuntanaDir, "usage.json")
+	usage, err := state.LoadUsageState(usagePath)
+	if err != nil {
+		log.Printf("Warning: loading usage state: %v (starting fresh)", err)
+		usage = state.NewUsageState()
+	}
+	b.SetUsageState(usage)
+
 	// Startup recovery: reconcile state with live tmux windows
 	liveBindings := b.ReconcileState()
 	log.Printf("Startup: %d live bindings recovered", liveBindings)
@@ -106,9 +116,13 @@ func runServe() error {
 	// Create session monitor
 	mon := monitor.New(cfg, b.State(), ms, q)
 	mon.PlanHandler = b.HandlePlanFromMonitor
+	mon.QuestionHandler = b.HandleQuestionFromMonitor
+	mon.FormatFor = b.TranscriptFormatFor
+	mon.Usage = usage
 
 	// Create status poller
 	sp := bot.NewStatusPoller(b, q, mon)
+	b.SetStatusPoller(sp)
 
 	// Context for graceful shutdown
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -120,6 +134,20 @@ func runServe() error {
 	// Start status poller in background
 	go sp.Run(ctx)
 
+	// Start scheduler for /c_schedule prompts
+	go bot.NewScheduler(b).Run(ctx)
+
+	// Relaunch sessions that died while serve was down, without holding up startup
+	go b.ResumeDeadWindows()
+
+	// Listen for hook events (permission requests, turn ends, notifications)
+	hookSrv, err := hooksock.Listen(hooksock.SocketPath(cfg.TramuntanaDir), b.HandleHookRequest)
+	if err != nil {
+		log.Printf("Warning: hook socket disabled: %v", err)
+	} else {
+		go hookSrv.Serve(ctx)
+	}
+
 	// Run bot (blocks until ctx is cancelled)
 	err = b.Run(ctx)
 
@@ -128,6 +156,9 @@ func runServe() error {
 	if err := ms.ForceSave(msPath); err != nil {
 		log.Printf("Error saving monitor state: %v", err)
 	}
+	if err := usage.ForceSave(usagePath); err != nil {
+		log.Printf("Error saving usage state: %v", err)
+	}
 
 	return err
 }
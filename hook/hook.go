@@ -3,26 +3,35 @@ package hook
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 // hookInput is the JSON structure read from stdin by the hook.
 type hookInput struct {
-	SessionID     string `json:"session_id"`
-	CWD           string `json:"cwd"`
-	HookEventName string `json:"hook_event_name"`
+	SessionID      string          `json:"session_id"`
+	CWD            string          `json:"cwd"`
+	HookEventName  string          `json:"hook_event_name"`
+	PermissionMode string          `json:"permission_mode"`
+	ToolName       string          `json:"tool_name"`
+	ToolInput      json.RawMessage `json:"tool_input"`
+	ToolUseID      string          `json:"tool_use_id"`
+
+	// Notification
+	Message          string `json:"message"`
+	NotificationType string `json:"notification_type"`
 }
 
 var uuidRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
-// Run executes the SessionStart hook logic:
-// reads stdin JSON, gets tmux pane info, writes to session_map.json.
+// Run executes the hook for the event named on stdin.
 // Does NOT import config package — uses TRAMUNTANA_DIR env or ~/.tramuntana.
 func Run() error {
 	var input hookInput
@@ -30,10 +39,19 @@ func Run() error {
 		return fmt.Errorf("reading stdin JSON: %w", err)
 	}
 
-	if input.HookEventName != "SessionStart" {
-		return nil // ignore non-SessionStart hooks
+	switch input.HookEventName {
+	case "SessionStart":
+		return runSessionStart(input)
+	case "PreToolUse":
+		return runPreToolUse(input, os.Stdout)
+	case "Stop", "SubagentStop", "Notification":
+		return runForward(input)
 	}
+	return nil // ignore other hooks
+}
 
+// runSessionStart gets tmux pane info and writes it to session_map.json.
+func runSessionStart(input hookInput) error {
 	if !uuidRegex.MatchString(input.SessionID) {
 		return fmt.Errorf("invalid session_id: %q", input.SessionID)
 	}
@@ -46,30 +64,14 @@ func Run() error {
 		return nil // not in tmux, exit silently
 	}
 
-	// Get session_name:window_id:window_name from tmux
-	info, err := tmux.DisplayMessage(paneID, "#{session_name}:#{window_id}:#{window_name}")
+	key, windowName, err := tmuxWindow(paneID)
 	if err != nil {
-		return fmt.Errorf("getting tmux info: %w", err)
-	}
-
-	parts := strings.SplitN(info, ":", 3)
-	if len(parts) < 3 {
-		return fmt.Errorf("unexpected tmux display-message output: %q", info)
+		return err
 	}
 
-	sessionName := parts[0]
-	windowID := parts[1]
-	windowName := parts[2]
-	key := sessionName + ":" + windowID
-
-	// Resolve tramuntana dir
-	dir := os.Getenv("TRAMUNTANA_DIR")
-	if dir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("getting home dir: %w", err)
-		}
-		dir = filepath.Join(home, ".tramuntana")
+	dir, err := tramuntanaDir()
+	if err != nil {
+		return err
 	}
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("creating tramuntana dir: %w", err)
@@ -86,6 +88,95 @@ func Run() error {
 	})
 }
 
+// runPreToolUse asks the serve process to approve a tool call and blocks until
+// it answers. Calls that Claude Code's own allow or deny rules settle aren't
+// asked about. If serve isn't running or has no opinion, nothing is written
+// and Claude Code falls back to its own permission prompt.
+func runPreToolUse(input hookInput, out io.Writer) error {
+	home, _ := os.UserHomeDir()
+	if claudeDecides(input, home) {
+		return nil
+	}
+
+	dir, err := tramuntanaDir()
+	if err != nil {
+		return err
+	}
+
+	resp, err := hooksock.Send(hooksock.SocketPath(dir), newRequest(input))
+	if err != nil || resp.Decision == "" {
+		return nil
+	}
+
+	return json.NewEncoder(out).Encode(map[string]any{
+		"hookSpecificOutput": map[string]any{
+			"hookEventName":            "PreToolUse",
+			"permissionDecision":       resp.Decision,
+			"permissionDecisionReason": resp.Reason,
+		},
+	})
+}
+
+// runForward passes a turn or notification event to the serve process. Nothing
+// is written to stdout, so Claude Code carries on as if the hook weren't there;
+// if serve isn't running the event is dropped.
+func runForward(input hookInput) error {
+	dir, err := tramuntanaDir()
+	if err != nil {
+		return err
+	}
+	hooksock.Send(hooksock.SocketPath(dir), newRequest(input))
+	return nil
+}
+
+// newRequest builds the socket request for a hook input, identifying the tmux
+// window when the hook runs inside one.
+func newRequest(input hookInput) hooksock.Request {
+	req := hooksock.Request{
+		Event:            input.HookEventName,
+		SessionID:        input.SessionID,
+		CWD:              input.CWD,
+		PermissionMode:   input.PermissionMode,
+		ToolName:         input.ToolName,
+		ToolInput:        input.ToolInput,
+		ToolUseID:        input.ToolUseID,
+		Message:          input.Message,
+		NotificationType: input.NotificationType,
+	}
+	if paneID := os.Getenv("TMUX_PANE"); paneID != "" {
+		if key, _, err := tmuxWindow(paneID); err == nil {
+			req.Window = key
+		}
+	}
+	return req
+}
+
+// tmuxWindow returns the "session_name:window_id" key and window name for a pane.
+func tmuxWindow(paneID string) (key, windowName string, err error) {
+	info, err := tmux.DisplayMessage(paneID, "#{session_name}:#{window_id}:#{window_name}")
+	if err != nil {
+		return "", "", fmt.Errorf("getting tmux info: %w", err)
+	}
+
+	parts := strings.SplitN(info, ":", 3)
+	if len(parts) < 3 {
+		return "", "", fmt.Errorf("unexpected tmux display-message output: %q", info)
+	}
+	return parts[0] + ":" + parts[1], parts[2], nil
+}
+
+// tramuntanaDir resolves TRAMUNTANA_DIR, defaulting to ~/.tramuntana.
+func tramuntanaDir() (string, error) {
+	if dir := os.Getenv("TRAMUNTANA_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return filepath.Join(home, ".tramuntana"), nil
+}
+
 // Install adds the tramuntana hook to ~/.claude/settings.json.
 func Install() error {
 	exePath, err := os.Executable()
@@ -122,28 +213,25 @@ func Install() error {
 
 	hookCommand := exePath + " hook"
 
-	// Check if already installed
-	if isHookInstalled(settings, hookCommand) {
-		fmt.Println("Hook already installed.")
-		return nil
-	}
-
-	// Add hook entry
 	hooks, _ := settings["hooks"].(map[string]any)
 	if hooks == nil {
 		hooks = make(map[string]any)
 	}
 
-	sessionStart, _ := hooks["SessionStart"].([]any)
-
-	hookEntry := map[string]any{
-		"type":    "command",
-		"command": hookCommand,
-		"timeout": 5,
+	// Add entries for events not yet registered (older installs may lack some)
+	added := 0
+	for _, ev := range hookEvents {
+		if isHookInstalled(settings, ev.Name) {
+			continue
+		}
+		entries, _ := hooks[ev.Name].([]any)
+		hooks[ev.Name] = append(entries, ev.entry(hookCommand))
+		added++
+	}
+	if added == 0 {
+		fmt.Println("Hook already installed.")
+		return nil
 	}
-
-	sessionStart = append(sessionStart, hookEntry)
-	hooks["SessionStart"] = sessionStart
 	settings["hooks"] = hooks
 
 	// Write back atomically
@@ -160,22 +248,70 @@ func Install() error {
 	return nil
 }
 
-// isHookInstalled checks if a hook with the given command is already present.
-func isHookInstalled(settings map[string]any, command string) bool {
+// hookEvent describes a Claude Code hook event tramuntana registers for.
+type hookEvent struct {
+	Name    string
+	Matcher string // tool matcher; empty for events that don't take one
+	Timeout int    // seconds
+}
+
+// hookEvents are the events registered by Install.
+var hookEvents = []hookEvent{
+	{Name: "SessionStart", Timeout: 5},
+	{Name: "PreToolUse", Matcher: permissionMatcher, Timeout: hooksock.PermissionHookTimeout},
+	{Name: "Stop", Timeout: 5},
+	{Name: "SubagentStop", Timeout: 5},
+	{Name: "Notification", Timeout: 5},
+}
+
+// entry builds the settings entry for the event: a flat command entry, or a
+// matcher group for tool events.
+func (ev hookEvent) entry(command string) map[string]any {
+	cmd := map[string]any{
+		"type":    "command",
+		"command": command,
+		"timeout": ev.Timeout,
+	}
+	if ev.Matcher == "" {
+		return cmd
+	}
+	return map[string]any{
+		"matcher": ev.Matcher,
+		"hooks":   []any{cmd},
+	}
+}
+
+// permissionMatcher selects the tools whose calls are sent to Telegram for
+// approval. Read-only tools never prompt in Claude Code, so they are left out.
+const permissionMatcher = "Bash|Edit|MultiEdit|Write|NotebookEdit|WebFetch|mcp__.*"
+
+// isHookInstalled checks if the tramuntana hook is registered for an event,
+// either as a flat entry or inside a matcher group's "hooks" list.
+func isHookInstalled(settings map[string]any, event string) bool {
 	hooks, _ := settings["hooks"].(map[string]any)
 	if hooks == nil {
 		return false
 	}
-	sessionStart, _ := hooks["SessionStart"].([]any)
-	for _, entry := range sessionStart {
+	entries, _ := hooks[event].([]any)
+	for _, entry := range entries {
 		m, _ := entry.(map[string]any)
 		if m == nil {
 			continue
 		}
-		cmd, _ := m["command"].(string)
-		if strings.Contains(cmd, "tramuntana hook") {
+		if isTramuntanaCommand(m) {
 			return true
 		}
+		nested, _ := m["hooks"].([]any)
+		for _, n := range nested {
+			if nm, _ := n.(map[string]any); nm != nil && isTramuntanaCommand(nm) {
+				return true
+			}
+		}
 	}
 	return false
 }
+
+func isTramuntanaCommand(entry map[string]any) bool {
+	cmd, _ := entry["command"].(string)
+	return strings.Contains(cmd, "tramuntana hook")
+}
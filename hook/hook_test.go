@@ -1,23 +1,27 @@
 package hook
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
 )
 
 func TestIsHookInstalled(t *testing.T) {
 	tests := []struct {
 		name     string
 		settings map[string]any
-		command  string
+		event    string
 		want     bool
 	}{
 		{
 			name:     "empty settings",
 			settings: map[string]any{},
-			command:  "/usr/bin/tramuntana hook",
+			event:    "SessionStart",
 			want:     false,
 		},
 		{
@@ -33,8 +37,8 @@ func TestIsHookInstalled(t *testing.T) {
 					},
 				},
 			},
-			command: "/usr/bin/tramuntana hook",
-			want:    true,
+			event: "SessionStart",
+			want:  true,
 		},
 		{
 			name: "different hook",
@@ -48,14 +52,49 @@ func TestIsHookInstalled(t *testing.T) {
 					},
 				},
 			},
-			command: "/usr/bin/tramuntana hook",
-			want:    false,
+			event: "SessionStart",
+			want:  false,
+		},
+		{
+			name: "matcher group",
+			settings: map[string]any{
+				"hooks": map[string]any{
+					"PreToolUse": []any{
+						map[string]any{
+							"matcher": "Bash",
+							"hooks": []any{
+								map[string]any{
+									"type":    "command",
+									"command": "/usr/bin/tramuntana hook",
+								},
+							},
+						},
+					},
+				},
+			},
+			event: "PreToolUse",
+			want:  true,
+		},
+		{
+			name: "other event only",
+			settings: map[string]any{
+				"hooks": map[string]any{
+					"SessionStart": []any{
+						map[string]any{
+							"type":    "command",
+							"command": "/usr/bin/tramuntana hook",
+						},
+					},
+				},
+			},
+			event: "PreToolUse",
+			want:  false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := isHookInstalled(tt.settings, tt.command)
+			got := isHookInstalled(tt.settings, tt.event)
 			if got != tt.want {
 				t.Errorf("isHookInstalled = %v, want %v", got, tt.want)
 			}
@@ -86,7 +125,7 @@ func TestInstall_CreatesSettingsFile(t *testing.T) {
 	hookCommand := fakeExe + " hook"
 
 	// Verify not installed
-	if isHookInstalled(settings, hookCommand) {
+	if isHookInstalled(settings, "SessionStart") {
 		t.Error("should not be installed initially")
 	}
 
@@ -107,7 +146,7 @@ func TestInstall_CreatesSettingsFile(t *testing.T) {
 	data, _ := os.ReadFile(settingsPath)
 	var loaded map[string]any
 	json.Unmarshal(data, &loaded)
-	if !isHookInstalled(loaded, hookCommand) {
+	if !isHookInstalled(loaded, "SessionStart") {
 		t.Error("should be installed after adding")
 	}
 }
@@ -134,3 +173,86 @@ func TestUUIDRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestRunPreToolUse(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TRAMUNTANA_DIR", dir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "")
+
+	var got hooksock.Request
+	srv, err := hooksock.Listen(hooksock.SocketPath(dir), func(req hooksock.Request) hooksock.Response {
+		got = req
+		return hooksock.Response{Decision: hooksock.DecisionAllow, Reason: "Approved from Telegram"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx)
+
+	input := hookInput{
+		SessionID:     "550e8400-e29b-41d4-a716-446655440000",
+		HookEventName: "PreToolUse",
+		ToolName:      "Bash",
+		ToolInput:     json.RawMessage(`{"command":"make test"}`),
+	}
+	var out bytes.Buffer
+	if err := runPreToolUse(input, &out); err != nil {
+		t.Fatalf("runPreToolUse: %v", err)
+	}
+
+	if got.ToolName != "Bash" || got.SessionID != input.SessionID {
+		t.Errorf("server got %+v", got)
+	}
+	var decoded struct {
+		HookSpecificOutput struct {
+			HookEventName      string `json:"hookEventName"`
+			PermissionDecision string `json:"permissionDecision"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("output not JSON: %q", out.String())
+	}
+	if decoded.HookSpecificOutput.HookEventName != "PreToolUse" || decoded.HookSpecificOutput.PermissionDecision != "allow" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestRunPreToolUse_NoServer(t *testing.T) {
+	t.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX_PANE", "")
+
+	var out bytes.Buffer
+	if err := runPreToolUse(hookInput{HookEventName: "PreToolUse", ToolName: "Bash"}, &out); err != nil {
+		t.Fatalf("runPreToolUse: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output without serve, got %q", out.String())
+	}
+}
+
+func TestHookEventEntries(t *testing.T) {
+	settings := map[string]any{"hooks": map[string]any{}}
+	hooks := settings["hooks"].(map[string]any)
+	for _, ev := range hookEvents {
+		hooks[ev.Name] = []any{ev.entry("/usr/bin/tramuntana hook")}
+	}
+
+	for _, ev := range hookEvents {
+		if !isHookInstalled(settings, ev.Name) {
+			t.Errorf("%s entry not recognised as installed", ev.Name)
+		}
+	}
+
+	pre := hooks["PreToolUse"].([]any)[0].(map[string]any)
+	if pre["matcher"] != permissionMatcher {
+		t.Errorf("PreToolUse should use a matcher group, got %v", pre)
+	}
+	stop := hooks["Stop"].([]any)[0].(map[string]any)
+	if _, ok := stop["matcher"]; ok || stop["command"] == nil {
+		t.Errorf("Stop should be a flat command entry, got %v", stop)
+	}
+}
@@ -0,0 +1,164 @@
+package hook
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// claudePermissions is the "permissions" block of Claude Code's settings.
+type claudePermissions struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+	Ask   []string `json:"ask"`
+}
+
+// settingsPermissions merges the permission rules of the user settings and
+// the project's shared and local settings. Unreadable files are skipped.
+func settingsPermissions(home, cwd string) claudePermissions {
+	var paths []string
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".claude", "settings.json"))
+	}
+	if cwd != "" {
+		paths = append(paths,
+			filepath.Join(cwd, ".claude", "settings.json"),
+			filepath.Join(cwd, ".claude", "settings.local.json"))
+	}
+
+	var merged claudePermissions
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var settings struct {
+			Permissions claudePermissions `json:"permissions"`
+		}
+		if json.Unmarshal(data, &settings) != nil {
+			continue
+		}
+		merged.Allow = append(merged.Allow, settings.Permissions.Allow...)
+		merged.Deny = append(merged.Deny, settings.Permissions.Deny...)
+		merged.Ask = append(merged.Ask, settings.Permissions.Ask...)
+	}
+	return merged
+}
+
+// claudeDecides reports whether Claude Code settles a tool call from its own
+// allow or deny rules, without prompting. Such calls aren't sent to Telegram.
+// An "ask" rule always prompts, so it wins over the other two.
+func claudeDecides(input hookInput, home string) bool {
+	perms := settingsPermissions(home, input.CWD)
+	for _, rule := range perms.Ask {
+		if permissionRuleMatches(rule, input) {
+			return false
+		}
+	}
+	for _, rule := range append(perms.Deny, perms.Allow...) {
+		if permissionRuleMatches(rule, input) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileTools are covered by Claude Code's Edit(...) rules.
+var fileTools = map[string]bool{"Edit": true, "MultiEdit": true, "Write": true, "NotebookEdit": true}
+
+// permissionRuleMatches reports whether a Claude Code permission rule such as
+// "Bash(npm run test:*)", "Edit(src/**)", "WebFetch(domain:go.dev)" or
+// "mcp__github" covers a tool call.
+func permissionRuleMatches(rule string, input hookInput) bool {
+	tool, specifier, hasSpecifier := strings.Cut(rule, "(")
+	specifier, closed := strings.CutSuffix(specifier, ")")
+	if hasSpecifier && !closed {
+		return false
+	}
+
+	if strings.HasPrefix(tool, "mcp__") {
+		server := strings.TrimSuffix(tool, "__*")
+		return !hasSpecifier && (input.ToolName == tool || strings.HasPrefix(input.ToolName, server+"__"))
+	}
+	if tool != input.ToolName && !(tool == "Edit" && fileTools[input.ToolName]) {
+		return false
+	}
+	if !hasSpecifier || specifier == "" || specifier == "*" {
+		return true
+	}
+
+	var toolInput map[string]any
+	json.Unmarshal(input.ToolInput, &toolInput)
+	str := func(key string) string {
+		s, _ := toolInput[key].(string)
+		return s
+	}
+
+	switch input.ToolName {
+	case "Bash":
+		// Claude Code checks each part of a compound command against its
+		// rules; that isn't reproduced here, so such commands go to Telegram
+		command := strings.TrimSpace(str("command"))
+		if state.IsCompoundCommand(command) {
+			return false
+		}
+		if prefix, ok := strings.CutSuffix(specifier, ":*"); ok {
+			return command == prefix || strings.HasPrefix(command, prefix+" ")
+		}
+		return wildcardRegexp(specifier, false).MatchString(command)
+	case "WebFetch":
+		domain, ok := strings.CutPrefix(specifier, "domain:")
+		u, err := url.Parse(str("url"))
+		if !ok || err != nil {
+			return false
+		}
+		host := u.Hostname()
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	default:
+		path := str("file_path")
+		if path == "" {
+			path = str("notebook_path")
+		}
+		return path != "" && wildcardRegexp(resolveRulePath(specifier, input.CWD), true).MatchString(filepath.Clean(path))
+	}
+}
+
+// resolveRulePath makes a file rule's pattern absolute: "//abs" and "~/x" as
+// in Claude Code, anything else relative to the session's directory.
+func resolveRulePath(pattern, cwd string) string {
+	switch {
+	case strings.HasPrefix(pattern, "//"):
+		return pattern[1:]
+	case strings.HasPrefix(pattern, "~/"):
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, pattern[2:])
+	}
+	return filepath.Join(cwd, pattern)
+}
+
+// wildcardRegexp compiles a rule pattern where * matches any run of
+// characters. For paths, * stops at a slash and ** doesn't.
+func wildcardRegexp(pattern string, path bool) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case pattern[i] == '*' && path:
+			sb.WriteString("[^/]*")
+		case pattern[i] == '*':
+			sb.WriteString(".*")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,125 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+)
+
+func TestPermissionRuleMatches(t *testing.T) {
+	bash := func(cmd string) hookInput {
+		in, _ := json.Marshal(map[string]string{"command": cmd})
+		return hookInput{ToolName: "Bash", ToolInput: in, CWD: "/work/proj"}
+	}
+	file := func(tool, path string) hookInput {
+		in, _ := json.Marshal(map[string]string{"file_path": path})
+		return hookInput{ToolName: tool, ToolInput: in, CWD: "/work/proj"}
+	}
+	fetch := func(u string) hookInput {
+		in, _ := json.Marshal(map[string]string{"url": u})
+		return hookInput{ToolName: "WebFetch", ToolInput: in}
+	}
+
+	tests := []struct {
+		rule  string
+		input hookInput
+		want  bool
+	}{
+		{"Bash", bash("rm -rf build"), true},
+		{"Bash(git status)", bash("git status"), true},
+		{"Bash(git status)", bash("git status --short"), false},
+		{"Bash(npm run test:*)", bash("npm run test"), true},
+		{"Bash(npm run test:*)", bash("npm run test -- --watch"), true},
+		{"Bash(npm run test:*)", bash("npm run testing"), false},
+		{"Bash(npm run test:*)", bash("npm run test && rm -rf ~"), false},
+		{"Bash(go test *)", bash("go test ./..."), true},
+		{"Bash(go test *)", bash("go test ./... | tee log"), false},
+		{"Bash(git status)", file("Edit", "/work/proj/a.go"), false},
+		{"Edit", file("Write", "/work/proj/a.go"), true},
+		{"Edit(src/**)", file("Edit", "/work/proj/src/pkg/a.go"), true},
+		{"Edit(src/*.go)", file("MultiEdit", "/work/proj/src/pkg/a.go"), false},
+		{"Edit(src/*.go)", file("Write", "/work/proj/src/a.go"), true},
+		{"Edit(//tmp/**)", file("Edit", "/tmp/x/y"), true},
+		{"Edit(src/**)", file("Edit", "/work/proj/../other/src/a.go"), false},
+		{"WebFetch(domain:go.dev)", fetch("https://pkg.go.dev/strings"), true},
+		{"WebFetch(domain:go.dev)", fetch("https://evilgo.dev/"), false},
+		{"mcp__github", hookInput{ToolName: "mcp__github__create_issue"}, true},
+		{"mcp__github__*", hookInput{ToolName: "mcp__github__create_issue"}, true},
+		{"mcp__github__get_issue", hookInput{ToolName: "mcp__github__create_issue"}, false},
+		{"Bash(git status", bash("git status"), false},
+	}
+	for _, tt := range tests {
+		if got := permissionRuleMatches(tt.rule, tt.input); got != tt.want {
+			t.Errorf("permissionRuleMatches(%q, %s %s) = %v, want %v", tt.rule, tt.input.ToolName, tt.input.ToolInput, got, tt.want)
+		}
+	}
+}
+
+func TestClaudeDecides(t *testing.T) {
+	home, cwd := t.TempDir(), t.TempDir()
+	write := func(path, content string) {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(home, ".claude", "settings.json"), `{"permissions":{"allow":["Bash(make:*)"]}}`)
+	write(filepath.Join(cwd, ".claude", "settings.json"), `{"permissions":{"deny":["Bash(git push:*)"],"ask":["Bash(make deploy)"]}}`)
+	write(filepath.Join(cwd, ".claude", "settings.local.json"), `{"permissions":{"allow":["WebFetch"]}}`)
+
+	bash := func(cmd string) hookInput {
+		in, _ := json.Marshal(map[string]string{"command": cmd})
+		return hookInput{ToolName: "Bash", ToolInput: in, CWD: cwd}
+	}
+	tests := []struct {
+		input hookInput
+		want  bool
+	}{
+		{bash("make test"), true},
+		{bash("git push origin main"), true},
+		{bash("make deploy"), false},
+		{bash("go test ./..."), false},
+		{hookInput{ToolName: "WebFetch", CWD: cwd}, true},
+		{hookInput{ToolName: "Write", CWD: cwd}, false},
+	}
+	for _, tt := range tests {
+		if got := claudeDecides(tt.input, home); got != tt.want {
+			t.Errorf("claudeDecides(%s %s) = %v, want %v", tt.input.ToolName, tt.input.ToolInput, got, tt.want)
+		}
+	}
+}
+
+func TestRunPreToolUse_AllowedBySettings(t *testing.T) {
+	dir, home := t.TempDir(), t.TempDir()
+	t.Setenv("TRAMUNTANA_DIR", dir)
+	t.Setenv("HOME", home)
+	t.Setenv("TMUX_PANE", "")
+	os.MkdirAll(filepath.Join(home, ".claude"), 0755)
+	os.WriteFile(filepath.Join(home, ".claude", "settings.json"), []byte(`{"permissions":{"allow":["Bash(make:*)"]}}`), 0644)
+
+	asked := false
+	srv, err := hooksock.Listen(hooksock.SocketPath(dir), func(req hooksock.Request) hooksock.Response {
+		asked = true
+		return hooksock.Response{Decision: hooksock.DecisionDeny}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx)
+
+	input := hookInput{HookEventName: "PreToolUse", ToolName: "Bash", ToolInput: json.RawMessage(`{"command":"make test"}`)}
+	var out bytes.Buffer
+	if err := runPreToolUse(input, &out); err != nil {
+		t.Fatalf("runPreToolUse: %v", err)
+	}
+	if asked || out.Len() != 0 {
+		t.Errorf("a call allowed by settings should not reach serve (asked=%v, output %q)", asked, out.String())
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// Profile bundles everything tramuntana needs to drive a terminal coding agent:
+// how to launch it, where its output comes from, and how to read its pane.
+type Profile struct {
+	Name  string // stored in WindowState.Agent
+	Label string // shown in the directory browser
+
+	// Command is the default launch command. Claude's comes from CLAUDE_COMMAND.
+	Command string
+	// CanResume reports whether the agent accepts --resume <session-id>.
+	CanResume bool
+	// SessionHook reports whether the agent registers itself in session_map.json
+	// through the SessionStart hook. Agents without it are never waited on.
+	SessionHook bool
+
+	// Transcript locates and parses the agent's transcripts. nil means output
+	// is scraped from the pane instead (see Output).
+	Transcript monitor.TranscriptFormat
+	// Output extracts the conversation area from a captured pane, dropping
+	// prompts and chrome. Used for pane scraping.
+	Output func(paneText string) string
+
+	// UIPatterns detect interactive prompts in the pane.
+	UIPatterns []monitor.UIPattern
+	// StatusLine extracts the agent's current activity from the pane.
+	StatusLine func(paneText string) (string, bool)
+	// Ready reports whether the pane shows the agent waiting for input.
+	Ready func(paneText string) bool
+}
+
+// Profile names.
+const (
+	NameClaude = "claude"
+	NameAider  = "aider"
+)
+
+var profiles = []*Profile{Claude, Aider}
+
+// Default is the profile used when none is recorded for a window.
+var Default = Claude
+
+// Get returns the profile with the given name, or Default for "" or unknown names.
+func Get(name string) *Profile {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return Default
+}
+
+// All returns every registered profile, default first.
+func All() []*Profile {
+	return profiles
+}
+
+// ScrapesPane reports whether the profile's output comes from pane scraping.
+func (p *Profile) ScrapesPane() bool {
+	return p.Transcript == nil
+}
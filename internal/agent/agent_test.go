@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name string
+		want *Profile
+	}{
+		{"", Claude},
+		{"claude", Claude},
+		{"aider", Aider},
+		{"unknown", Claude},
+	}
+	for _, tt := range tests {
+		if got := Get(tt.name); got != tt.want {
+			t.Errorf("Get(%q) = %s, want %s", tt.name, got.Name, tt.want.Name)
+		}
+	}
+}
+
+func TestScrapesPane(t *testing.T) {
+	if Claude.ScrapesPane() {
+		t.Error("Claude has a transcript, should not scrape the pane")
+	}
+	if !Aider.ScrapesPane() {
+		t.Error("Aider has no transcript, should scrape the pane")
+	}
+}
+
+func TestProfilesComplete(t *testing.T) {
+	for _, p := range All() {
+		if p.Name == "" || p.Label == "" || p.Command == "" {
+			t.Errorf("profile %q missing name, label or command", p.Name)
+		}
+		if p.Output == nil || p.StatusLine == nil || p.Ready == nil {
+			t.Errorf("profile %q missing pane functions", p.Name)
+		}
+	}
+}
+
+func TestClaudeReady(t *testing.T) {
+	if claudeReady("Loading...\n") {
+		t.Error("should not be ready without chrome separator")
+	}
+	if !claudeReady("Welcome\n" + strings.Repeat("─", 40) + "\n❯ \n") {
+		t.Error("should be ready with chrome separator")
+	}
+}
+
+func TestAiderReady(t *testing.T) {
+	tests := []struct {
+		pane string
+		want bool
+	}{
+		{"Aider v0.50\nModel: gpt-4o\n> \n\n", true},
+		{"Added main.go\narchitect> ", true},
+		{"> fix the bug\nWaiting for gpt-4o", false},
+		{"> half-typed input", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := aiderReady(tt.pane); got != tt.want {
+			t.Errorf("aiderReady(%q) = %v, want %v", tt.pane, got, tt.want)
+		}
+	}
+}
+
+func TestAiderStatusLine(t *testing.T) {
+	text, ok := aiderStatusLine("> fix the bug\n\n░█ Waiting for gpt-4o\n")
+	if !ok || text != "Waiting for gpt-4o" {
+		t.Errorf("got %q %v, want Waiting for gpt-4o", text, ok)
+	}
+	if _, ok := aiderStatusLine("Done.\n> "); ok {
+		t.Error("should not find status on idle prompt")
+	}
+}
+
+func TestAiderOutput(t *testing.T) {
+	pane := strings.Join([]string{
+		strings.Repeat("─", 40),
+		"> fix the bug",
+		"",
+		"The bug was an off-by-one.",
+		"Applied edit to main.go",
+		strings.Repeat("─", 40),
+		"> ",
+	}, "\n")
+	got := strings.TrimSpace(aiderOutput(pane))
+	want := "The bug was an off-by-one.\nApplied edit to main.go"
+	if got != want {
+		t.Errorf("aiderOutput = %q, want %q", got, want)
+	}
+}
+
+func TestAiderConfirmPattern(t *testing.T) {
+	pane := "> add a test\nAdd main_test.go to the chat? (Y)es/(N)o [Yes]: "
+	ui, ok := monitor.MatchUIPatterns(pane, Aider.UIPatterns)
+	if !ok || ui.Name != "Confirm" {
+		t.Fatalf("expected Confirm UI, got %q %v", ui.Name, ok)
+	}
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// Aider runs aider (https://aider.chat). It keeps no JSONL transcript, so its
+// output is scraped from the pane between prompts.
+var Aider = &Profile{
+	Name:       NameAider,
+	Label:      "Aider",
+	Command:    "aider",
+	Output:     aiderOutput,
+	StatusLine: aiderStatusLine,
+	Ready:      aiderReady,
+	UIPatterns: []monitor.UIPattern{
+		{
+			Name:       "Confirm",
+			TopMarkers: []string{"(Y)es/(N)o"},
+			SingleLine: true,
+		},
+	},
+}
+
+// reAiderPrompt matches aider's input prompt, with an optional mode prefix
+// ("> ", "ask> ", "architect> ", "multi> ").
+var reAiderPrompt = regexp.MustCompile(`^[a-z-]*> ?`)
+
+// aiderReady reports whether the last non-empty line is an empty input prompt.
+func aiderReady(paneText string) bool {
+	line := lastNonEmpty(paneText)
+	return reAiderPrompt.MatchString(line) && strings.TrimSpace(reAiderPrompt.ReplaceAllString(line, "")) == ""
+}
+
+// aiderStatusLine reports the "Waiting for <model>" spinner shown while aider
+// streams a reply.
+func aiderStatusLine(paneText string) (string, bool) {
+	lines := strings.Split(strings.TrimRight(paneText, "\n "), "\n")
+	for i := len(lines) - 1; i >= 0 && i >= len(lines)-3; i-- {
+		if idx := strings.Index(lines[i], "Waiting for "); idx >= 0 {
+			return strings.TrimSpace(lines[i][idx:]), true
+		}
+	}
+	return "", false
+}
+
+// aiderOutput drops prompt lines (including echoed user input) and the
+// horizontal rules aider prints between turns.
+func aiderOutput(paneText string) string {
+	var out []string
+	for _, line := range strings.Split(paneText, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if reAiderPrompt.MatchString(trimmed) || isRule(trimmed) {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func isRule(line string) bool {
+	if len(line) == 0 {
+		return false
+	}
+	for _, r := range line {
+		if r != '─' && r != '━' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func lastNonEmpty(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if t := strings.TrimSpace(lines[i]); t != "" {
+			return t
+		}
+	}
+	return ""
+}
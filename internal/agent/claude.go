@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// Claude is the built-in Claude Code profile: JSONL transcripts under
+// ~/.claude/projects, SessionStart hook registration, spinner status line.
+var Claude = &Profile{
+	Name:        NameClaude,
+	Label:       "Claude Code",
+	Command:     "claude",
+	CanResume:   true,
+	SessionHook: true,
+	Transcript:  monitor.ClaudeTranscript,
+	Output:      monitor.StripPaneChrome,
+	UIPatterns:  monitor.ClaudeUIPatterns(),
+	StatusLine:  monitor.ExtractStatusLine,
+	Ready:       claudeReady,
+}
+
+// claudeReady checks for Claude Code's chrome separator (≥20 ─ chars), which
+// appears once the TUI accepts input.
+func claudeReady(paneText string) bool {
+	for _, line := range strings.Split(paneText, "\n") {
+		count := 0
+		for _, r := range strings.TrimSpace(line) {
+			if r == '─' || r == '━' {
+				count++
+			}
+		}
+		if count >= 20 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// Backend names, as stored in WindowState.Backend and TRAMUNTANA_BACKEND.
+const (
+	NameTmux       = "tmux"
+	NameStreamJSON = "stream-json"
+)
+
+// ErrNotFound is returned when the session behind a window ID no longer exists.
+// Its message contains "not found" so tmux.IsWindowDead also recognises it.
+var ErrNotFound = errors.New("session not found")
+
+// ErrNoTerminal is returned for terminal-only operations (pane capture, raw keys)
+// on a session that has no terminal.
+var ErrNoTerminal = errors.New("session has no terminal")
+
+// Status is a point-in-time view of what a session is doing.
+type Status struct {
+	Text        string // current status line, "" when idle
+	Interactive bool   // an interactive prompt is waiting for keys
+}
+
+// Backend runs Claude sessions and exchanges input with them. Window IDs are
+// opaque to callers; output is still delivered through the JSONL transcript.
+type Backend interface {
+	// Name returns the backend name (NameTmux or NameStreamJSON).
+	Name() string
+	// Start launches claudeCmd in dir and returns the new window ID.
+	Start(dir, name, claudeCmd string, env map[string]string) (string, error)
+	// WaitReady blocks until the session accepts input or timeout passes.
+	WaitReady(windowID string, timeout time.Duration) bool
+	// SendText submits text as a user message.
+	SendText(windowID, text string) error
+	// SendKey sends a named key (tmux key names: "Escape", "Enter", "Up", ...).
+	SendKey(windowID, key string) error
+	// Capture returns the visible terminal content.
+	Capture(windowID string, withAnsi bool) (string, error)
+	// Status reports the current status line and interactive state.
+	Status(windowID string) (Status, error)
+	// Kill stops the session. Returns nil if it is already gone.
+	Kill(windowID string) error
+	// List returns the live sessions.
+	List() ([]tmux.Window, error)
+}
+
+// headlessPrefix marks window IDs owned by the stream-json backend. tmux
+// window IDs always start with "@", so the two never collide.
+const headlessPrefix = "~"
+
+// IsHeadless reports whether a window ID belongs to the stream-json backend.
+func IsHeadless(windowID string) bool {
+	return strings.HasPrefix(windowID, headlessPrefix)
+}
+
+// IsDead reports whether err means the session behind a window is gone.
+func IsDead(err error) bool {
+	return errors.Is(err, ErrNotFound) || tmux.IsWindowDead(err)
+}
+
+// ValidName reports whether name is a known backend name.
+func ValidName(name string) bool {
+	return name == NameTmux || name == NameStreamJSON
+}
@@ -0,0 +1,333 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// streamFlags switch Claude into headless mode with structured I/O on stdin/stdout.
+const streamFlags = "-p --input-format stream-json --output-format stream-json --verbose"
+
+// Stream runs Claude as a child process speaking stream-json. User messages
+// go over stdin; stdout events drive status and register the session in
+// session_map.json (replacing the SessionStart hook). Transcript content is
+// still read by the monitor from Claude's JSONL file, so message formatting
+// is identical across backends.
+type Stream struct {
+	tramuntanaDir string
+	keyPrefix     string // session_map key prefix (the tmux session name)
+
+	// OnSession is called when a session reports its Claude session ID.
+	OnSession func(windowID, sessionID string)
+
+	mu    sync.Mutex
+	procs map[string]*streamProc
+	seq   int64
+}
+
+// streamProc is a running headless Claude process.
+type streamProc struct {
+	id   string
+	name string
+	dir  string
+	cmd  *exec.Cmd
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu        sync.Mutex
+	sessionID string
+	status    string
+}
+
+// NewStream creates a stream-json backend. keyPrefix is used for session_map
+// keys ("<keyPrefix>:<windowID>") so the monitor treats both backends alike.
+func NewStream(tramuntanaDir, keyPrefix string) *Stream {
+	return &Stream{
+		tramuntanaDir: tramuntanaDir,
+		keyPrefix:     keyPrefix,
+		procs:         make(map[string]*streamProc),
+		seq:           time.Now().UnixMilli(),
+	}
+}
+
+func (s *Stream) Name() string { return NameStreamJSON }
+
+// Start launches claudeCmd in headless stream-json mode.
+func (s *Stream) Start(dir, name, claudeCmd string, env map[string]string) (string, error) {
+	cmd := exec.Command("sh", "-c", claudeCmd+" "+streamFlags)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", fmt.Errorf("stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("starting headless claude in %s: %w", dir, err)
+	}
+
+	s.mu.Lock()
+	s.seq++
+	id := headlessPrefix + strconv.FormatInt(s.seq, 36)
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	p := &streamProc{id: id, name: name, dir: dir, cmd: cmd, stdin: stdin}
+	s.procs[id] = p
+	s.mu.Unlock()
+
+	// Wait closes the pipes, so it must only run once both have hit EOF
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go func() {
+		defer readers.Done()
+		s.readEvents(p, stdout)
+	}()
+	go func() {
+		defer readers.Done()
+		logStderr(id, stderr)
+	}()
+	go func() {
+		readers.Wait()
+		err := cmd.Wait()
+		s.mu.Lock()
+		delete(s.procs, id)
+		s.mu.Unlock()
+		log.Printf("Headless session %s exited: %v", id, err)
+	}()
+
+	return id, nil
+}
+
+// WaitReady returns immediately: stdin is buffered until Claude reads it.
+func (s *Stream) WaitReady(windowID string, timeout time.Duration) bool {
+	_, err := s.get(windowID)
+	return err == nil
+}
+
+// SendText writes a user message to the session's stdin.
+func (s *Stream) SendText(windowID, text string) error {
+	p, err := s.get(windowID)
+	if err != nil {
+		return err
+	}
+	msg := map[string]any{
+		"type": "user",
+		"message": map[string]any{
+			"role":    "user",
+			"content": text,
+		},
+	}
+	// Set before writing: the reply's events may arrive before write returns
+	p.setStatus("Thinking…")
+	if err := p.write(msg); err != nil {
+		p.setStatus("")
+		return err
+	}
+	return nil
+}
+
+// SendKey supports only Escape, which is mapped to an interrupt request.
+func (s *Stream) SendKey(windowID, key string) error {
+	p, err := s.get(windowID)
+	if err != nil {
+		return err
+	}
+	if key != "Escape" {
+		return fmt.Errorf("key %s: %w", key, ErrNoTerminal)
+	}
+	s.mu.Lock()
+	s.seq++
+	reqID := "tramuntana-" + strconv.FormatInt(s.seq, 36)
+	s.mu.Unlock()
+	return p.write(map[string]any{
+		"type":       "control_request",
+		"request_id": reqID,
+		"request":    map[string]any{"subtype": "interrupt"},
+	})
+}
+
+func (s *Stream) Capture(windowID string, withAnsi bool) (string, error) {
+	if _, err := s.get(windowID); err != nil {
+		return "", err
+	}
+	return "", ErrNoTerminal
+}
+
+// Status reports activity derived from the event stream. Headless sessions
+// never show interactive prompts.
+func (s *Stream) Status(windowID string) (Status, error) {
+	p, err := s.get(windowID)
+	if err != nil {
+		return Status{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Status{Text: p.status}, nil
+}
+
+func (s *Stream) Kill(windowID string) error {
+	p, err := s.get(windowID)
+	if err != nil {
+		return nil
+	}
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	return nil
+}
+
+func (s *Stream) List() ([]tmux.Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	windows := make([]tmux.Window, 0, len(s.procs))
+	for _, p := range s.procs {
+		windows = append(windows, tmux.Window{ID: p.id, Name: p.name, CWD: p.dir})
+	}
+	return windows, nil
+}
+
+func (s *Stream) get(windowID string) (*streamProc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.procs[windowID]
+	if !ok {
+		return nil, fmt.Errorf("headless %s: %w", windowID, ErrNotFound)
+	}
+	return p, nil
+}
+
+// streamEvent is the subset of a stream-json stdout event we act on.
+type streamEvent struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype"`
+	SessionID string `json:"session_id"`
+	Message   struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// readEvents consumes stdout until the process exits.
+func (s *Stream) readEvents(p *streamProc, r io.Reader) {
+	lr := monitor.NewLineReader(r)
+	for {
+		line, _, err := lr.Next()
+		if err == monitor.ErrLineTooLong {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		var ev streamEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+		s.handleEvent(p, ev)
+	}
+}
+
+func (s *Stream) handleEvent(p *streamProc, ev streamEvent) {
+	if ev.SessionID != "" {
+		p.mu.Lock()
+		changed := ev.SessionID != p.sessionID
+		p.sessionID = ev.SessionID
+		p.mu.Unlock()
+		if changed {
+			s.registerSession(p, ev.SessionID)
+		}
+	}
+
+	switch ev.Type {
+	case "assistant":
+		p.setStatus(assistantStatus(ev.Message.Content))
+	case "result":
+		p.setStatus("")
+	}
+}
+
+// registerSession writes the session_map entry the SessionStart hook would write.
+// OnSession runs first so callers can re-link offsets before the monitor sees it.
+func (s *Stream) registerSession(p *streamProc, sessionID string) {
+	if s.OnSession != nil {
+		s.OnSession(p.id, sessionID)
+	}
+	key := s.keyPrefix + ":" + p.id
+	path := filepath.Join(s.tramuntanaDir, "session_map.json")
+	err := state.ReadModifyWriteSessionMap(path, func(data map[string]state.SessionMapEntry) {
+		data[key] = state.SessionMapEntry{SessionID: sessionID, CWD: p.dir, WindowName: p.name}
+	})
+	if err != nil {
+		log.Printf("Headless %s: writing session map: %v", p.id, err)
+	}
+}
+
+// assistantStatus summarizes an assistant event's content as a status line.
+func assistantStatus(content json.RawMessage) string {
+	var blocks []struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(content, &blocks) == nil {
+		for _, b := range blocks {
+			if b.Type == "tool_use" && b.Name != "" {
+				return "Running " + b.Name + "…"
+			}
+		}
+	}
+	return "Thinking…"
+}
+
+func (p *streamProc) setStatus(text string) {
+	p.mu.Lock()
+	p.status = text
+	p.mu.Unlock()
+}
+
+// write sends one JSON line on stdin.
+func (p *streamProc) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("headless %s: %v: %w", p.id, err, ErrNotFound)
+	}
+	return nil
+}
+
+// logStderr forwards a headless process's stderr to the log.
+func logStderr(id string, r io.Reader) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		log.Printf("Headless %s: %s", id, sc.Text())
+	}
+	io.Copy(io.Discard, r) // keep draining past an overlong line
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// fakeClaude is a stand-in for `claude -p` speaking stream-json: it reports a
+// session on startup, and answers every stdin line with a tool call and a result.
+const fakeClaude = `#!/bin/sh
+echo '{"type":"system","subtype":"init","session_id":"sess-1"}'
+while read -r line; do
+  echo '{"type":"assistant","session_id":"sess-1","message":{"content":[{"type":"tool_use","name":"Bash"}]}}'
+  sleep 0.2
+  echo '{"type":"result","session_id":"sess-1"}'
+done
+`
+
+func writeFakeClaude(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(path, []byte(fakeClaude), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestStream_Lifecycle(t *testing.T) {
+	tramDir := t.TempDir()
+	s := NewStream(tramDir, "tramuntana")
+
+	reported := make(chan string, 1)
+	s.OnSession = func(windowID, sessionID string) { reported <- windowID + " " + sessionID }
+
+	id, err := s.Start(t.TempDir(), "proj", writeFakeClaude(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHeadless(id) {
+		t.Errorf("window ID %q should be headless", id)
+	}
+
+	select {
+	case got := <-reported:
+		if got != id+" sess-1" {
+			t.Errorf("OnSession = %q, want %q", got, id+" sess-1")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("session was never reported")
+	}
+
+	waitFor(t, "session_map entry", func() bool {
+		sm, _ := state.LoadSessionMap(filepath.Join(tramDir, "session_map.json"))
+		return sm["tramuntana:"+id].SessionID == "sess-1"
+	})
+
+	if err := s.SendText(id, "hello"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	waitFor(t, "tool status", func() bool {
+		st, _ := s.Status(id)
+		return st.Text == "Running Bash…"
+	})
+	waitFor(t, "idle after result", func() bool {
+		st, _ := s.Status(id)
+		return st.Text == ""
+	})
+
+	if _, err := s.Capture(id, false); err != ErrNoTerminal {
+		t.Errorf("Capture err = %v, want ErrNoTerminal", err)
+	}
+
+	s.Kill(id)
+	waitFor(t, "process exit", func() bool {
+		windows, _ := s.List()
+		return len(windows) == 0
+	})
+	if err := s.SendText(id, "again"); !IsDead(err) {
+		t.Errorf("SendText after exit: err = %v, want dead", err)
+	}
+}
+
+func TestAssistantStatus(t *testing.T) {
+	tool := json.RawMessage(`[{"type":"text","text":"ok"},{"type":"tool_use","name":"Read"}]`)
+	if got := assistantStatus(tool); got != "Running Read…" {
+		t.Errorf("assistantStatus(tool) = %q", got)
+	}
+	text := json.RawMessage(`[{"type":"text","text":"ok"}]`)
+	if got := assistantStatus(text); got != "Thinking…" {
+		t.Errorf("assistantStatus(text) = %q", got)
+	}
+}
+
+func TestIsHeadless(t *testing.T) {
+	if IsHeadless("@12") {
+		t.Error("tmux window should not be headless")
+	}
+	if !IsHeadless("~abc") {
+		t.Error("~ prefix should be headless")
+	}
+}
@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"strings"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/agent"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// Tmux runs an agent's TUI in tmux windows and scrapes the pane for status.
+type Tmux struct {
+	Session string // tmux session name
+
+	// ProfileFor returns the agent profile running in a window (nil → agent.Default).
+	ProfileFor func(windowID string) *agent.Profile
+}
+
+// NewTmux creates a tmux backend for the given session.
+func NewTmux(session string) *Tmux {
+	return &Tmux{Session: session}
+}
+
+func (t *Tmux) Name() string { return NameTmux }
+
+func (t *Tmux) Start(dir, name, claudeCmd string, env map[string]string) (string, error) {
+	windowID, err := tmux.NewWindow(t.Session, name, dir, claudeCmd, env)
+	if err != nil {
+		return "", err
+	}
+	// Kill the placeholder _init window now that we have a real window
+	tmux.CleanupInitWindow(t.Session)
+	return windowID, nil
+}
+
+// WaitReady polls the pane until the agent's profile reports it ready for input.
+func (t *Tmux) WaitReady(windowID string, timeout time.Duration) bool {
+	p := t.profile(windowID)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		text, err := tmux.CapturePane(t.Session, windowID, false)
+		if err == nil && p.Ready(text) {
+			return true
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return false
+}
+
+const (
+	// pasteThreshold is the length above which SendText pastes instead of typing.
+	pasteThreshold = 300
+	// pasteTimeout is how long a paste has to show up in the pane.
+	pasteTimeout = 5 * time.Second
+)
+
+// SendText submits text to the pane. Short single-line text is typed, with
+// Enter after 500ms; longer or multi-line text is pasted (see Paste).
+func (t *Tmux) SendText(windowID, text string) error {
+	if len(text) > pasteThreshold || strings.Contains(text, "\n") {
+		return t.Paste(windowID, text)
+	}
+	return tmux.SendKeysWithDelay(t.Session, windowID, text, 500)
+}
+
+// Paste submits text through a tmux paste buffer with bracketed paste, and
+// presses Enter once the pane shows it landed.
+func (t *Tmux) Paste(windowID, text string) error {
+	return tmux.PasteText(t.Session, windowID, text, pasteTimeout)
+}
+
+func (t *Tmux) SendKey(windowID, key string) error {
+	return tmux.SendSpecialKey(t.Session, windowID, key)
+}
+
+func (t *Tmux) Capture(windowID string, withAnsi bool) (string, error) {
+	return tmux.CapturePane(t.Session, windowID, withAnsi)
+}
+
+// Status captures the pane and extracts the status line or interactive UI
+// using the window's agent profile.
+func (t *Tmux) Status(windowID string) (Status, error) {
+	paneText, err := tmux.CapturePane(t.Session, windowID, false)
+	if err != nil {
+		return Status{}, err
+	}
+	p := t.profile(windowID)
+	if _, ok := monitor.MatchUIPatterns(paneText, p.UIPatterns); ok {
+		return Status{Interactive: true}, nil
+	}
+	text, _ := p.StatusLine(paneText)
+	return Status{Text: text}, nil
+}
+
+func (t *Tmux) profile(windowID string) *agent.Profile {
+	if t.ProfileFor != nil {
+		if p := t.ProfileFor(windowID); p != nil {
+			return p
+		}
+	}
+	return agent.Default
+}
+
+func (t *Tmux) Kill(windowID string) error {
+	return tmux.KillWindow(t.Session, windowID)
+}
+
+func (t *Tmux) List() ([]tmux.Window, error) {
+	return tmux.ListWindows(t.Session)
+}
@@ -230,6 +230,7 @@ func (b *Bot) handleTaskPick(cq *tgbotapi.CallbackQuery, data string) {
 		return
 	}
 
+	b.trackTask(windowID, taskID)
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s...", taskID))
 }
 
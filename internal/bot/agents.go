@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"github.com/otaviocarvalho/tramuntana/internal/agent"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+)
+
+// agentFor returns the agent profile running in a window.
+func (b *Bot) agentFor(windowID string) *agent.Profile {
+	ws, _ := b.state.GetWindowState(windowID)
+	return agent.Get(ws.Agent)
+}
+
+// agentCommand returns the launch command for a profile, honoring config overrides.
+func (b *Bot) agentCommand(p *agent.Profile) string {
+	switch p.Name {
+	case agent.NameClaude:
+		return b.config.ClaudeCommand
+	case agent.NameAider:
+		if b.config.AiderCommand != "" {
+			return b.config.AiderCommand
+		}
+	}
+	return p.Command
+}
+
+// TranscriptFormatFor returns the transcript format for a window, or nil for
+// windows whose agent has no transcript (the monitor then uses Claude's).
+// Set as Monitor.FormatFor by the serve command.
+func (b *Bot) TranscriptFormatFor(windowID string) monitor.TranscriptFormat {
+	return b.agentFor(windowID).Transcript
+}
@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const allowUsage = "Usage: /c_allow [project] <Tool> [glob]\n" +
+	"e.g. /c_allow Bash go test *\n" +
+	"     /c_allow project Edit internal/*.go\n\n" +
+	"Send /c_allow alone to list and remove rules."
+
+// reYesSelected matches a permission dialog whose cursor is on the "Yes" option.
+var reYesSelected = regexp.MustCompile(`❯\s*1\.\s*Yes\b`)
+
+// autoApproved remembers when each window's pane prompt was last answered, so
+// a poll that still sees the old dialog doesn't press Enter twice.
+var autoApproved = struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}{last: make(map[string]time.Time)}
+
+const autoApproveDedupe = 3 * time.Second
+
+// handleAllowCommand lists the topic's auto-approval rules or adds one.
+func (b *Bot) handleAllowCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+	project, _ := b.state.GetProject(threadIDStr)
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		text, kb := b.buildAllowRulesList(threadIDStr, project)
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+			log.Printf("Error sending allow rules: %v", err)
+		}
+		return
+	}
+
+	projectScope, tool, pattern, ok := parseAllowArgs(args)
+	if !ok {
+		b.reply(chatID, threadID, "Missing tool name.\n\n"+allowUsage)
+		return
+	}
+
+	if tool == "Bash" && state.IsCompoundCommand(pattern) {
+		b.reply(chatID, threadID, "Chained, piped or redirected commands are never auto-approved, so this rule would never match. Allow each command on its own.")
+		return
+	}
+
+	rule := state.AllowRule{Tool: tool, Pattern: pattern}
+	if projectScope {
+		if project == "" {
+			b.reply(chatID, threadID, "No project bound. Use /p_bind first, or drop \"project\" to add a topic rule.")
+			return
+		}
+		rule.Project = project
+	} else {
+		rule.Thread = threadIDStr
+	}
+
+	if _, added := b.state.AddAllowRule(rule); !added {
+		b.reply(chatID, threadID, "Rule already exists: "+describeAllowRule(rule))
+		return
+	}
+	b.saveState()
+	b.reply(chatID, threadID, "Auto-approving "+describeAllowRule(rule))
+}
+
+// parseAllowArgs parses "[project] <Tool> [glob]". The glob defaults to "*".
+// Returns false if no tool is given.
+func parseAllowArgs(args string) (projectScope bool, tool, pattern string, ok bool) {
+	fields := strings.Fields(args)
+	if len(fields) > 0 && fields[0] == "project" {
+		projectScope = true
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return false, "", "", false
+	}
+	tool = fields[0]
+	pattern = strings.Join(fields[1:], " ")
+	if pattern == "" {
+		pattern = "*"
+	}
+	return projectScope, tool, pattern, true
+}
+
+// processAllowCallback handles allow_rm:<id> and allow_close buttons.
+func (b *Bot) processAllowCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	if cq.Data == "allow_close" {
+		b.editMessageText(chatID, messageID, "Auto-approval rules closed.")
+		return
+	}
+
+	id, ok := strings.CutPrefix(cq.Data, "allow_rm:")
+	if !ok {
+		return
+	}
+	if b.state.RemoveAllowRule(id) {
+		b.saveState()
+	}
+
+	threadIDStr := strconv.Itoa(getThreadID(cq.Message))
+	project, _ := b.state.GetProject(threadIDStr)
+	text, kb := b.buildAllowRulesList(threadIDStr, project)
+	if err := b.editMessageWithKeyboard(chatID, messageID, text, kb); err != nil {
+		log.Printf("Error refreshing allow rules: %v", err)
+	}
+}
+
+// buildAllowRulesList renders the rules that apply to a topic, one remove
+// button per rule.
+func (b *Bot) buildAllowRulesList(threadID, project string) (string, tgbotapi.InlineKeyboardMarkup) {
+	rules := b.state.AllowRulesFor(threadID, project)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, r := range rules {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("\U0001F5D1 "+describeAllowRule(r), "allow_rm:"+r.ID),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Close", "allow_close"),
+	))
+
+	text := "No auto-approval rules for this topic.\n\n" + allowUsage
+	if len(rules) > 0 {
+		text = fmt.Sprintf("Auto-approval rules (%d). Tap a rule to remove it.", len(rules))
+	}
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// describeAllowRule renders a rule as "Bash: go test * (topic)".
+func describeAllowRule(r state.AllowRule) string {
+	scope := "topic"
+	if r.Project != "" {
+		scope = "project " + r.Project
+	}
+	return fmt.Sprintf("%s: %s (%s)", r.Tool, r.Pattern, scope)
+}
+
+// matchAllowRule returns the first rule covering a tool call, looking at every
+// topic bound to the window and the projects those topics are bound to.
+func (b *Bot) matchAllowRule(windowID, tool, subject string) (state.AllowRule, bool) {
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		project, _ := b.state.GetProject(ut.ThreadID)
+		for _, r := range b.state.AllowRulesFor(ut.ThreadID, project) {
+			if r.Matches(tool, subject) {
+				return r, true
+			}
+		}
+	}
+	return state.AllowRule{}, false
+}
+
+// auditAutoApproval posts a one-line record of an auto-approved call.
+func (b *Bot) auditAutoApproval(windowID, tool, subject string, r state.AllowRule) {
+	subject = truncateDetail(strings.ReplaceAll(subject, "\n", " "), 200)
+	b.postToWindow(windowID, fmt.Sprintf("✅ Auto-approved %s: %s (rule: %s %s)", tool, subject, r.Tool, r.Pattern))
+}
+
+// hookSubject extracts what a rule's glob is matched against from a PreToolUse
+// tool input: the Bash command, the file path (relative to cwd when under it),
+// or the fetched URL. Empty for tools rules don't cover.
+func hookSubject(tool string, inputJSON json.RawMessage, cwd string) string {
+	var input map[string]any
+	json.Unmarshal(inputJSON, &input)
+	str := func(key string) string {
+		s, _ := input[key].(string)
+		return s
+	}
+
+	switch tool {
+	case "Bash":
+		return str("command")
+	case "Edit", "MultiEdit", "Write":
+		return relativeTo(cwd, str("file_path"))
+	case "NotebookEdit":
+		return relativeTo(cwd, str("notebook_path"))
+	case "WebFetch":
+		return str("url")
+	}
+	return ""
+}
+
+func relativeTo(cwd, path string) string {
+	if cwd == "" || path == "" {
+		return path
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// autoApprovePane answers a permission dialog on the pane with Enter when a
+// rule covers it. Returns true if the dialog was (or was just) handled, in
+// which case no keyboard should be shown.
+func (b *Bot) autoApprovePane(windowID, paneText string) bool {
+	req, ok := monitor.ParsePermissionPrompt(paneText)
+	if !ok || !reYesSelected.MatchString(paneText) {
+		return false
+	}
+	rule, ok := b.matchAllowRule(windowID, req.Tool, req.Subject)
+	if !ok {
+		return false
+	}
+
+	autoApproved.mu.Lock()
+	if time.Since(autoApproved.last[windowID]) < autoApproveDedupe {
+		autoApproved.mu.Unlock()
+		return true
+	}
+	autoApproved.last[windowID] = time.Now()
+	autoApproved.mu.Unlock()
+
+	if err := b.backendFor(windowID).SendKey(windowID, "Enter"); err != nil {
+		log.Printf("Auto-approve: sending Enter to %s: %v", windowID, err)
+		return false
+	}
+	b.auditAutoApproval(windowID, req.Tool, req.Subject, rule)
+	return true
+}
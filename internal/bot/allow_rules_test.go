@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestParseAllowArgs(t *testing.T) {
+	tests := []struct {
+		args        string
+		wantProject bool
+		wantTool    string
+		wantPattern string
+		wantOK      bool
+	}{
+		{"Bash go test *", false, "Bash", "go test *", true},
+		{"project Edit internal/*.go", true, "Edit", "internal/*.go", true},
+		{"WebFetch", false, "WebFetch", "*", true},
+		{"project", false, "", "", false},
+		{"", false, "", "", false},
+	}
+	for _, tt := range tests {
+		project, tool, pattern, ok := parseAllowArgs(tt.args)
+		if project != tt.wantProject || tool != tt.wantTool || pattern != tt.wantPattern || ok != tt.wantOK {
+			t.Errorf("parseAllowArgs(%q) = %v, %q, %q, %v", tt.args, project, tool, pattern, ok)
+		}
+	}
+}
+
+func TestBuildAllowRulesList(t *testing.T) {
+	b := newTestBot(t)
+	r, _ := b.state.AddAllowRule(state.AllowRule{Tool: "Bash", Pattern: "go test *", Thread: "42"})
+	b.state.AddAllowRule(state.AllowRule{Tool: "Edit", Pattern: "*.md", Project: "proj"})
+	b.state.AddAllowRule(state.AllowRule{Tool: "Bash", Pattern: "make", Thread: "99"})
+
+	text, kb := b.buildAllowRulesList("42", "proj")
+	if !strings.Contains(text, "(2)") {
+		t.Errorf("unexpected text: %q", text)
+	}
+	if len(kb.InlineKeyboard) != 3 {
+		t.Fatalf("expected 2 rule rows + close, got %d rows", len(kb.InlineKeyboard))
+	}
+	if got := *kb.InlineKeyboard[0][0].CallbackData; got != "allow_rm:"+r.ID {
+		t.Errorf("first row callback = %q", got)
+	}
+	if got := *kb.InlineKeyboard[2][0].CallbackData; got != "allow_close" {
+		t.Errorf("last row callback = %q", got)
+	}
+	for _, row := range kb.InlineKeyboard {
+		if len(*row[0].CallbackData) > 64 {
+			t.Errorf("callback data too long: %q", *row[0].CallbackData)
+		}
+	}
+
+	text, kb = b.buildAllowRulesList("7", "")
+	if !strings.Contains(text, "No auto-approval rules") || len(kb.InlineKeyboard) != 1 {
+		t.Errorf("empty list: %q, %d rows", text, len(kb.InlineKeyboard))
+	}
+}
+
+func TestHookSubject(t *testing.T) {
+	tests := []struct {
+		tool  string
+		input string
+		want  string
+	}{
+		{"Bash", `{"command":"go test ./..."}`, "go test ./..."},
+		{"Edit", `{"file_path":"/src/app/main.go"}`, "main.go"},
+		{"Write", `{"file_path":"/src/app/internal/x.go"}`, "internal/x.go"},
+		{"Edit", `{"file_path":"/etc/hosts"}`, "/etc/hosts"},
+		{"WebFetch", `{"url":"https://go.dev"}`, "https://go.dev"},
+		{"mcp__search", `{"q":"x"}`, ""},
+	}
+	for _, tt := range tests {
+		if got := hookSubject(tt.tool, json.RawMessage(tt.input), "/src/app"); got != tt.want {
+			t.Errorf("hookSubject(%s, %s) = %q, want %q", tt.tool, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHandlePermissionRequest_AllowRule(t *testing.T) {
+	b := newTestBot(t)
+	b.state.BindThread("100", "42", "@3")
+	b.state.BindProject("55", "proj")
+	b.state.BindThread("100", "55", "@4")
+	b.state.AddAllowRule(state.AllowRule{Tool: "Bash", Pattern: "go test *", Thread: "42"})
+	b.state.AddAllowRule(state.AllowRule{Tool: "Edit", Pattern: "*.md", Project: "proj"})
+
+	tests := []struct {
+		name   string
+		window string
+		tool   string
+		input  string
+		want   string
+	}{
+		{"topic rule", "@3", "Bash", `{"command":"go test ./..."}`, hooksock.DecisionAllow},
+		{"project rule", "@4", "Edit", `{"file_path":"/src/README.md"}`, hooksock.DecisionAllow},
+		{"rule of another topic", "@4", "Bash", `{"command":"go test ./..."}`, ""},
+		{"non-matching command", "@3", "Bash", `{"command":"rm -rf /"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unmatched requests fall through to the Telegram prompt, which
+			// defers when the topic has no chat ID
+			resp := b.HandleHookRequest(hooksock.Request{
+				Event:     "PreToolUse",
+				Window:    "test-session:" + tt.window,
+				CWD:       "/src",
+				ToolName:  tt.tool,
+				ToolInput: json.RawMessage(tt.input),
+			})
+			if resp.Decision != tt.want {
+				t.Errorf("decision = %q, want %q", resp.Decision, tt.want)
+			}
+		})
+	}
+}
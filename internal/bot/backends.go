@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+)
+
+// backendFor returns the backend that owns a window ID.
+func (b *Bot) backendFor(windowID string) backend.Backend {
+	if backend.IsHeadless(windowID) {
+		return b.headless
+	}
+	return b.tmuxBackend
+}
+
+// backendByName returns the backend for a name, falling back to the configured default.
+func (b *Bot) backendByName(name string) backend.Backend {
+	if name == "" {
+		name = b.config.DefaultBackend
+	}
+	if name == backend.NameStreamJSON {
+		return b.headless
+	}
+	return b.tmuxBackend
+}
+
+// liveWindows lists live sessions across all backends.
+func (b *Bot) liveWindows() ([]tmux.Window, error) {
+	windows, err := b.tmuxBackend.List()
+	if err != nil {
+		return nil, err
+	}
+	headless, _ := b.headless.List()
+	return append(windows, headless...), nil
+}
+
+// onHeadlessSession records the Claude session ID reported by a headless process.
+// A resumed session that comes back under a new ID is followed from its end.
+func (b *Bot) onHeadlessSession(windowID, sessionID string) {
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.SessionID == sessionID {
+		return
+	}
+	log.Printf("Headless %s: session %s", windowID, sessionID)
+	if ws.SessionID != "" {
+		b.relinkForkedSession(b.config.TmuxSessionName+":"+windowID, sessionID)
+	}
+	ws.SessionID = sessionID
+	b.state.SetWindowState(windowID, ws)
+	b.saveState()
+}
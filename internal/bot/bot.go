@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
 	"github.com/otaviocarvalho/tramuntana/internal/minuano"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
@@ -38,12 +39,35 @@ type Bot struct {
 	pendingInputs map[int64]*pendingInput
 	// Per-user pending plan approval state
 	planStates map[int64]*planState
+	// Transcribed voice notes awaiting Send, by draft id
+	voiceDrafts     map[string]string
+	voiceDraftOrder []string // ids by age, for expiring old drafts
+	nextVoiceDraft  uint64
+	// Albums whose files are still arriving, by media group id
+	albums map[string]*pendingAlbum
+	// Per-user snippets waiting for their params (see /c_snip)
+	snippetFills map[int64]*snippetFill
+	// Per-topic messages held while Claude works (see /c_queue)
+	inputMu         sync.Mutex
+	inputViewMu     sync.Mutex // serialises updates of the "Queued (N)" messages
+	inputQueues     map[topicKey]*inputQueue
+	nextQueuedInput uint64
+	inputDeliveries chan inputDelivery
+	// Dead windows found at startup, for ResumeDeadWindows
+	startupResumes []pendingResume
 	// Monitor state (set by serve command when monitor is started)
 	monitorState *state.MonitorState
+	// Token usage totals (set by serve command)
+	usage *state.UsageState
 	// Minuano CLI bridge
 	minuanoBridge *minuano.Bridge
 	// Message queue (set after construction via SetQueue)
 	msgQueue *queue.Queue
+	// Status poller (set via SetStatusPoller), told about hook turn events
+	statusPoller *StatusPoller
+	// Session backends: tmux windows and headless stream-json processes
+	tmuxBackend *backend.Tmux
+	headless    *backend.Stream
 }
 
 // New creates a new Bot instance.
@@ -67,7 +91,7 @@ func New(cfg *config.Config) (*Bot, error) {
 		return nil, fmt.Errorf("ensuring tmux session: %w", err)
 	}
 
-	return &Bot{
+	b := &Bot{
 		api:                api,
 		config:             cfg,
 		state:              st,
@@ -79,8 +103,14 @@ func New(cfg *config.Config) (*Bot, error) {
 		taskPickerStates:   make(map[int64]*taskPickerState),
 		pendingInputs:      make(map[int64]*pendingInput),
 		planStates:         make(map[int64]*planState),
+		inputDeliveries:    make(chan inputDelivery, inputDeliveryBuffer),
 		minuanoBridge:      minuano.NewBridge(cfg.MinuanoBin, cfg.MinuanoDB),
-	}, nil
+		tmuxBackend:        backend.NewTmux(cfg.TmuxSessionName),
+		headless:           backend.NewStream(cfg.TramuntanaDir, cfg.TmuxSessionName),
+	}
+	b.headless.OnSession = b.onHeadlessSession
+	b.tmuxBackend.ProfileFor = b.agentFor
+	return b, nil
 }
 
 // registerCommands sets the bot's command menu in Telegram.
@@ -92,16 +122,26 @@ func (b *Bot) registerCommands() {
 		tgbotapi.BotCommand{Command: "c_clear", Description: "Forward /clear to Claude Code"},
 		tgbotapi.BotCommand{Command: "c_help", Description: "Forward /help to Claude Code"},
 		tgbotapi.BotCommand{Command: "c_get", Description: "Browse and send a file"},
+		tgbotapi.BotCommand{Command: "c_allow", Description: "Auto-approve matching permission prompts"},
+		tgbotapi.BotCommand{Command: "c_verbosity", Description: "How much of Claude's work this topic shows"},
+		tgbotapi.BotCommand{Command: "c_queue", Description: "Hold messages sent while Claude is working"},
+		tgbotapi.BotCommand{Command: "c_schedule", Description: "Send a prompt to this topic on a cron schedule"},
+		tgbotapi.BotCommand{Command: "c_snip", Description: "Send a saved prompt snippet"},
+		tgbotapi.BotCommand{Command: "c_cmds", Description: "Run the project's custom Claude commands"},
+		tgbotapi.BotCommand{Command: "c_model", Description: "Restart this topic's session with another model"},
+		tgbotapi.BotCommand{Command: "c_mode", Description: "Permission mode, allowed tools and flags for this topic"},
 		tgbotapi.BotCommand{Command: "p_bind", Description: "Bind a Minuano project to this topic"},
 		tgbotapi.BotCommand{Command: "p_tasks", Description: "List tasks for the bound project"},
 		tgbotapi.BotCommand{Command: "p_add", Description: "Create a new Minuano task"},
 		tgbotapi.BotCommand{Command: "p_delete", Description: "Delete a Minuano task"},
 		tgbotapi.BotCommand{Command: "p_history", Description: "Message history for this topic"},
+		tgbotapi.BotCommand{Command: "c_usage", Description: "Token usage and cost for this topic and project"},
 		tgbotapi.BotCommand{Command: "t_pick", Description: "Assign a specific task to Claude"},
 		tgbotapi.BotCommand{Command: "t_pickw", Description: "Pick task in isolated worktree"},
 		tgbotapi.BotCommand{Command: "t_auto", Description: "Auto-claim and work project tasks"},
 		tgbotapi.BotCommand{Command: "t_batch", Description: "Work a list of tasks in order"},
 		tgbotapi.BotCommand{Command: "t_unclaim", Description: "Release a claimed task back to ready"},
+		tgbotapi.BotCommand{Command: "c_budget", Description: "Token, cost and time limits for /t_auto runs"},
 		tgbotapi.BotCommand{Command: "t_merge", Description: "Merge a branch (auto-resolve conflicts)"},
 		tgbotapi.BotCommand{Command: "t_plan", Description: "Plan and create tasks from a description"},
 		tgbotapi.BotCommand{Command: "plan", Description: "Open a planner session in this topic"},
@@ -116,6 +156,7 @@ func (b *Bot) registerCommands() {
 // Run starts the bot polling loop. Blocks until ctx is cancelled.
 func (b *Bot) Run(ctx context.Context) error {
 	b.registerCommands()
+	go b.runInputDelivery(ctx)
 	log.Println("Bot is running...")
 
 	offset := 0
@@ -197,6 +238,18 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		return
 	}
 
+	// Handle voice notes and audio files
+	if up, ok := voiceMessage(msg); ok {
+		b.handleVoice(msg, up)
+		return
+	}
+
+	// Handle photos and documents
+	if up, ok := messageUpload(msg); ok {
+		b.handleUpload(msg, up)
+		return
+	}
+
 	// Handle text messages
 	if msg.Text != "" {
 		b.handleTextMessage(msg)
@@ -244,6 +297,11 @@ func (b *Bot) SetQueue(q *queue.Queue) {
 	b.msgQueue = q
 }
 
+// SetStatusPoller sets the status poller that hook turn events are passed to.
+func (b *Bot) SetStatusPoller(sp *StatusPoller) {
+	b.statusPoller = sp
+}
+
 // answerCallback answers an inline callback query with a toast message.
 func (b *Bot) answerCallback(callbackID, text string) {
 	cb := tgbotapi.NewCallback(callbackID, text)
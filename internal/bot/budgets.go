@@ -0,0 +1,458 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const budgetUsage = "Usage: /c_budget [project] <limit>... | off\n" +
+	"Limits: 500k or 2M (tokens), $5 (estimated cost), 3h or 90m (run time)\n" +
+	"e.g. /c_budget $5 2h\n" +
+	"     /c_budget project 10M\n\n" +
+	"Budgets apply to each /t_auto run."
+
+// spend is what a run has used in one scope.
+type spend struct {
+	tokens  int64
+	usd     float64
+	elapsed time.Duration
+}
+
+// autoRun is a /t_auto run being watched against its topic's and project's budgets.
+type autoRun struct {
+	windowID string
+	chatID   int64
+	threadID int
+	project  string
+	started  time.Time
+	// topic and project totals when the run (or its last Continue) started
+	topicBase   spend
+	projectBase spend
+	paused      bool   // interrupted by the guard, waiting for Continue or Stop
+	inFlight    string // task to offer unclaiming while paused
+}
+
+// autoRuns tracks the active auto runs by window ID.
+var autoRuns = struct {
+	mu   sync.Mutex
+	runs map[string]*autoRun
+}{runs: make(map[string]*autoRun)}
+
+// handleBudgetCommand shows or sets the topic's or project's auto-mode budget.
+func (b *Bot) handleBudgetCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+	project, _ := b.state.GetProject(threadIDStr)
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		b.reply(chatID, threadID, b.describeBudgets(threadIDStr, project))
+		return
+	}
+
+	projectScope, budget, err := parseBudgetArgs(args)
+	if err != nil {
+		b.reply(chatID, threadID, "Invalid budget: "+err.Error()+"\n\n"+budgetUsage)
+		return
+	}
+
+	scope := "topic"
+	if projectScope {
+		if project == "" {
+			b.reply(chatID, threadID, "No project bound. Use /p_bind first, or drop \"project\" to set a topic budget.")
+			return
+		}
+		scope = "project " + project
+		b.state.SetProjectBudget(project, budget)
+	} else {
+		b.state.SetTopicBudget(threadIDStr, budget)
+	}
+	b.saveState()
+
+	if budget.IsZero() {
+		b.reply(chatID, threadID, "Budget removed for "+scope+".")
+		return
+	}
+	b.reply(chatID, threadID, fmt.Sprintf("Budget for %s: %s per /t_auto run.", scope, describeBudget(budget)))
+}
+
+// parseBudgetArgs parses "[project] <limit>..." or "[project] off".
+func parseBudgetArgs(args []string) (projectScope bool, budget state.Budget, err error) {
+	if args[0] == "project" {
+		projectScope = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return false, budget, fmt.Errorf("missing limit")
+	}
+	if len(args) == 1 && args[0] == "off" {
+		return projectScope, budget, nil
+	}
+	for _, arg := range args {
+		if arg == "tokens" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(arg, "$"); ok {
+			usd, err := strconv.ParseFloat(v, 64)
+			if err != nil || usd <= 0 {
+				return false, budget, fmt.Errorf("bad cost limit %q", arg)
+			}
+			budget.USD = usd
+			continue
+		}
+		// Durations use lowercase units (90m, 2h); token counts k/K or M (2M)
+		if d, err := time.ParseDuration(arg); err == nil {
+			if d <= 0 {
+				return false, budget, fmt.Errorf("bad time limit %q", arg)
+			}
+			budget.Hours = d.Hours()
+			continue
+		}
+		tokens, err := parseTokenCount(arg)
+		if err != nil || tokens <= 0 {
+			return false, budget, fmt.Errorf("bad limit %q", arg)
+		}
+		budget.Tokens = tokens
+	}
+	return projectScope, budget, nil
+}
+
+// parseTokenCount parses 500000, 500k or 1.5M.
+func parseTokenCount(s string) (int64, error) {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		mult, s = 1e3, s[:len(s)-1]
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n * mult), nil
+}
+
+// describeBudget renders a budget as "2M tokens · $5.00 · 2h00m".
+func describeBudget(bud state.Budget) string {
+	var parts []string
+	if bud.Tokens > 0 {
+		parts = append(parts, formatTokens(bud.Tokens)+" tokens")
+	}
+	if bud.USD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f", bud.USD))
+	}
+	if bud.Hours > 0 {
+		parts = append(parts, formatElapsed(hoursDuration(bud.Hours)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// describeBudgets lists the budgets that apply to a topic and the spend of its active run.
+func (b *Bot) describeBudgets(threadID, project string) string {
+	var sb strings.Builder
+	if bud, ok := b.state.TopicBudget(threadID); ok {
+		fmt.Fprintf(&sb, "Topic budget: %s\n", describeBudget(bud))
+	} else {
+		sb.WriteString("Topic budget: none\n")
+	}
+	if project != "" {
+		if bud, ok := b.state.ProjectBudget(project); ok {
+			fmt.Fprintf(&sb, "Project %s budget: %s\n", project, describeBudget(bud))
+		} else {
+			fmt.Fprintf(&sb, "Project %s budget: none\n", project)
+		}
+	}
+
+	autoRuns.mu.Lock()
+	var run *autoRun
+	for _, r := range autoRuns.runs {
+		if strconv.Itoa(r.threadID) == threadID {
+			c := *r
+			run = &c
+		}
+	}
+	autoRuns.mu.Unlock()
+	if run != nil && b.usage != nil {
+		used, _ := b.runSpend(run)
+		fmt.Fprintf(&sb, "\nCurrent auto run: %s", describeSpend(used))
+		if run.paused {
+			sb.WriteString(" (paused)")
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n" + budgetUsage)
+	return sb.String()
+}
+
+// describeSpend renders spend as "1.2M tokens · $4.80 · 1h12m".
+func describeSpend(s spend) string {
+	return fmt.Sprintf("%s tokens · $%.2f · %s", formatTokens(s.tokens), s.usd, formatElapsed(s.elapsed))
+}
+
+// startAutoRun begins watching a /t_auto run in a topic, replacing any earlier run in the window.
+func (b *Bot) startAutoRun(windowID string, chatID int64, threadID int, project string) {
+	run := &autoRun{windowID: windowID, chatID: chatID, threadID: threadID, project: project}
+	b.resetRunBase(run)
+	autoRuns.mu.Lock()
+	autoRuns.runs[windowID] = run
+	autoRuns.mu.Unlock()
+}
+
+// endAutoRun stops watching a window's run when its turn ends. Runs paused by
+// the guard are kept until Continue or Stop is pressed.
+func endAutoRun(windowID string) {
+	autoRuns.mu.Lock()
+	defer autoRuns.mu.Unlock()
+	if run, ok := autoRuns.runs[windowID]; ok && !run.paused {
+		delete(autoRuns.runs, windowID)
+	}
+}
+
+// clearAutoRun forgets a window's run (dead window, topic closed).
+func clearAutoRun(windowID string) {
+	autoRuns.mu.Lock()
+	delete(autoRuns.runs, windowID)
+	autoRuns.mu.Unlock()
+}
+
+// resetRunBase starts a new budget period for run from the current totals.
+func (b *Bot) resetRunBase(run *autoRun) {
+	run.started = time.Now()
+	run.topicBase, run.projectBase = b.scopeTotals(run)
+}
+
+// scopeTotals returns the all-time usage of a run's topic and project.
+func (b *Bot) scopeTotals(run *autoRun) (topic, project spend) {
+	if b.usage == nil {
+		return
+	}
+	thread := strconv.Itoa(run.threadID)
+	topic = b.totalSpend(func(r state.UsageRecord) bool { return r.Thread == thread })
+	if run.project != "" {
+		project = b.totalSpend(func(r state.UsageRecord) bool { return r.Project == run.project })
+	}
+	return topic, project
+}
+
+func (b *Bot) totalSpend(filter func(state.UsageRecord) bool) spend {
+	byModel := state.SumByModel(b.usage.Matching(time.Time{}, filter))
+	var s spend
+	for _, u := range byModel {
+		s.tokens += u.Total()
+	}
+	s.usd, _ = usageCost(b.config.Prices, byModel)
+	return s
+}
+
+// runSpend returns what a run has used in its topic and, across all its
+// topics, in its project since the run (or its last Continue) started.
+func (b *Bot) runSpend(run *autoRun) (topic, project spend) {
+	topicNow, projectNow := b.scopeTotals(run)
+	elapsed := time.Since(run.started)
+	topic = spend{topicNow.tokens - run.topicBase.tokens, topicNow.usd - run.topicBase.usd, elapsed}
+	project = spend{projectNow.tokens - run.projectBase.tokens, projectNow.usd - run.projectBase.usd, elapsed}
+	return topic, project
+}
+
+// exceededLimit returns a description of the first limit of bud that s has reached, or "".
+func exceededLimit(bud state.Budget, s spend) string {
+	switch {
+	case bud.Tokens > 0 && s.tokens >= bud.Tokens:
+		return fmt.Sprintf("%s / %s tokens", formatTokens(s.tokens), formatTokens(bud.Tokens))
+	case bud.USD > 0 && s.usd >= bud.USD:
+		return fmt.Sprintf("$%.2f / $%.2f", s.usd, bud.USD)
+	case bud.Hours > 0 && s.elapsed >= hoursDuration(bud.Hours):
+		return fmt.Sprintf("%s / %s", formatElapsed(s.elapsed), formatElapsed(hoursDuration(bud.Hours)))
+	}
+	return ""
+}
+
+// checkBudgets interrupts auto runs that have reached their topic's or
+// project's budget. Called on every status poll.
+func (b *Bot) checkBudgets() {
+	autoRuns.mu.Lock()
+	var active []autoRun
+	for _, r := range autoRuns.runs {
+		if !r.paused {
+			active = append(active, *r)
+		}
+	}
+	autoRuns.mu.Unlock()
+
+	for _, run := range active {
+		topic, project := b.runSpend(&run)
+		var reason string
+		if bud, ok := b.state.TopicBudget(strconv.Itoa(run.threadID)); ok {
+			if hit := exceededLimit(bud, topic); hit != "" {
+				reason = "topic budget reached: " + hit
+			}
+		}
+		if bud, ok := b.state.ProjectBudget(run.project); ok && reason == "" && run.project != "" {
+			if hit := exceededLimit(bud, project); hit != "" {
+				reason = fmt.Sprintf("project %s budget reached: %s", run.project, hit)
+			}
+		}
+		if reason != "" {
+			b.pauseAutoRun(run.windowID, reason, topic)
+		}
+	}
+}
+
+// pauseAutoRun interrupts a run and asks whether to continue.
+func (b *Bot) pauseAutoRun(windowID, reason string, used spend) {
+	autoRuns.mu.Lock()
+	run, ok := autoRuns.runs[windowID]
+	if !ok || run.paused {
+		autoRuns.mu.Unlock()
+		return
+	}
+	run.paused = true
+	chatID, threadID, project := run.chatID, run.threadID, run.project
+	autoRuns.mu.Unlock()
+
+	log.Printf("Budget guard: pausing auto run in %s: %s", windowID, reason)
+	if err := b.backendFor(windowID).SendKey(windowID, "Escape"); err != nil {
+		log.Printf("Budget guard: sending Escape to %s: %v", windowID, err)
+	}
+
+	inFlight := b.inFlightTask(windowID, project)
+	autoRuns.mu.Lock()
+	run.inFlight = inFlight
+	autoRuns.mu.Unlock()
+
+	text := fmt.Sprintf("💸 Auto mode paused — %s\nThis run: %s", reason, describeSpend(used))
+	if inFlight != "" {
+		text += "\nIn-flight task: " + inFlight
+	}
+	if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, buildBudgetKeyboard(windowID, inFlight != "")); err != nil {
+		log.Printf("Error sending budget alert: %v", err)
+	}
+}
+
+// inFlightTask returns the task the window's session has claimed in the
+// project, or "" if that can't be told: no single task is claimed under the
+// session's agent ID, or another bound session runs under the same ID.
+func (b *Bot) inFlightTask(windowID, project string) string {
+	if b.minuanoBridge == nil || project == "" {
+		return ""
+	}
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		return ""
+	}
+	agentID := minuanoAgentID(filepath.Base(ws.CWD))
+	for other := range b.state.AllBoundWindowIDs() {
+		if ows, ok := b.state.GetWindowState(other); ok && other != windowID && ows.CWD != "" &&
+			minuanoAgentID(filepath.Base(ows.CWD)) == agentID {
+			return ""
+		}
+	}
+
+	tasks, err := b.minuanoBridge.Status(project)
+	if err != nil {
+		log.Printf("Budget guard: listing tasks for %s: %v", project, err)
+		return ""
+	}
+	return claimedTaskOf(tasks, agentID)
+}
+
+// claimedTaskOf returns the only task claimed by agentID, or "".
+func claimedTaskOf(tasks []minuano.Task, agentID string) string {
+	var claimed []string
+	for _, t := range tasks {
+		if t.Status == "claimed" && t.ClaimedBy != nil && *t.ClaimedBy == agentID {
+			claimed = append(claimed, t.ID)
+		}
+	}
+	if len(claimed) != 1 {
+		return ""
+	}
+	return claimed[0]
+}
+
+// buildBudgetKeyboard returns the Continue/Stop buttons of a budget alert.
+func buildBudgetKeyboard(windowID string, canUnclaim bool) tgbotapi.InlineKeyboardMarkup {
+	row := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("▶ Continue", "budget_go:"+windowID),
+		tgbotapi.NewInlineKeyboardButtonData("⏹ Stop", "budget_stop:"+windowID),
+	)
+	if canUnclaim {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⏹ Stop + unclaim", "budget_unclaim:"+windowID))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// processBudgetCallback handles budget_go, budget_stop and budget_unclaim buttons.
+func (b *Bot) processBudgetCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+	action, windowID, _ := strings.Cut(strings.TrimPrefix(cq.Data, "budget_"), ":")
+
+	autoRuns.mu.Lock()
+	run, ok := autoRuns.runs[windowID]
+	if !ok || !run.paused {
+		autoRuns.mu.Unlock()
+		b.editMessageText(chatID, messageID, cq.Message.Text+"\n\n(expired)")
+		return
+	}
+	inFlight := run.inFlight
+	if action == "go" {
+		run.paused = false
+		run.inFlight = ""
+		b.resetRunBase(run)
+	} else {
+		delete(autoRuns.runs, windowID)
+	}
+	autoRuns.mu.Unlock()
+
+	var outcome string
+	switch action {
+	case "go":
+		if err := b.backendFor(windowID).SendText(windowID, "continue"); err != nil {
+			log.Printf("Error resuming auto run in %s: %v", windowID, err)
+			outcome = "⚠️ Failed to resume: " + err.Error()
+			break
+		}
+		outcome = "▶ Continued with a fresh budget."
+	case "stop":
+		outcome = "⏹ Auto mode stopped."
+	case "unclaim":
+		outcome = "⏹ Auto mode stopped."
+		if inFlight != "" {
+			if err := b.minuanoBridge.Unclaim(inFlight); err != nil {
+				outcome += fmt.Sprintf(" Failed to unclaim %s: %v", inFlight, err)
+			} else {
+				outcome += fmt.Sprintf(" Task %s is ready again.", inFlight)
+			}
+		}
+	}
+	b.editMessageText(chatID, messageID, cq.Message.Text+"\n\n"+outcome)
+}
+
+func hoursDuration(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour))
+}
+
+// formatElapsed renders a duration as 45s, 12m or 1h05m.
+func formatElapsed(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/minuano"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestParseBudgetArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		project bool
+		want    state.Budget
+		err     bool
+	}{
+		{args: "2M", want: state.Budget{Tokens: 2_000_000}},
+		{args: "500k tokens", want: state.Budget{Tokens: 500_000}},
+		{args: "$5 90m", want: state.Budget{USD: 5, Hours: 1.5}},
+		{args: "project 1.5M $2.50 2h", project: true, want: state.Budget{Tokens: 1_500_000, USD: 2.5, Hours: 2}},
+		{args: "off"},
+		{args: "project off", project: true},
+		{args: "project", err: true},
+		{args: "$x", err: true},
+		{args: "lots", err: true},
+		{args: "0", err: true},
+	}
+	for _, tt := range tests {
+		project, got, err := parseBudgetArgs(strings.Fields(tt.args))
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseBudgetArgs(%q) expected error", tt.args)
+			}
+			continue
+		}
+		if err != nil || project != tt.project || got != tt.want {
+			t.Errorf("parseBudgetArgs(%q) = %v, %+v, %v; want %v, %+v", tt.args, project, got, err, tt.project, tt.want)
+		}
+	}
+}
+
+func TestExceededLimit(t *testing.T) {
+	bud := state.Budget{Tokens: 1_000_000, USD: 5, Hours: 1}
+	if got := exceededLimit(bud, spend{tokens: 999_999, usd: 4.99, elapsed: 59 * time.Minute}); got != "" {
+		t.Errorf("under budget reported %q", got)
+	}
+	if got := exceededLimit(bud, spend{tokens: 1_200_000}); got != "1.2M / 1M tokens" {
+		t.Errorf("token limit = %q", got)
+	}
+	if got := exceededLimit(bud, spend{usd: 5.5}); got != "$5.50 / $5.00" {
+		t.Errorf("cost limit = %q", got)
+	}
+	if got := exceededLimit(bud, spend{elapsed: 65 * time.Minute}); got != "1h05m / 1h00m" {
+		t.Errorf("time limit = %q", got)
+	}
+	if exceededLimit(state.Budget{}, spend{tokens: 1 << 40}) != "" {
+		t.Error("an empty budget never trips")
+	}
+}
+
+func TestAutoRunSpend(t *testing.T) {
+	b := newTestBot(t)
+	b.config.Prices = config.DefaultPrices
+	b.usage = state.NewUsageState()
+	add := func(thread string, out int64) {
+		b.usage.Add("@1", time.Now(), state.UsageRecord{Thread: thread, Project: "web", Model: "claude-haiku-4-5", TokenUsage: state.TokenUsage{Output: out}})
+	}
+	add("5", 1000) // before the run
+
+	b.startAutoRun("@1", -100, 5, "web")
+	defer clearAutoRun("@1")
+	add("5", 200_000)
+	add("9", 400_000) // another topic of the same project
+
+	autoRuns.mu.Lock()
+	run := *autoRuns.runs["@1"]
+	autoRuns.mu.Unlock()
+	topic, project := b.runSpend(&run)
+	if topic.tokens != 200_000 || project.tokens != 600_000 {
+		t.Errorf("run spend = topic %d, project %d tokens", topic.tokens, project.tokens)
+	}
+	if topic.usd < 0.99 || topic.usd > 1.01 {
+		t.Errorf("topic cost = %v, want 1.00", topic.usd)
+	}
+
+	// Under budget: nothing is paused
+	b.state.SetTopicBudget("5", state.Budget{Tokens: 300_000})
+	b.checkBudgets()
+	autoRuns.mu.Lock()
+	paused := autoRuns.runs["@1"].paused
+	autoRuns.mu.Unlock()
+	if paused {
+		t.Error("run under budget should keep going")
+	}
+}
+
+func TestEndAutoRunKeepsPausedRuns(t *testing.T) {
+	b := newTestBot(t)
+	b.startAutoRun("@7", -100, 5, "")
+	endAutoRun("@7")
+	autoRuns.mu.Lock()
+	_, ok := autoRuns.runs["@7"]
+	autoRuns.mu.Unlock()
+	if ok {
+		t.Fatal("turn end should end the run")
+	}
+
+	b.startAutoRun("@7", -100, 5, "")
+	autoRuns.mu.Lock()
+	autoRuns.runs["@7"].paused = true
+	autoRuns.mu.Unlock()
+	endAutoRun("@7")
+	autoRuns.mu.Lock()
+	_, ok = autoRuns.runs["@7"]
+	autoRuns.mu.Unlock()
+	if !ok {
+		t.Error("a paused run waits for Continue or Stop")
+	}
+	clearAutoRun("@7")
+}
+
+func TestBuildBudgetKeyboard(t *testing.T) {
+	got := strings.Join(callbackData(buildBudgetKeyboard("@3", false).InlineKeyboard), ",")
+	if got != "budget_go:@3,budget_stop:@3" {
+		t.Errorf("callbacks = %s", got)
+	}
+	got = strings.Join(callbackData(buildBudgetKeyboard("@3", true).InlineKeyboard), ",")
+	if got != "budget_go:@3,budget_stop:@3,budget_unclaim:@3" {
+		t.Errorf("callbacks with unclaim = %s", got)
+	}
+}
+
+func TestClaimedTaskOf(t *testing.T) {
+	me, other := "tramuntana-api", "tramuntana-web"
+	tasks := []minuano.Task{
+		{ID: "t1", Status: "claimed", ClaimedBy: &other},
+		{ID: "t2", Status: "claimed", ClaimedBy: &me},
+		{ID: "t3", Status: "ready"},
+		{ID: "t4", Status: "claimed"},
+	}
+	if got := claimedTaskOf(tasks, me); got != "t2" {
+		t.Errorf("claimedTaskOf = %q, want t2", got)
+	}
+	if got := claimedTaskOf(tasks, "tramuntana-cli"); got != "" {
+		t.Errorf("a session with no claim should get no task, got %q", got)
+	}
+	tasks = append(tasks, minuano.Task{ID: "t5", Status: "claimed", ClaimedBy: &me})
+	if got := claimedTaskOf(tasks, me); got != "" {
+		t.Errorf("two claims are ambiguous, got %q", got)
+	}
+}
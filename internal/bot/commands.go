@@ -8,9 +8,10 @@ import (
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/git"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
-	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 // handleCommand routes slash commands.
@@ -31,6 +32,26 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 		b.forwardCommand(msg, "help")
 	case "c_memory":
 		b.forwardCommand(msg, "memory")
+	case "c_allow":
+		b.handleAllowCommand(msg)
+	case "c_usage":
+		b.handleUsageCommand(msg)
+	case "c_budget":
+		b.handleBudgetCommand(msg)
+	case "c_verbosity":
+		b.handleVerbosityCommand(msg)
+	case "c_queue":
+		b.handleQueueCommand(msg)
+	case "c_schedule":
+		b.handleScheduleCommand(msg)
+	case "c_snip":
+		b.handleSnipCommand(msg)
+	case "c_cmds":
+		b.handleCmdsCommand(msg)
+	case "c_model":
+		b.handleModelCommand(msg)
+	case "c_mode":
+		b.handleModeCommand(msg)
 	case "esc", "c_esc":
 		b.handleEsc(msg)
 	case "c_screenshot":
@@ -75,7 +96,7 @@ func (b *Bot) resolveWindow(msg *tgbotapi.Message) (string, bool) {
 	return b.state.GetWindowForThread(userID, threadID)
 }
 
-// forwardCommand sends a command as text to the bound tmux window.
+// forwardCommand sends a command as text to the bound session.
 // claudeCmd is the Claude-side command name (e.g. "clear", not "c_clear").
 func (b *Bot) forwardCommand(msg *tgbotapi.Message, claudeCmd string) {
 	windowID, bound := b.resolveWindow(msg)
@@ -85,8 +106,8 @@ func (b *Bot) forwardCommand(msg *tgbotapi.Message, claudeCmd string) {
 	}
 
 	cmdText := "/" + claudeCmd
-	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, cmdText, 500); err != nil {
-		if tmux.IsWindowDead(err) {
+	if err := b.backendFor(windowID).SendText(windowID, cmdText); err != nil {
+		if backend.IsDead(err) {
 			b.handleDeadWindow(msg, windowID, "")
 			return
 		}
@@ -103,6 +124,19 @@ func (b *Bot) forwardCommand(msg *tgbotapi.Message, claudeCmd string) {
 
 // resetSessionTracking clears session monitor state for a window after /clear.
 func (b *Bot) resetSessionTracking(windowID string) {
+	// The next todo list belongs to a new conversation: give it its own message
+	if b.msgQueue != nil {
+		for _, t := range b.windowTopics(windowID) {
+			b.msgQueue.Enqueue(queue.MessageTask{
+				UserID:      t.UserID,
+				ThreadID:    t.ThreadID,
+				ChatID:      t.ChatID,
+				ContentType: "todo_clear",
+				WindowID:    windowID,
+			})
+		}
+	}
+
 	// Remove window state's session info so the monitor starts fresh
 	// The monitor_state.json offset will be reset when the new JSONL file appears
 	if b.monitorState != nil {
@@ -120,7 +154,7 @@ func (b *Bot) resetSessionTracking(windowID string) {
 	}
 }
 
-// handleEsc sends Escape key to tmux.
+// handleEsc sends Escape to interrupt Claude.
 func (b *Bot) handleEsc(msg *tgbotapi.Message) {
 	windowID, bound := b.resolveWindow(msg)
 	if !bound {
@@ -128,8 +162,8 @@ func (b *Bot) handleEsc(msg *tgbotapi.Message) {
 		return
 	}
 
-	if err := tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, "Escape"); err != nil {
-		if tmux.IsWindowDead(err) {
+	if err := b.backendFor(windowID).SendKey(windowID, "Escape"); err != nil {
+		if backend.IsDead(err) {
 			b.handleDeadWindow(msg, windowID, "")
 			return
 		}
@@ -218,10 +252,11 @@ func (b *Bot) handleTopicClose(msg *tgbotapi.Message) {
 
 		cleaned = true
 
-		// Kill tmux window (ignore errors — may already be dead)
-		tmux.KillWindow(b.config.TmuxSessionName, windowID)
+		// Kill the session (ignore errors — may already be dead)
+		b.backendFor(windowID).Kill(windowID)
 
 		// Clean up state
+		clearAutoRun(windowID)
 		b.state.UnbindThread(userID, threadIDStr)
 		b.state.RemoveWindowState(windowID)
 		b.state.RemoveGroupChatID(userID, threadIDStr)
@@ -242,8 +277,13 @@ func (b *Bot) handleTopicClose(msg *tgbotapi.Message) {
 		}
 	}
 
-	// Remove project binding for this thread
+	// Remove project binding, budget, verbosity, input queue and schedules for this thread
 	b.state.RemoveProject(threadIDStr)
+	b.state.SetTopicBudget(threadIDStr, state.Budget{})
+	b.state.RemoveTopicVerbosity(threadIDStr)
+	b.state.SetInputQueue(threadIDStr, false)
+	b.dropInputQueue(msg.Chat.ID, threadID)
+	b.state.RemoveTopicSchedules(threadIDStr)
 
 	// Clean up worktree if this thread has one
 	if wi, ok := b.state.GetWorktreeInfo(threadIDStr); ok {
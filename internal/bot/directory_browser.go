@@ -11,8 +11,9 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/agent"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
-	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 const dirsPerPage = 6
@@ -23,6 +24,7 @@ type BrowseState struct {
 	Page        int
 	Dirs        []string // cached subdirectory names for index-based callbacks
 	PendingText string
+	Launch      state.LaunchProfile // chosen in the launch options step
 	MessageID   int
 	ChatID      int64
 	ThreadID    int
@@ -131,6 +133,13 @@ func buildDirectoryBrowser(currentPath string, page int) (string, tgbotapi.Inlin
 		rows = append(rows, paginationRow)
 	}
 
+	// Headless row: start with the stream-json backend instead of the default,
+	// or pick a model and permission mode first
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Select (headless)", "dir_headless"),
+		tgbotapi.NewInlineKeyboardButtonData("Options…", "dir_opts"),
+	))
+
 	// Action row: .. | Select | Cancel
 	actionRow := []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("..", "dir_up"),
@@ -172,7 +181,21 @@ func (b *Bot) processDirectoryCallback(cq *tgbotapi.CallbackQuery) {
 	case data == "dir_up":
 		b.handleDirUp(cq, bs, userID)
 	case data == "dir_confirm":
-		b.handleDirConfirm(cq, bs, userID)
+		if len(agent.All()) > 1 {
+			text, keyboard := buildAgentPicker(bs.CurrentPath)
+			b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
+			return
+		}
+		b.handleDirConfirm(cq, bs, userID, spawnOptions{})
+	case strings.HasPrefix(data, "dir_agent:"):
+		b.handleDirConfirm(cq, bs, userID, spawnOptions{Agent: strings.TrimPrefix(data, "dir_agent:")})
+	case data == "dir_headless":
+		b.handleDirConfirm(cq, bs, userID, spawnOptions{Backend: backend.NameStreamJSON})
+	case data == "dir_opts", strings.HasPrefix(data, "dir_model:"), strings.HasPrefix(data, "dir_mode:"):
+		b.handleDirOptions(cq, bs)
+	case data == "dir_back":
+		text, keyboard, _ := buildDirectoryBrowser(bs.CurrentPath, bs.Page)
+		b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
 	case data == "dir_cancel":
 		b.handleDirCancel(cq, bs, userID)
 	case data == "dir_noop":
@@ -241,20 +264,114 @@ type createWindowResult struct {
 	WindowName string
 }
 
-// createWindowForDir creates a new tmux window in the given directory, waits for the
+// createWindowForDir creates a new session window in the given directory, waits for the
 // session_map entry, binds the thread, and renames the topic. Returns the result or error.
 func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadID int) (*createWindowResult, error) {
+	return b.createWindowForDirWith(dir, userID, chatID, threadID, spawnOptions{})
+}
+
+// spawnOptions controls how a new Claude session is launched.
+type spawnOptions struct {
+	Name    string      // window name ("" lets the backend pick one)
+	Backend string      // backend name ("" uses the configured default)
+	Agent   string      // agent profile name ("" for Claude Code)
+	Resume  *resumeInfo // conversation to resume, if any
+	Launch  state.LaunchProfile
+}
+
+// createWindowForDirWith is createWindowForDir with explicit launch options.
+func (b *Bot) createWindowForDirWith(dir string, userID int64, chatID int64, threadID int, opts spawnOptions) (*createWindowResult, error) {
+	windowID, err := b.spawnWindow(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind thread to window
+	userIDStr := strconv.FormatInt(userID, 10)
+	threadIDStr := strconv.Itoa(threadID)
+	b.state.BindThread(userIDStr, threadIDStr, windowID)
+	b.saveState()
+
+	// Get window name for topic rename
+	windowName := filepath.Base(dir)
+	if dn, ok := b.state.GetWindowDisplayName(windowID); ok {
+		windowName = dn
+	}
+
+	// Rename topic
+	b.renameForumTopic(chatID, threadID, windowName)
+
+	return &createWindowResult{WindowID: windowID, WindowName: windowName}, nil
+}
+
+// spawnWindow starts Claude on the selected backend, waits for its session_map
+// entry and records the window state. When opts.Resume is set, Claude is started
+// with --resume and the transcript offsets are re-linked to the new window.
+func (b *Bot) spawnWindow(dir string, opts spawnOptions) (string, error) {
+	be := b.backendByName(opts.Backend)
+	profile := agent.Get(opts.Agent)
+	resume := opts.Resume
+	name := opts.Name
+	if be.Name() == backend.NameStreamJSON && profile != agent.Claude {
+		// Headless mode speaks Claude's stream-json protocol only
+		be = b.backendByName(backend.NameTmux)
+	}
+	if !profile.CanResume {
+		resume = nil
+	}
+	launch := opts.Launch
+	if profile != agent.Claude {
+		// Model, permission mode and tools are Claude Code flags
+		launch = state.LaunchProfile{}
+	}
+
 	// Build Minuano environment if configured
 	env := b.buildMinuanoEnv(filepath.Base(dir))
 
-	// Create new tmux window
-	windowID, err := tmux.NewWindow(b.config.TmuxSessionName, "", dir, b.config.ClaudeCommand, env)
+	claudeCmd := b.agentCommand(profile)
+	if args := launchArgs(launch); args != "" {
+		claudeCmd += " " + args
+	}
+	if resume != nil {
+		claudeCmd = resumeCommand(claudeCmd, resume.SessionID)
+	}
+
+	windowID, err := be.Start(dir, name, claudeCmd, env)
 	if err != nil {
-		return nil, fmt.Errorf("creating window: %w", err)
+		return "", fmt.Errorf("creating window: %w", err)
 	}
 
-	// Kill the placeholder _init window now that we have a real window
-	tmux.CleanupInitWindow(b.config.TmuxSessionName)
+	if resume != nil {
+		b.relinkResumedOffset(windowID, *resume)
+	}
+
+	// Agents without the SessionStart hook never show up in session_map:
+	// record the window directly and wait for the agent's prompt
+	if !profile.SessionHook {
+		if name == "" {
+			name = filepath.Base(dir)
+		}
+		b.state.SetWindowState(windowID, state.WindowState{CWD: dir, WindowName: name, Backend: be.Name(), Agent: profile.Name, LaunchProfile: launch})
+		b.state.SetWindowDisplayName(windowID, name)
+		be.WaitReady(windowID, 15*time.Second)
+		return windowID, nil
+	}
+
+	// Headless sessions report their session ID with the first reply, not at
+	// startup, so there is no session_map entry to wait for
+	if be.Name() == backend.NameStreamJSON {
+		if name == "" {
+			name = filepath.Base(dir)
+		}
+		ws := state.WindowState{CWD: dir, WindowName: name, Backend: be.Name(), LaunchProfile: launch}
+		if resume != nil {
+			ws.SessionID = resume.SessionID
+			b.seedSessionMap(windowID, dir, name, resume.SessionID)
+		}
+		b.state.SetWindowState(windowID, ws)
+		b.state.SetWindowDisplayName(windowID, name)
+		return windowID, nil
+	}
 
 	// Wait for session_map entry (up to 5s)
 	sessionMapPath := filepath.Join(b.config.TramuntanaDir, "session_map.json")
@@ -269,11 +386,15 @@ func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadI
 			if strings.HasSuffix(key, ":"+windowID) {
 				sessionKey = key
 				b.state.SetWindowState(windowID, state.WindowState{
-					SessionID:  entry.SessionID,
-					CWD:        entry.CWD,
-					WindowName: entry.WindowName,
+					SessionID:     entry.SessionID,
+					CWD:           entry.CWD,
+					WindowName:    entry.WindowName,
+					LaunchProfile: launch,
 				})
 				b.state.SetWindowDisplayName(windowID, entry.WindowName)
+				if resume != nil && entry.SessionID != resume.SessionID {
+					b.relinkForkedSession(key, entry.SessionID)
+				}
 				break
 			}
 		}
@@ -282,28 +403,61 @@ func (b *Bot) createWindowForDir(dir string, userID int64, chatID int64, threadI
 		}
 	}
 
+	// The hook hasn't reported yet: link the resumed session ourselves so the
+	// monitor keeps following the transcript
+	if sessionKey == "" && resume != nil {
+		b.seedSessionMap(windowID, dir, name, resume.SessionID)
+	}
+	// Keep the launch profile even if the hook is late, so restarts reuse it
+	if sessionKey == "" && launch != (state.LaunchProfile{}) {
+		ws, ok := b.state.GetWindowState(windowID)
+		if !ok {
+			ws = state.WindowState{CWD: dir, WindowName: name}
+		}
+		ws.LaunchProfile = launch
+		b.state.SetWindowState(windowID, ws)
+	}
+
 	// Wait for Claude Code TUI to be ready before sending any text
-	tmux.WaitForReady(b.config.TmuxSessionName, windowID, 15*time.Second)
+	be.WaitReady(windowID, 15*time.Second)
 
-	// Bind thread to window
-	userIDStr := strconv.FormatInt(userID, 10)
-	threadIDStr := strconv.Itoa(threadID)
-	b.state.BindThread(userIDStr, threadIDStr, windowID)
-	b.saveState()
+	return windowID, nil
+}
 
-	// Get window name for topic rename
-	windowName := filepath.Base(dir)
-	if dn, ok := b.state.GetWindowDisplayName(windowID); ok {
-		windowName = dn
+// buildAgentPicker builds the agent choice shown after a directory is selected.
+func buildAgentPicker(dir string) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := fmt.Sprintf("Start which agent in %s?", shortenPath(dir))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range agent.All() {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(p.Label, "dir_agent:"+p.Name),
+		))
 	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "dir_cancel"),
+	))
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
 
-	// Rename topic
-	b.renameForumTopic(chatID, threadID, windowName)
+// handleDirOptions shows the launch options step, applying a model or
+// permission mode choice.
+func (b *Bot) handleDirOptions(cq *tgbotapi.CallbackQuery, bs *BrowseState) {
+	b.mu.Lock()
+	if model, ok := strings.CutPrefix(cq.Data, "dir_model:"); ok {
+		bs.Launch.Model = model
+	}
+	if mode, ok := strings.CutPrefix(cq.Data, "dir_mode:"); ok {
+		bs.Launch.PermissionMode = mode
+	}
+	launch := bs.Launch
+	b.mu.Unlock()
 
-	return &createWindowResult{WindowID: windowID, WindowName: windowName}, nil
+	text, keyboard := buildLaunchOptions(bs.CurrentPath, launch)
+	b.editMessageWithKeyboard(bs.ChatID, bs.MessageID, text, keyboard)
 }
 
-func (b *Bot) handleDirConfirm(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64) {
+func (b *Bot) handleDirConfirm(cq *tgbotapi.CallbackQuery, bs *BrowseState, userID int64, opts spawnOptions) {
+	opts.Launch = bs.Launch
 	selectedPath := bs.CurrentPath
 	pendingText := bs.PendingText
 	chatID := bs.ChatID
@@ -317,7 +471,7 @@ func (b *Bot) handleDirConfirm(cq *tgbotapi.CallbackQuery, bs *BrowseState, user
 	// Edit message to show progress
 	b.editMessageText(chatID, bs.MessageID, fmt.Sprintf("Creating session in %s...", shortenPath(selectedPath)))
 
-	result, err := b.createWindowForDir(selectedPath, userID, chatID, threadID)
+	result, err := b.createWindowForDirWith(selectedPath, userID, chatID, threadID, opts)
 	if err != nil {
 		log.Printf("Error creating window: %v", err)
 		b.editMessageText(chatID, bs.MessageID, "Error: failed to create session.")
@@ -329,7 +483,7 @@ func (b *Bot) handleDirConfirm(cq *tgbotapi.CallbackQuery, bs *BrowseState, user
 
 	// Send pending text
 	if pendingText != "" {
-		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, pendingText, 500); err != nil {
+		if err := b.backendFor(result.WindowID).SendText(result.WindowID, pendingText); err != nil {
 			log.Printf("Error sending pending text: %v", err)
 		}
 	}
@@ -115,6 +115,20 @@ func TestBuildDirectoryBrowser_ActionRow(t *testing.T) {
 	}
 }
 
+func TestBuildAgentPicker(t *testing.T) {
+	_, kb := buildAgentPicker(t.TempDir())
+
+	want := []string{"dir_agent:claude", "dir_agent:aider", "dir_cancel"}
+	if len(kb.InlineKeyboard) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(kb.InlineKeyboard))
+	}
+	for i, row := range kb.InlineKeyboard {
+		if got := *row[0].CallbackData; got != want[i] {
+			t.Errorf("row %d: got %s, want %s", i, got, want[i])
+		}
+	}
+}
+
 func TestTruncateName(t *testing.T) {
 	tests := []struct {
 		name   string
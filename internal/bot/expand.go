@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+const (
+	// expandPartLen is the size of each message the full text is split into.
+	expandPartLen = 3500
+	// expandMaxParts is how many messages are sent before falling back to a .txt document.
+	expandMaxParts = 4
+	// expandTitleLen caps the tool header repeated on each message.
+	expandTitleLen = 200
+)
+
+// processExpandCallback handles exp_<ref> "Show more" buttons on truncated
+// tool results and thinking blocks, and sub_<ref> "Subagent log" buttons on
+// Task calls: the full text is fetched from the monitor and posted in the topic.
+func (b *Bot) processExpandCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadID(cq.Message)
+
+	if b.statusPoller == nil || b.statusPoller.monitor == nil {
+		return
+	}
+	mon := b.statusPoller.monitor
+	var title, text string
+	var err error
+	if ref, ok := strings.CutPrefix(cq.Data, monitor.SubagentCallbackPrefix); ok {
+		title, text, err = mon.SubagentLog(ref)
+	} else {
+		title, text, err = mon.Expanded(strings.TrimPrefix(cq.Data, monitor.ExpandCallbackPrefix))
+	}
+	if err != nil {
+		if !errors.Is(err, monitor.ErrExpandGone) {
+			log.Printf("Error expanding %s: %v", cq.Data, err)
+		}
+		b.reply(chatID, threadID, "Full output is no longer available.")
+		return
+	}
+	b.sendFullText(chatID, threadID, title, text)
+}
+
+// processSendFileCallback handles file_<ref> "Send file" buttons on Write
+// results: the written file is sent as a document, as from /c_get.
+func (b *Bot) processSendFileCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	threadID := getThreadID(cq.Message)
+
+	if b.statusPoller == nil || b.statusPoller.monitor == nil {
+		return
+	}
+	path, err := b.statusPoller.monitor.WrittenFile(strings.TrimPrefix(cq.Data, monitor.FileCallbackPrefix))
+	if err != nil {
+		b.reply(chatID, threadID, "File is no longer available.")
+		return
+	}
+	if err := b.sendFile(chatID, threadID, path); err != nil {
+		log.Printf("Error sending %s: %v", path, err)
+		b.reply(chatID, threadID, fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// sendFullText posts text under title as a few messages, or as a .txt
+// document when it's longer.
+func (b *Bot) sendFullText(chatID int64, threadID int, title, text string) {
+	parts := expandParts(title, text)
+	if parts == nil {
+		filename := expandFilename(title)
+		if _, err := b.sendDocumentInThread(chatID, threadID, []byte(text), filename, tgbotapi.InlineKeyboardMarkup{}); err != nil {
+			log.Printf("Error sending %s: %v", filename, err)
+		}
+		return
+	}
+	for _, part := range parts {
+		if _, err := b.sendMessageInThread(chatID, threadID, part); err != nil {
+			log.Printf("Error sending expanded output: %v", err)
+			return
+		}
+	}
+}
+
+// expandParts splits the full text into titled messages, or returns nil if it
+// needs more than expandMaxParts and should be sent as a document.
+func expandParts(title, text string) []string {
+	if strings.TrimSpace(text) == "" {
+		text = "(No output)"
+	}
+	if len(title) > expandTitleLen {
+		title = title[:expandTitleLen] + "…"
+	}
+	chunks := render.SplitMessage(text, expandPartLen)
+	if len(chunks) > expandMaxParts {
+		return nil
+	}
+	parts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		header := title
+		if len(chunks) > 1 {
+			header = fmt.Sprintf("%s [%d/%d]", title, i+1, len(chunks))
+		}
+		parts[i] = header + "\n\n" + chunk
+	}
+	return parts
+}
+
+// expandFilename names the document for a title: "Bash(ls -la)" → "bash-output.txt".
+func expandFilename(title string) string {
+	if title == "Thinking" {
+		return "thinking.txt"
+	}
+	name, _, _ := strings.Cut(title, "(")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = "tool"
+	}
+	return name + "-output.txt"
+}
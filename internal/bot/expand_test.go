@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandParts(t *testing.T) {
+	parts := expandParts("Bash(ls)", "a\nb")
+	if len(parts) != 1 || parts[0] != "Bash(ls)\n\na\nb" {
+		t.Errorf("short output = %q", parts)
+	}
+
+	line := strings.Repeat("x", 100) + "\n"
+	parts = expandParts("Bash(ls)", strings.Repeat(line, 60))
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "Bash(ls) [2/2]\n\n") {
+		t.Errorf("medium output split into %d parts", len(parts))
+	}
+	for _, p := range parts {
+		if len(p) > 4096 {
+			t.Errorf("part of %d bytes exceeds Telegram's limit", len(p))
+		}
+	}
+
+	if parts := expandParts("Bash(ls)", strings.Repeat(line, 500)); parts != nil {
+		t.Errorf("long output should go to a document, got %d parts", len(parts))
+	}
+
+	if parts := expandParts("Thinking", " "); parts[0] != "Thinking\n\n(No output)" {
+		t.Errorf("empty output = %q", parts)
+	}
+}
+
+func TestExpandFilename(t *testing.T) {
+	tests := map[string]string{
+		"Bash(go test ./...)": "bash-output.txt",
+		"WebFetch(https://x)": "webfetch-output.txt",
+		"Thinking":            "thinking.txt",
+		"(odd)":               "tool-output.txt",
+	}
+	for title, want := range tests {
+		if got := expandFilename(title); got != want {
+			t.Errorf("expandFilename(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
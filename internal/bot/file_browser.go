@@ -229,33 +229,11 @@ func (b *Bot) handleGetSelect(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, u
 		return
 	}
 
-	// It's a file — stat for size check
-	info, err := os.Stat(fullPath)
-	if err != nil {
+	if err := b.sendFile(fs.ChatID, fs.ThreadID, fullPath); err != nil {
 		b.showFileBrowserError(fs, fmt.Sprintf("Error: %v", err))
 		return
 	}
 
-	const maxFileSize = 50 * 1024 * 1024 // 50MB
-	if info.Size() > maxFileSize {
-		b.showFileBrowserError(fs, fmt.Sprintf("File too large: %s (%d MB limit is 50 MB)",
-			entry.Name, info.Size()/(1024*1024)))
-		return
-	}
-
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		b.showFileBrowserError(fs, fmt.Sprintf("Error reading file: %v", err))
-		return
-	}
-
-	// Send file as document
-	_, err = b.sendDocumentInThread(fs.ChatID, fs.ThreadID, data, entry.Name, tgbotapi.InlineKeyboardMarkup{})
-	if err != nil {
-		b.showFileBrowserError(fs, fmt.Sprintf("Error sending file: %v", err))
-		return
-	}
-
 	// Success — edit browser message and clean up state
 	b.editMessageText(fs.ChatID, fs.MessageID, fmt.Sprintf("Sent: %s", entry.Name))
 
@@ -264,6 +242,29 @@ func (b *Bot) handleGetSelect(cq *tgbotapi.CallbackQuery, fs *FileBrowseState, u
 	b.mu.Unlock()
 }
 
+// maxSendFileSize is Telegram's upload limit for bots.
+const maxSendFileSize = 50 * 1024 * 1024 // 50MB
+
+// sendFile sends a file from disk as a document in a topic.
+func (b *Bot) sendFile(chatID int64, threadID int, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxSendFileSize {
+		return fmt.Errorf("%s is too large (%d MB, limit is 50 MB)", filepath.Base(path), info.Size()/(1024*1024))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	if _, err := b.sendDocumentInThread(chatID, threadID, data, filepath.Base(path), tgbotapi.InlineKeyboardMarkup{}); err != nil {
+		return fmt.Errorf("sending file: %w", err)
+	}
+	return nil
+}
+
 // showFileBrowserError shows an error in the browser message but keeps state alive.
 func (b *Bot) showFileBrowserError(fs *FileBrowseState, errMsg string) {
 	text, keyboard, entries := buildFileBrowser(fs.CurrentPath, fs.Page)
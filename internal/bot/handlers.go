@@ -1,16 +1,18 @@
 package bot
 
 import (
+	"errors"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
-// handleTextMessage forwards user text to the bound tmux window.
+// handleTextMessage forwards user text to the bound session.
 func (b *Bot) handleTextMessage(msg *tgbotapi.Message) {
 	userID := strconv.FormatInt(msg.From.ID, 10)
 	threadID := strconv.Itoa(getThreadID(msg))
@@ -48,14 +50,22 @@ func (b *Bot) handleTextMessage(msg *tgbotapi.Message) {
 		return
 	}
 
-	// Send text to tmux with 500ms delay before Enter
-	if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, text, 500); err != nil {
-		if tmux.IsWindowDead(err) {
+	b.submitText(msg, windowID, text)
+}
+
+// forwardText sends text to the session of a window, recovering a dead one.
+func (b *Bot) forwardText(msg *tgbotapi.Message, windowID, text string) {
+	if err := b.backendFor(windowID).SendText(windowID, text); err != nil {
+		if backend.IsDead(err) {
 			b.handleDeadWindow(msg, windowID, text)
 			return
 		}
 		log.Printf("Error sending keys to %s: %v", windowID, err)
-		b.reply(chatID, getThreadID(msg), "Error: failed to send to Claude session.")
+		if errors.Is(err, tmux.ErrPasteUnconfirmed) {
+			b.reply(msg.Chat.ID, getThreadID(msg), "The message was pasted but didn't show up in Claude's input, so it wasn't submitted. Check with /c_screenshot.")
+			return
+		}
+		b.reply(msg.Chat.ID, getThreadID(msg), "Error: failed to send to Claude session.")
 	}
 }
 
@@ -65,8 +75,8 @@ func (b *Bot) handleUnboundTopic(msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 	threadID := getThreadID(msg)
 
-	// Get unbound windows
-	windows, err := tmux.ListWindows(b.config.TmuxSessionName)
+	// Get unbound windows, tmux and headless
+	windows, err := b.liveWindows()
 	if err != nil {
 		log.Printf("Error listing windows: %v", err)
 		b.reply(chatID, threadID, "Error listing tmux windows.")
@@ -93,6 +103,10 @@ func (b *Bot) handleUnboundTopic(msg *tgbotapi.Message) {
 
 // handleBashCommand sends a ! command to Claude's bash mode.
 func (b *Bot) handleBashCommand(msg *tgbotapi.Message, windowID, text string) {
+	if backend.IsHeadless(windowID) {
+		b.reply(msg.Chat.ID, getThreadID(msg), "Bash mode needs a terminal; not available in headless sessions.")
+		return
+	}
 	session := b.config.TmuxSessionName
 
 	// Send ! first to enter bash mode
@@ -158,6 +172,30 @@ func (b *Bot) routeCallback(cq *tgbotapi.CallbackQuery) {
 		b.processPlannerCallback(cq, data)
 	case strings.HasPrefix(data, "approval_"):
 		b.processApprovalCallback(cq)
+	case strings.HasPrefix(data, "perm_"):
+		b.processPermissionCallback(cq)
+	case strings.HasPrefix(data, "ask_"):
+		b.processQuestionCallback(cq)
+	case strings.HasPrefix(data, "allow_"):
+		b.processAllowCallback(cq)
+	case strings.HasPrefix(data, "budget_"):
+		b.processBudgetCallback(cq)
+	case strings.HasPrefix(data, "verb_"):
+		b.processVerbosityCallback(cq)
+	case strings.HasPrefix(data, "exp_"), strings.HasPrefix(data, "sub_"):
+		b.processExpandCallback(cq)
+	case strings.HasPrefix(data, "file_"):
+		b.processSendFileCallback(cq)
+	case strings.HasPrefix(data, "voice_"):
+		b.processVoiceCallback(cq)
+	case strings.HasPrefix(data, "inq_"):
+		b.processInputQueueCallback(cq)
+	case strings.HasPrefix(data, "sched_"):
+		b.processScheduleCallback(cq)
+	case strings.HasPrefix(data, "snip_"):
+		b.processSnippetCallback(cq)
+	case strings.HasPrefix(data, "cmds_"):
+		b.processCmdsCallback(cq)
 	case strings.HasPrefix(data, "menu_"):
 		b.handleMenuCallback(cq)
 	case data == "noop":
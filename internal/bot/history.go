@@ -1,7 +1,6 @@
 package bot
 
 import (
-	"bufio"
 	"fmt"
 	"log"
 	"os"
@@ -111,6 +110,11 @@ func (b *Bot) findJSONLForWindow(windowID string) string {
 		return ""
 	}
 
+	return b.findJSONLForSession(sessionID)
+}
+
+// findJSONLForSession finds the JSONL transcript file for a Claude session ID.
+func (b *Bot) findJSONLForSession(sessionID string) string {
 	// Check monitor state for cached path
 	if b.monitorState != nil {
 		for _, key := range b.monitorState.AllKeys() {
@@ -155,11 +159,16 @@ func readAllEntries(path string) []historyEntry {
 
 	var entries []historyEntry
 	pending := make(map[string]monitor.PendingTool)
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024)
+	lr := monitor.NewLineReader(f)
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
+	for {
+		line, _, err := lr.Next()
+		if err == monitor.ErrLineTooLong {
+			continue
+		}
+		if err != nil {
+			break
+		}
 		if len(line) == 0 {
 			continue
 		}
@@ -241,6 +250,9 @@ func formatHistoryEntry(entry historyEntry) string {
 		text := truncateText(entry.Text, 60)
 		return "Thinking: " + text
 
+	case "image":
+		return "[image]"
+
 	default:
 		return truncateText(entry.Text, 100)
 	}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// HandleHookRequest answers hook events forwarded by `tramuntana hook` over
+// the hook socket. Set as the hooksock.Server handler by the serve command.
+func (b *Bot) HandleHookRequest(req hooksock.Request) hooksock.Response {
+	switch req.Event {
+	case "PreToolUse":
+		return b.handlePermissionRequest(req)
+	case "Stop":
+		if windowID := b.windowForHook(req); windowID != "" && b.statusPoller != nil {
+			b.statusPoller.FinishTurn(windowID)
+		}
+	case "SubagentStop":
+		if windowID := b.windowForHook(req); windowID != "" && b.statusPoller != nil {
+			b.statusPoller.KeepAlive(windowID)
+		}
+	case "Notification":
+		if windowID := b.windowForHook(req); windowID != "" {
+			b.notifyWindow(windowID, req.Message)
+		}
+	}
+	return hooksock.Response{}
+}
+
+// notifyWindow pings every topic bound to a window with a Claude notification
+// ("Claude is waiting for your input"). Skipped while a Telegram permission
+// prompt for the window is open, since that message already asks.
+func (b *Bot) notifyWindow(windowID, message string) {
+	if message == "" || hasPendingPermission(windowID) {
+		return
+	}
+	b.postToWindow(windowID, "\U0001F514 "+message)
+}
+
+// postToWindow queues a text message to every topic bound to a window.
+func (b *Bot) postToWindow(windowID, text string) {
+	for _, t := range b.windowTopics(windowID) {
+		if b.msgQueue == nil {
+			b.reply(t.ChatID, t.ThreadID, text)
+			continue
+		}
+		b.msgQueue.Enqueue(queue.MessageTask{
+			UserID:      t.UserID,
+			ThreadID:    t.ThreadID,
+			ChatID:      t.ChatID,
+			Parts:       []string{text},
+			ContentType: "content",
+			WindowID:    windowID,
+		})
+	}
+}
+
+// windowTopic is a topic bound to a window, with its group chat.
+type windowTopic struct {
+	UserID   int64
+	ThreadID int
+	ChatID   int64
+}
+
+// windowTopics returns the topics bound to a window whose chat ID is known.
+func (b *Bot) windowTopics(windowID string) []windowTopic {
+	var topics []windowTopic
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		topics = append(topics, windowTopic{userID, threadID, chatID})
+	}
+	return topics
+}
+
+// windowForHook resolves the window a hook event came from: by tmux window
+// when the hook ran in our tmux session, else by session ID (headless sessions).
+func (b *Bot) windowForHook(req hooksock.Request) string {
+	prefix := b.config.TmuxSessionName + ":"
+	if strings.HasPrefix(req.Window, prefix) {
+		return strings.TrimPrefix(req.Window, prefix)
+	}
+	if req.SessionID != "" {
+		if windowID, ok := b.state.FindWindowBySession(req.SessionID); ok {
+			return windowID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// inputPreviewLen caps each queued message in the "Queued (N)" list.
+const inputPreviewLen = 60
+
+// topicKey identifies a topic across chats.
+type topicKey struct {
+	ChatID   int64
+	ThreadID int
+}
+
+// queuedInput is a message held until Claude's turn ends.
+type queuedInput struct {
+	ID   string
+	Text string
+	Msg  *tgbotapi.Message // the original message, for dead-session recovery
+}
+
+// inputDeliveryBuffer is how many dequeued messages can wait for the
+// delivery worker before the status poller blocks.
+const inputDeliveryBuffer = 64
+
+// inputDelivery is a dequeued message on its way to a window.
+type inputDelivery struct {
+	Key      topicKey
+	WindowID string
+	Item     queuedInput
+}
+
+// inputQueue holds a topic's messages while Claude is working.
+type inputQueue struct {
+	WindowID  string
+	Items     []queuedInput
+	MessageID int // the "Queued (N)" message, 0 if not sent yet
+}
+
+// index returns the position of an item, or -1.
+func (q *inputQueue) index(id string) int {
+	return slices.IndexFunc(q.Items, func(it queuedInput) bool { return it.ID == id })
+}
+
+// remove takes an item out of the queue.
+func (q *inputQueue) remove(id string) (queuedInput, bool) {
+	i := q.index(id)
+	if i < 0 {
+		return queuedInput{}, false
+	}
+	it := q.Items[i]
+	q.Items = slices.Delete(q.Items, i, i+1)
+	return it, true
+}
+
+// moveUp swaps an item with the one before it.
+func (q *inputQueue) moveUp(id string) bool {
+	i := q.index(id)
+	if i <= 0 {
+		return false
+	}
+	q.Items[i-1], q.Items[i] = q.Items[i], q.Items[i-1]
+	return true
+}
+
+// render builds the "Queued (N)" message and its per-item buttons.
+func (q *inputQueue) render() (string, tgbotapi.InlineKeyboardMarkup) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📥 Queued (%d) — sent in order when Claude finishes", len(q.Items))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, it := range q.Items {
+		n := strconv.Itoa(i + 1)
+		preview := strings.Join(strings.Fields(it.Text), " ")
+		if r := []rune(preview); len(r) > inputPreviewLen {
+			preview = string(r[:inputPreviewLen]) + "…"
+		}
+		fmt.Fprintf(&sb, "\n%s. %s", n, preview)
+
+		row := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("✖ "+n, "inq_del:"+it.ID),
+		}
+		if i > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("↑ "+n, "inq_up:"+it.ID))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⚡ Send "+n+" now", "inq_now:"+it.ID))
+		rows = append(rows, row)
+	}
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// submitText forwards text to a window, or holds it in the topic's input
+// queue if the queue is on and Claude is busy (or earlier messages are still
+// waiting).
+func (b *Bot) submitText(msg *tgbotapi.Message, windowID, text string) {
+	sp := b.statusPoller
+	if sp == nil || !b.state.InputQueueEnabled(strconv.Itoa(getThreadID(msg))) {
+		b.forwardText(msg, windowID, text)
+		return
+	}
+
+	key := topicKey{msg.Chat.ID, getThreadID(msg)}
+	b.inputMu.Lock()
+	q := b.inputQueues[key]
+	if (q == nil || len(q.Items) == 0) && !sp.Busy(windowID) {
+		sp.MarkBusy(windowID)
+		b.inputMu.Unlock()
+		b.forwardText(msg, windowID, text)
+		return
+	}
+	if q == nil {
+		if b.inputQueues == nil {
+			b.inputQueues = make(map[topicKey]*inputQueue)
+		}
+		q = &inputQueue{}
+		b.inputQueues[key] = q
+	}
+	b.nextQueuedInput++
+	q.WindowID = windowID
+	q.Items = append(q.Items, queuedInput{ID: strconv.FormatUint(b.nextQueuedInput, 36), Text: text, Msg: msg})
+	b.inputMu.Unlock()
+	b.showInputQueue(key)
+}
+
+// showInputQueue sends or updates the topic's "Queued (N)" message, or
+// deletes it and drops the queue once it's empty. The queue is read under
+// inputMu but the Telegram calls are made without it; inputViewMu keeps
+// refreshes in order so the message always ends up showing the latest state.
+func (b *Bot) showInputQueue(key topicKey) {
+	b.inputViewMu.Lock()
+	defer b.inputViewMu.Unlock()
+
+	b.inputMu.Lock()
+	q, ok := b.inputQueues[key]
+	if !ok {
+		b.inputMu.Unlock()
+		return
+	}
+	empty := len(q.Items) == 0
+	if empty {
+		delete(b.inputQueues, key)
+	}
+	messageID := q.MessageID
+	text, kb := q.render()
+	b.inputMu.Unlock()
+
+	if empty {
+		if messageID != 0 {
+			b.deleteMessage(key.ChatID, messageID)
+		}
+		return
+	}
+	if messageID != 0 {
+		if err := b.editMessageWithKeyboard(key.ChatID, messageID, text, kb); err == nil {
+			return
+		}
+	}
+	sent, err := b.sendMessageWithKeyboard(key.ChatID, key.ThreadID, text, kb)
+	if err != nil {
+		log.Printf("Error sending input queue: %v", err)
+		return
+	}
+	// Only a refresh drops the queue, so q is still the topic's queue here
+	b.inputMu.Lock()
+	q.MessageID = sent.MessageID
+	b.inputMu.Unlock()
+}
+
+// deliverQueuedInput takes the next queued message of a topic bound to a
+// window once Claude is idle, and hands it to the delivery worker. One message
+// per turn: delivering it starts the next turn. Called from the status
+// poller, so it makes no Telegram or backend calls itself.
+func (b *Bot) deliverQueuedInput(windowID string) {
+	sp := b.statusPoller
+	if sp == nil {
+		return
+	}
+	b.inputMu.Lock()
+	if sp.Busy(windowID) {
+		b.inputMu.Unlock()
+		return
+	}
+	var next *inputDelivery
+	for key, q := range b.inputQueues {
+		if q.WindowID != windowID || len(q.Items) == 0 {
+			continue
+		}
+		next = &inputDelivery{Key: key, WindowID: windowID, Item: q.Items[0]}
+		q.Items = q.Items[1:]
+		sp.MarkBusy(windowID)
+		break
+	}
+	b.inputMu.Unlock()
+
+	if next != nil {
+		b.inputDeliveries <- *next
+	}
+}
+
+// runInputDelivery forwards dequeued messages and refreshes their topic's
+// queue message, one at a time, until ctx is cancelled.
+func (b *Bot) runInputDelivery(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-b.inputDeliveries:
+			b.showInputQueue(d.Key)
+			b.forwardText(d.Item.Msg, d.WindowID, d.Item.Text)
+		}
+	}
+}
+
+// dropInputQueue discards a topic's queued messages.
+func (b *Bot) dropInputQueue(chatID int64, threadID int) {
+	key := topicKey{chatID, threadID}
+	b.inputMu.Lock()
+	q, ok := b.inputQueues[key]
+	if ok {
+		q.Items = nil
+	}
+	b.inputMu.Unlock()
+	if ok {
+		b.showInputQueue(key)
+	}
+}
+
+// processInputQueueCallback handles the cancel, move up and send now
+// buttons of a topic's queued messages.
+func (b *Bot) processInputQueueCallback(cq *tgbotapi.CallbackQuery) {
+	action, id, _ := strings.Cut(strings.TrimPrefix(cq.Data, "inq_"), ":")
+	key := topicKey{cq.Message.Chat.ID, getThreadID(cq.Message)}
+
+	b.inputMu.Lock()
+	q, ok := b.inputQueues[key]
+	if !ok {
+		b.inputMu.Unlock()
+		b.editMessageText(key.ChatID, cq.Message.MessageID, "Queue is empty.")
+		return
+	}
+	var now queuedInput
+	switch action {
+	case "del":
+		q.remove(id)
+	case "up":
+		q.moveUp(id)
+	case "now":
+		now, ok = q.remove(id)
+	}
+	windowID := q.WindowID
+	b.inputMu.Unlock()
+	b.showInputQueue(key)
+
+	if action == "now" && ok {
+		b.interruptWith(now, windowID)
+	}
+}
+
+// interruptWith stops Claude's current turn with Escape and sends a queued
+// message in its place.
+func (b *Bot) interruptWith(it queuedInput, windowID string) {
+	if err := b.backendFor(windowID).SendKey(windowID, "Escape"); err != nil {
+		log.Printf("Error sending Escape to %s: %v", windowID, err)
+	}
+	time.Sleep(time.Second) // let the TUI return to its prompt
+	if b.statusPoller != nil {
+		b.statusPoller.MarkBusy(windowID)
+	}
+	b.forwardText(it.Msg, windowID, it.Text)
+}
+
+const inputQueueUsage = "Usage: /c_queue <on|off>\n\n" +
+	"on — hold messages sent while Claude is working and send them in order when the turn ends\n" +
+	"off — type messages into the session right away"
+
+// handleQueueCommand shows or sets whether the topic queues input while Claude works.
+func (b *Bot) handleQueueCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+
+	switch strings.TrimSpace(msg.CommandArguments()) {
+	case "":
+		status := "off"
+		if b.state.InputQueueEnabled(threadIDStr) {
+			status = "on"
+		}
+		b.reply(chatID, threadID, "Input queue for this topic: "+status+"\n\n"+inputQueueUsage)
+	case "on":
+		b.state.SetInputQueue(threadIDStr, true)
+		b.saveState()
+		b.reply(chatID, threadID, "Input queue on: messages sent while Claude is working wait for the turn to end.")
+	case "off":
+		b.state.SetInputQueue(threadIDStr, false)
+		b.saveState()
+		b.reply(chatID, threadID, "Input queue off. Messages already queued are still delivered.")
+	default:
+		b.reply(chatID, threadID, inputQueueUsage)
+	}
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInputQueue_Reorder(t *testing.T) {
+	q := &inputQueue{Items: []queuedInput{{ID: "a", Text: "one"}, {ID: "b", Text: "two"}, {ID: "c", Text: "three"}}}
+
+	if q.moveUp("a") {
+		t.Error("the first item can't move up")
+	}
+	if !q.moveUp("c") {
+		t.Error("moveUp failed")
+	}
+	if it, ok := q.remove("b"); !ok || it.Text != "two" {
+		t.Errorf("remove = %+v, %v", it, ok)
+	}
+	if _, ok := q.remove("b"); ok {
+		t.Error("removing twice should fail")
+	}
+	if len(q.Items) != 2 || q.Items[0].ID != "a" || q.Items[1].ID != "c" {
+		t.Errorf("items = %+v", q.Items)
+	}
+}
+
+func TestInputQueue_Render(t *testing.T) {
+	q := &inputQueue{Items: []queuedInput{
+		{ID: "a", Text: "fix the\nfailing test"},
+		{ID: "b", Text: strings.Repeat("x", 100)},
+	}}
+	text, kb := q.render()
+	if !strings.HasPrefix(text, "📥 Queued (2)") || !strings.Contains(text, "\n1. fix the failing test") {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(text, "\n2. "+strings.Repeat("x", inputPreviewLen)+"…") {
+		t.Errorf("long preview not truncated: %q", text)
+	}
+	if len(kb.InlineKeyboard) != 2 {
+		t.Fatalf("rows = %d, want one per item", len(kb.InlineKeyboard))
+	}
+	if len(kb.InlineKeyboard[0]) != 2 || len(kb.InlineKeyboard[1]) != 3 {
+		t.Errorf("first row should have no move up button: %d, %d buttons", len(kb.InlineKeyboard[0]), len(kb.InlineKeyboard[1]))
+	}
+	if got := *kb.InlineKeyboard[1][1].CallbackData; got != "inq_up:b" {
+		t.Errorf("move up callback = %q", got)
+	}
+}
+
+func TestStatusPoller_Busy(t *testing.T) {
+	b := newTestBot(t)
+	sp := NewStatusPoller(b, nil, nil)
+	b.statusPoller = sp
+
+	if sp.Busy("@1") {
+		t.Error("new window should be idle")
+	}
+	sp.MarkBusy("@1")
+	if !sp.Busy("@1") {
+		t.Error("window should be busy right after a message is sent")
+	}
+
+	sp.submitted["@1"] = time.Now().Add(-time.Minute)
+	sp.updateWorking("@1", true, false)
+	if !sp.Busy("@1") {
+		t.Error("a status line should mark the window busy")
+	}
+	sp.missCount["@1"] = missThreshold
+	sp.updateWorking("@1", false, false)
+	if sp.Busy("@1") {
+		t.Error("window should be idle once the status line is gone")
+	}
+
+	sp.updateWorking("@1", false, true)
+	sp.FinishTurn("@1")
+	if sp.Busy("@1") {
+		t.Error("Stop should end the turn")
+	}
+	sp.updateWorking("@1", true, false)
+	if sp.Busy("@1") {
+		t.Error("the final spinner frame after Stop shouldn't restart the turn")
+	}
+}
+
+func TestDeliverQueuedInput_HandsOffToWorker(t *testing.T) {
+	b := newTestBot(t)
+	sp := NewStatusPoller(b, nil, nil)
+	b.statusPoller = sp
+	b.inputDeliveries = make(chan inputDelivery, 1)
+	key := topicKey{ChatID: -100, ThreadID: 5}
+	b.inputQueues = map[topicKey]*inputQueue{
+		key: {WindowID: "@1", Items: []queuedInput{{ID: "a", Text: "one"}, {ID: "b", Text: "two"}}},
+	}
+
+	b.deliverQueuedInput("@1")
+	select {
+	case d := <-b.inputDeliveries:
+		if d.Key != key || d.WindowID != "@1" || d.Item.Text != "one" {
+			t.Errorf("delivery = %+v", d)
+		}
+	default:
+		t.Fatal("the first queued message should be handed to the worker")
+	}
+	if !sp.Busy("@1") {
+		t.Error("the window should count as busy once a message is dequeued")
+	}
+
+	b.deliverQueuedInput("@1")
+	if len(b.inputDeliveries) != 0 {
+		t.Error("only one message per turn")
+	}
+	if q := b.inputQueues[key]; len(q.Items) != 1 || q.Items[0].ID != "b" {
+		t.Errorf("remaining = %+v", q.Items)
+	}
+}
@@ -10,8 +10,8 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
-	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 var interactiveRetryRe = regexp.MustCompile(`retry after (\d+)`)
@@ -36,19 +36,26 @@ var interactive = &interactiveState{
 
 // handleInteractiveUI captures pane, detects interactive content, and sends/updates keyboard.
 func (b *Bot) handleInteractiveUI(chatID int64, threadID int, userID int64, windowID string) {
-	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, false)
+	paneText, err := b.backendFor(windowID).Capture(windowID, false)
 	if err != nil {
-		if tmux.IsWindowDead(err) {
+		if backend.IsDead(err) {
 			log.Printf("Interactive UI: window %s is dead", windowID)
 			clearInteractiveUI(userID, threadID)
 		}
 		return
 	}
 
-	ui, ok := monitor.ExtractInteractiveContent(paneText)
+	ui, ok := monitor.MatchUIPatterns(paneText, b.agentFor(windowID).UIPatterns)
 	if !ok {
 		return
 	}
+	if ui.Name == "PermissionPrompt" && b.autoApprovePane(windowID, paneText) {
+		return
+	}
+	if (strings.HasPrefix(ui.Name, "AskUserQuestion") || ui.Name == "ExitPlanMode") && hasQuestionPrompt(windowID) {
+		// Already shown with one button per option
+		return
+	}
 
 	keyboard := buildInteractiveKeyboard(ui.Name)
 	text := formatInteractiveContent(ui)
@@ -119,10 +126,10 @@ func (b *Bot) handleInteractiveCallback(cq *tgbotapi.CallbackQuery) {
 	}
 
 	data := cq.Data
-	session := b.config.TmuxSessionName
+	be := b.backendFor(windowID)
 
 	sendKey := func(key string) error {
-		return tmux.SendSpecialKey(session, windowID, key)
+		return be.SendKey(windowID, key)
 	}
 
 	var sendErr error
@@ -147,6 +154,17 @@ func (b *Bot) handleInteractiveCallback(cq *tgbotapi.CallbackQuery) {
 		sendErr = sendKey("Enter")
 		clearInteractiveUI(userID, threadID)
 		return
+	case data == "nav_yes", data == "nav_no":
+		// Line-based confirms (e.g. Aider's Yes/No) take a typed answer
+		answer := "y"
+		if data == "nav_no" {
+			answer = "n"
+		}
+		if err := be.SendText(windowID, answer); err != nil && backend.IsDead(err) {
+			log.Printf("Interactive callback: window %s is dead", windowID)
+		}
+		clearInteractiveUI(userID, threadID)
+		return
 	case data == "nav_refresh":
 		// Just refresh, no key sent
 	default:
@@ -154,7 +172,7 @@ func (b *Bot) handleInteractiveCallback(cq *tgbotapi.CallbackQuery) {
 	}
 
 	if sendErr != nil {
-		if tmux.IsWindowDead(sendErr) {
+		if backend.IsDead(sendErr) {
 			log.Printf("Interactive callback: window %s is dead", windowID)
 			clearInteractiveUI(userID, threadID)
 		}
@@ -199,7 +217,15 @@ func retryOnFlood(fn func() error) error {
 func buildInteractiveKeyboard(uiType string) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
-	if uiType == "RestoreCheckpoint" {
+	if uiType == "Confirm" {
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Yes", "nav_yes"),
+				tgbotapi.NewInlineKeyboardButtonData("No", "nav_no"),
+				tgbotapi.NewInlineKeyboardButtonData("\U0001F504", "nav_refresh"),
+			),
+		)
+	} else if uiType == "RestoreCheckpoint" {
 		// Vertical-only layout
 		rows = append(rows,
 			tgbotapi.NewInlineKeyboardRow(
@@ -36,6 +36,17 @@ func TestBuildInteractiveKeyboard_RestoreCheckpoint(t *testing.T) {
 	}
 }
 
+func TestBuildInteractiveKeyboard_Confirm(t *testing.T) {
+	kb := buildInteractiveKeyboard("Confirm")
+	if len(kb.InlineKeyboard) != 1 {
+		t.Fatalf("expected 1 row for Confirm, got %d", len(kb.InlineKeyboard))
+	}
+	row := kb.InlineKeyboard[0]
+	if *row[0].CallbackData != "nav_yes" || *row[1].CallbackData != "nav_no" {
+		t.Errorf("expected Yes/No buttons, got %q %q", *row[0].CallbackData, *row[1].CallbackData)
+	}
+}
+
 func TestFormatInteractiveContent(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,330 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/agent"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// launchModels are the model aliases offered in the directory browser; any
+// name Claude Code accepts can be set with /c_model.
+var launchModels = []string{"opus", "sonnet", "haiku"}
+
+// permissionModes are the values of Claude Code's --permission-mode.
+var permissionModes = []string{"default", "acceptEdits", "plan", "bypassPermissions"}
+
+// reModelName matches a model alias or full model name.
+var reModelName = regexp.MustCompile(`^[A-Za-z0-9._\[\]-]+$`)
+
+const modelUsage = "Usage: /c_model <model>\n" +
+	"e.g. /c_model opus, /c_model sonnet, /c_model haiku, or a full model name\n" +
+	"/c_model default — use the model CLAUDE_COMMAND picks\n\n" +
+	"The session restarts and resumes its conversation."
+
+const modeUsage = "Usage: /c_mode <default|acceptEdits|plan|bypassPermissions>\n" +
+	"/c_mode tools <tools> — allowed tools, e.g. /c_mode tools Bash(git:*) Edit\n" +
+	"/c_mode flags <flags> — extra launch flags, quoted as in a shell, e.g. /c_mode flags --append-system-prompt \"be terse\"\n" +
+	"Use - to clear tools or flags.\n\n" +
+	"The session restarts and resumes its conversation."
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// splitShellWords splits s into words the way sh does, honouring single and
+// double quotes and backslash escapes but expanding nothing. Unbalanced quotes
+// or a trailing backslash are an error.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(rs) && strings.ContainsRune("$`\"\\\n", rs[i+1]):
+				i++
+				word.WriteRune(rs[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			if i+1 == len(rs) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteRune(rs[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unbalanced %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// launchArgs renders a launch profile as Claude Code flags, to append to the
+// launch command.
+func launchArgs(p state.LaunchProfile) string {
+	var args []string
+	if p.Model != "" {
+		args = append(args, "--model", shellQuote(p.Model))
+	}
+	if p.PermissionMode != "" {
+		args = append(args, "--permission-mode", shellQuote(p.PermissionMode))
+	}
+	if p.AllowedTools != "" {
+		args = append(args, "--allowedTools", shellQuote(p.AllowedTools))
+	}
+	// Flags are split into shell words, each quoted, so they can't run shell
+	// syntax. /c_mode refuses flags that don't split.
+	flags, err := splitShellWords(p.Flags)
+	if err != nil {
+		log.Printf("Ignoring launch flags %q: %v", p.Flags, err)
+	}
+	for _, f := range flags {
+		args = append(args, shellQuote(f))
+	}
+	return strings.Join(args, " ")
+}
+
+// describeLaunch renders a launch profile for messages.
+func describeLaunch(p state.LaunchProfile) string {
+	if p == (state.LaunchProfile{}) {
+		return "the default launch options"
+	}
+	var parts []string
+	if p.Model != "" {
+		parts = append(parts, "model "+p.Model)
+	}
+	if p.PermissionMode != "" {
+		parts = append(parts, "permission mode "+p.PermissionMode)
+	}
+	if p.AllowedTools != "" {
+		parts = append(parts, "allowed tools "+p.AllowedTools)
+	}
+	if p.Flags != "" {
+		parts = append(parts, "flags "+p.Flags)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleModelCommand shows or sets the model of the topic's session.
+func (b *Bot) handleModelCommand(msg *tgbotapi.Message) {
+	arg := strings.TrimSpace(msg.CommandArguments())
+	switch {
+	case arg == "":
+		b.showLaunchProfile(msg, modelUsage)
+	case arg != "default" && !reModelName.MatchString(arg):
+		b.reply(msg.Chat.ID, getThreadID(msg), "Invalid model name.\n\n"+modelUsage)
+	default:
+		if arg == "default" {
+			arg = ""
+		}
+		b.updateLaunchProfile(msg, func(p *state.LaunchProfile) { p.Model = arg })
+	}
+}
+
+// handleModeCommand shows or sets the permission mode, allowed tools or
+// extra flags of the topic's session.
+func (b *Bot) handleModeCommand(msg *tgbotapi.Message) {
+	args := strings.TrimSpace(msg.CommandArguments())
+	verb, rest, _ := strings.Cut(args, " ")
+	rest = strings.TrimSpace(rest)
+	if rest == "-" {
+		rest = ""
+	}
+
+	switch {
+	case args == "":
+		b.showLaunchProfile(msg, modeUsage)
+	case verb == "tools":
+		b.updateLaunchProfile(msg, func(p *state.LaunchProfile) { p.AllowedTools = rest })
+	case verb == "flags":
+		if _, err := splitShellWords(rest); err != nil {
+			b.reply(msg.Chat.ID, getThreadID(msg), "Invalid flags: "+err.Error()+".\n\n"+modeUsage)
+			return
+		}
+		b.updateLaunchProfile(msg, func(p *state.LaunchProfile) { p.Flags = rest })
+	case slices.Contains(permissionModes, args):
+		b.updateLaunchProfile(msg, func(p *state.LaunchProfile) {
+			p.PermissionMode = strings.TrimPrefix(args, "default")
+		})
+	default:
+		b.reply(msg.Chat.ID, getThreadID(msg), "Unknown permission mode.\n\n"+modeUsage)
+	}
+}
+
+// showLaunchProfile replies with the launch options of the topic's session.
+func (b *Bot) showLaunchProfile(msg *tgbotapi.Message, usage string) {
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(msg.Chat.ID, getThreadID(msg), "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+	ws, _ := b.state.GetWindowState(windowID)
+	b.reply(msg.Chat.ID, getThreadID(msg), "This session runs with "+describeLaunch(ws.LaunchProfile)+".\n\n"+usage)
+}
+
+// updateLaunchProfile applies a change to the launch profile of the topic's
+// session and restarts it with the result.
+func (b *Bot) updateLaunchProfile(msg *tgbotapi.Message, change func(*state.LaunchProfile)) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+	if b.agentFor(windowID) != agent.Claude {
+		b.reply(chatID, threadID, "Launch options only apply to Claude Code sessions.")
+		return
+	}
+
+	ws, _ := b.state.GetWindowState(windowID)
+	launch := ws.LaunchProfile
+	change(&launch)
+	if launch == ws.LaunchProfile {
+		b.reply(chatID, threadID, "Already running with "+describeLaunch(launch)+".")
+		return
+	}
+	b.relaunchWithProfile(msg, windowID, launch)
+}
+
+// relaunchWithProfile restarts a window with a new launch profile, resuming
+// its conversation, and rebinds every topic that was bound to it.
+func (b *Bot) relaunchWithProfile(msg *tgbotapi.Message, windowID string, launch state.LaunchProfile) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		b.reply(chatID, threadID, "Error: the session's working directory is unknown.")
+		return
+	}
+	b.reply(chatID, threadID, "Restarting with "+describeLaunch(launch)+"...")
+
+	// Save what cleanup drops before the window goes away
+	resume := b.resumableSession(windowID)
+	displayName, _ := b.state.GetWindowDisplayName(windowID)
+	bindings := b.state.FindUsersForWindow(windowID)
+	chatIDs := make(map[state.UserThread]int64)
+	for _, ut := range bindings {
+		if cid, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID); ok {
+			chatIDs[ut] = cid
+		}
+	}
+
+	if err := b.backendFor(windowID).Kill(windowID); err != nil {
+		log.Printf("Error killing %s for relaunch: %v", windowID, err)
+	}
+	cleanupDeadWindow(b, windowID)
+	for ut, cid := range chatIDs {
+		b.state.SetGroupChatID(ut.UserID, ut.ThreadID, cid)
+	}
+	b.saveState()
+
+	newID, err := b.spawnWindow(ws.CWD, spawnOptions{
+		Name: displayName, Backend: ws.Backend, Agent: ws.Agent, Resume: resume, Launch: launch,
+	})
+	if err != nil {
+		log.Printf("Error relaunching %s in %s: %v", windowID, ws.CWD, err)
+		b.reply(chatID, threadID, "Failed to restart. Send a message to start a new session.")
+		return
+	}
+	if displayName != "" {
+		b.state.SetWindowDisplayName(newID, displayName)
+	}
+	for _, ut := range bindings {
+		b.state.BindThread(ut.UserID, ut.ThreadID, newID)
+	}
+	b.saveState()
+
+	if resume == nil {
+		b.reply(chatID, threadID, "Restarted. There was no conversation to resume, so this is a new one.")
+		return
+	}
+	b.reply(chatID, threadID, "Restarted, conversation resumed.")
+}
+
+// buildLaunchOptions builds the launch options step of the directory browser:
+// a model and a permission mode for the new session.
+func buildLaunchOptions(dir string, launch state.LaunchProfile) (string, tgbotapi.InlineKeyboardMarkup) {
+	mark := func(label string, selected bool) string {
+		if selected {
+			return "✓ " + label
+		}
+		return label
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	modelRow := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(mark("default", launch.Model == ""), "dir_model:"),
+	}
+	for _, m := range launchModels {
+		modelRow = append(modelRow, tgbotapi.NewInlineKeyboardButtonData(mark(m, launch.Model == m), "dir_model:"+m))
+	}
+	rows = append(rows, modelRow)
+
+	var modeRow []tgbotapi.InlineKeyboardButton
+	for _, m := range permissionModes {
+		value := strings.TrimPrefix(m, "default")
+		modeRow = append(modeRow, tgbotapi.NewInlineKeyboardButtonData(mark(m, launch.PermissionMode == value), "dir_mode:"+value))
+		if len(modeRow) == 2 {
+			rows = append(rows, modeRow)
+			modeRow = nil
+		}
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("..", "dir_back"),
+		tgbotapi.NewInlineKeyboardButtonData("Start", "dir_confirm"),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "dir_cancel"),
+	))
+
+	model, mode := launch.Model, launch.PermissionMode
+	if model == "" {
+		model = "default"
+	}
+	if mode == "" {
+		mode = "default"
+	}
+	text := fmt.Sprintf("Launch options for %s:\nModel: %s\nPermission mode: %s\n\n"+
+		"Allowed tools and extra flags can be set later with /c_mode.", shortenPath(dir), model, mode)
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestLaunchArgs(t *testing.T) {
+	tests := []struct {
+		profile state.LaunchProfile
+		want    string
+	}{
+		{state.LaunchProfile{}, ""},
+		{state.LaunchProfile{Model: "opus"}, "--model 'opus'"},
+		{
+			state.LaunchProfile{Model: "sonnet", PermissionMode: "acceptEdits", AllowedTools: "Bash(git:*) Edit", Flags: "--verbose"},
+			"--model 'sonnet' --permission-mode 'acceptEdits' --allowedTools 'Bash(git:*) Edit' '--verbose'",
+		},
+		{state.LaunchProfile{Flags: "--add-dir ../shared  --verbose"}, "'--add-dir' '../shared' '--verbose'"},
+		{state.LaunchProfile{Flags: "--verbose; rm -rf ~ $(id)"}, "'--verbose;' 'rm' '-rf' '~' '$(id)'"},
+		{state.LaunchProfile{AllowedTools: "Bash(echo 'hi')"}, `--allowedTools 'Bash(echo '\''hi'\'')'`},
+		{state.LaunchProfile{Flags: `--append-system-prompt "be terse"`}, `'--append-system-prompt' 'be terse'`},
+		{state.LaunchProfile{Flags: `--verbose "unbalanced`}, ""},
+	}
+	for _, tt := range tests {
+		if got := launchArgs(tt.profile); got != tt.want {
+			t.Errorf("launchArgs(%+v) = %q, want %q", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"  --verbose   --debug ", []string{"--verbose", "--debug"}},
+		{`--append-system-prompt "be terse"`, []string{"--append-system-prompt", "be terse"}},
+		{`--x 'it''s' a\ b`, []string{"--x", "its", "a b"}},
+		{`"say \"hi\" \n"`, []string{`say "hi" \n`}},
+		{`--empty ""`, []string{"--empty", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitShellWords(tt.in)
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitShellWords(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{`"be terse`, `'open`, `trailing\`} {
+		if _, err := splitShellWords(bad); err == nil {
+			t.Errorf("splitShellWords(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDescribeLaunch(t *testing.T) {
+	if got := describeLaunch(state.LaunchProfile{}); got != "the default launch options" {
+		t.Errorf("zero profile = %q", got)
+	}
+	got := describeLaunch(state.LaunchProfile{Model: "opus", PermissionMode: "plan"})
+	if got != "model opus, permission mode plan" {
+		t.Errorf("describeLaunch = %q", got)
+	}
+}
+
+func TestBuildLaunchOptions(t *testing.T) {
+	text, kb := buildLaunchOptions(t.TempDir(), state.LaunchProfile{Model: "opus", PermissionMode: "plan"})
+	if !strings.Contains(text, "Model: opus") || !strings.Contains(text, "Permission mode: plan") {
+		t.Errorf("unexpected text: %q", text)
+	}
+
+	selected := map[string]bool{}
+	for _, row := range kb.InlineKeyboard {
+		for _, btn := range row {
+			if strings.HasPrefix(btn.Text, "✓ ") {
+				selected[*btn.CallbackData] = true
+			}
+		}
+	}
+	if len(selected) != 2 || !selected["dir_model:opus"] || !selected["dir_mode:plan"] {
+		t.Errorf("expected opus and plan to be marked, got %v", selected)
+	}
+
+	last := kb.InlineKeyboard[len(kb.InlineKeyboard)-1]
+	want := []string{"dir_back", "dir_confirm", "dir_cancel"}
+	for i, btn := range last {
+		if *btn.CallbackData != want[i] {
+			t.Errorf("action button %d = %s, want %s", i, *btn.CallbackData, want[i])
+		}
+	}
+
+	// Defaults are marked when nothing is chosen
+	_, kb = buildLaunchOptions(t.TempDir(), state.LaunchProfile{})
+	if !strings.HasPrefix(kb.InlineKeyboard[0][0].Text, "✓ default") || !strings.HasPrefix(kb.InlineKeyboard[1][0].Text, "✓ default") {
+		t.Errorf("defaults should be marked: %q, %q", kb.InlineKeyboard[0][0].Text, kb.InlineKeyboard[1][0].Text)
+	}
+}
@@ -22,12 +22,15 @@ func buildMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Screenshot", "menu_c_screenshot"),
 			tgbotapi.NewInlineKeyboardButtonData("Esc", "menu_c_esc"),
+			tgbotapi.NewInlineKeyboardButtonData("Verbosity", "menu_c_verbosity"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Clear", "menu_c_clear"),
 			tgbotapi.NewInlineKeyboardButtonData("Help", "menu_c_help"),
 			tgbotapi.NewInlineKeyboardButtonData("Get", "menu_c_get"),
+			tgbotapi.NewInlineKeyboardButtonData("Allow", "menu_c_allow"),
 		),
+
 		// Project header
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("── Project ──", "noop"),
@@ -40,6 +43,7 @@ func buildMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 			tgbotapi.NewInlineKeyboardButtonData("Add", "menu_p_add"),
 			tgbotapi.NewInlineKeyboardButtonData("Delete", "menu_p_delete"),
 			tgbotapi.NewInlineKeyboardButtonData("History", "menu_p_history"),
+			tgbotapi.NewInlineKeyboardButtonData("Usage", "menu_c_usage"),
 		),
 		// Task Execution header
 		tgbotapi.NewInlineKeyboardRow(
@@ -59,6 +63,7 @@ func buildMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Unclaim", "menu_t_unclaim"),
+			tgbotapi.NewInlineKeyboardButtonData("Budget", "menu_c_budget"),
 		),
 	)
 }
@@ -79,6 +84,14 @@ func (b *Bot) handleMenuCallback(cq *tgbotapi.CallbackQuery) {
 		b.forwardCommand(msg, "help")
 	case "c_get":
 		b.handleGet(msg)
+	case "c_allow":
+		b.handleAllowCommand(msg)
+	case "c_usage":
+		b.handleUsageCommand(msg)
+	case "c_budget":
+		b.handleBudgetCommand(msg)
+	case "c_verbosity":
+		b.handleVerbosityCommand(msg)
 	case "p_bind":
 		b.handleProject(msg)
 	case "p_tasks":
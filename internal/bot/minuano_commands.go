@@ -3,11 +3,11 @@ package bot
 import (
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/minuano"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
@@ -150,6 +150,7 @@ func (b *Bot) handlePickCommand(msg *tgbotapi.Message) {
 		return
 	}
 
+	b.trackTask(windowID, task.ID)
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s...", task.ID))
 }
 
@@ -188,6 +189,8 @@ func (b *Bot) handleAutoCommand(msg *tgbotapi.Message) {
 		return
 	}
 
+	b.trackTask(windowID, "")
+	b.startAutoRun(windowID, chatID, threadID, project)
 	b.reply(chatID, threadID, fmt.Sprintf("Starting autonomous mode for project %s...", project))
 }
 
@@ -244,6 +247,7 @@ func (b *Bot) executeBatch(msg *tgbotapi.Message, args []string) {
 		return
 	}
 
+	b.trackTask(windowID, "")
 	b.reply(chatID, threadID, fmt.Sprintf("Working on batch: %s...", strings.Join(args, ", ")))
 }
 
@@ -401,24 +405,14 @@ func (b *Bot) executeUnclaimTask(chatID int64, threadID int, taskID, title strin
 	b.reply(chatID, threadID, fmt.Sprintf("Unclaimed: %s — %s", taskID, title))
 }
 
-// sendPromptToTmux writes a prompt to a temp file and sends a reference to tmux.
-// Long prompts exceed tmux send-keys limits, so we use a temp file.
+// sendPromptToTmux submits a generated task prompt. Tmux windows always get
+// it through a paste buffer, which takes long multi-line text intact;
+// headless sessions get it directly.
 func (b *Bot) sendPromptToTmux(windowID, prompt string) error {
-	// Write prompt to temp file
-	tmpFile, err := os.CreateTemp("", "tramuntana-task-*.md")
-	if err != nil {
-		return fmt.Errorf("creating temp file: %w", err)
-	}
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.WriteString(prompt); err != nil {
-		return fmt.Errorf("writing prompt: %w", err)
+	if t, ok := b.backendFor(windowID).(*backend.Tmux); ok {
+		return t.Paste(windowID, prompt)
 	}
-	tmpFile.Close()
-
-	// Send reference to tmux
-	ref := fmt.Sprintf("Please read and follow the instructions in %s", tmpFile.Name())
-	return tmux.SendKeysWithDelay(b.config.TmuxSessionName, windowID, ref, 500)
+	return b.backendFor(windowID).SendText(windowID, prompt)
 }
 
 // buildMinuanoEnv returns environment variables to set in tmux windows for Minuano
@@ -430,7 +424,7 @@ func (b *Bot) buildMinuanoEnv(windowName string) map[string]string {
 
 	env := map[string]string{
 		"DATABASE_URL": b.config.MinuanoDB,
-		"AGENT_ID":     fmt.Sprintf("tramuntana-%s", windowName),
+		"AGENT_ID":     minuanoAgentID(windowName),
 	}
 
 	if b.config.MinuanoScriptsDir != "" {
@@ -440,6 +434,11 @@ func (b *Bot) buildMinuanoEnv(windowName string) map[string]string {
 	return env
 }
 
+// minuanoAgentID is the agent ID a window's session claims Minuano tasks under.
+func minuanoAgentID(windowName string) string {
+	return "tramuntana-" + windowName
+}
+
 // statusSymbol returns a display symbol for a task status.
 func statusSymbol(status string) string {
 	switch status {
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// scrapePaneOutput forwards new pane output for agents without a transcript.
+// The pane is only read while the agent waits for input, so a reply is sent
+// once it has finished rather than in fragments. The first capture of a
+// window is a baseline and is not forwarded.
+func (sp *StatusPoller) scrapePaneOutput(windowID string, users []state.UserThread) {
+	p := sp.bot.agentFor(windowID)
+	paneText, err := sp.bot.backendFor(windowID).Capture(windowID, false)
+	if err != nil || !p.Ready(paneText) {
+		return
+	}
+	cur := outputLines(p.Output(paneText))
+
+	sp.mu.Lock()
+	prev, seen := sp.paneLines[windowID]
+	sp.paneLines[windowID] = cur
+	sp.mu.Unlock()
+	if !seen {
+		return
+	}
+
+	text := strings.TrimSpace(strings.Join(monitor.PaneDelta(prev, cur), "\n"))
+	if text == "" || sp.queue == nil {
+		return
+	}
+	text = render.FormatText(text)
+
+	for _, ut := range users {
+		chatID, ok := sp.bot.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		sp.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{text},
+			ContentType: "content",
+			WindowID:    windowID,
+		})
+	}
+}
+
+// outputLines splits scraped output into lines, dropping trailing blank lines
+// so the cursor row doesn't defeat the overlap check in PaneDelta.
+func outputLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -2,13 +2,14 @@ package bot
 
 import (
 	"log"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // pendingInput represents a command waiting for user text input.
 type pendingInput struct {
-	Command  string // "p_bind", "p_add", "t_batch", "t_merge", "t_plan"
+	Command  string // "p_bind", "p_add", "t_batch", "t_merge", "t_plan", "c_snip", "voice_edit", "ask_other:<id>", "c_cmds:<name>"
 	ChatID   int64
 	ThreadID int
 }
@@ -57,6 +58,15 @@ func (b *Bot) handlePendingInput(msg *tgbotapi.Message) bool {
 	text := msg.Text
 	log.Printf("Pending input consumed: command=%s text=%q", pi.Command, text)
 
+	if id, ok := strings.CutPrefix(pi.Command, "ask_other:"); ok {
+		b.answerQuestionOther(msg, id, text)
+		return true
+	}
+	if name, ok := strings.CutPrefix(pi.Command, "c_cmds:"); ok {
+		b.forwardCommand(msg, strings.TrimSpace(name+" "+strings.TrimSpace(text)))
+		return true
+	}
+
 	switch pi.Command {
 	case "p_bind":
 		b.executeProjectBind(msg, text)
@@ -68,6 +78,10 @@ func (b *Bot) handlePendingInput(msg *tgbotapi.Message) bool {
 		b.executeMergeWithBranch(msg, text)
 	case "t_plan":
 		b.executePlanWithDescription(msg, text)
+	case "c_snip":
+		b.continueSnippet(msg, text)
+	case "voice_edit":
+		b.postVoiceDraft(msg.Chat.ID, getThreadID(msg), text)
 	default:
 		log.Printf("Unknown pending input command: %s", pi.Command)
 		return false
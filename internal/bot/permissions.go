@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+)
+
+// permissionPrompt is a PreToolUse request waiting for an answer in Telegram.
+type permissionPrompt struct {
+	windowID string
+	toolName string
+	reply    chan hooksock.Response
+	messages []sentMessage
+}
+
+// sentMessage identifies a message posted to a topic.
+type sentMessage struct {
+	ChatID    int64
+	MessageID int
+}
+
+// permissionState tracks open prompts and per-window "always allow" choices.
+type permissionState struct {
+	mu      sync.Mutex
+	seq     int
+	pending map[string]*permissionPrompt // prompt ID → prompt
+	allowed map[string]map[string]bool   // windowID → tool name → always allowed
+}
+
+var permissions = &permissionState{
+	pending: make(map[string]*permissionPrompt),
+	allowed: make(map[string]map[string]bool),
+}
+
+// editTools are auto-approved by Claude Code in acceptEdits mode.
+var editTools = map[string]bool{"Edit": true, "MultiEdit": true, "Write": true, "NotebookEdit": true}
+
+// handlePermissionRequest posts an Approve/Deny/Always-allow keyboard to every
+// topic bound to the requesting window and blocks until one is pressed or
+// PERMISSION_TIMEOUT applies PERMISSION_DEFAULT.
+func (b *Bot) handlePermissionRequest(req hooksock.Request) hooksock.Response {
+	windowID := b.windowForHook(req)
+	if windowID == "" {
+		return hooksock.Response{}
+	}
+
+	// Claude Code won't prompt in these modes, so neither do we
+	if req.PermissionMode == "bypassPermissions" || (req.PermissionMode == "acceptEdits" && editTools[req.ToolName]) {
+		return hooksock.Response{}
+	}
+
+	subject := hookSubject(req.ToolName, req.ToolInput, req.CWD)
+	if rule, ok := b.matchAllowRule(windowID, req.ToolName, subject); ok {
+		b.auditAutoApproval(windowID, req.ToolName, subject, rule)
+		return hooksock.Response{Decision: hooksock.DecisionAllow, Reason: "Auto-approved by rule " + rule.Tool + " " + rule.Pattern}
+	}
+
+	if isAlwaysAllowed(windowID, req.ToolName) {
+		return hooksock.Response{Decision: hooksock.DecisionAllow, Reason: "Always allowed from Telegram"}
+	}
+
+	id, prompt := openPermissionPrompt(windowID, req.ToolName)
+	text := formatPermissionPrompt(req.ToolName, req.ToolInput)
+	keyboard := buildPermissionKeyboard(id)
+
+	for _, ut := range b.state.FindUsersForWindow(windowID) {
+		chatID, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
+		}
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		msg, err := b.sendMessageWithKeyboard(chatID, threadID, text, keyboard)
+		if err != nil {
+			log.Printf("Permission: sending prompt for %s: %v", req.ToolName, err)
+			continue
+		}
+		prompt.messages = append(prompt.messages, sentMessage{chatID, msg.MessageID})
+	}
+	if len(prompt.messages) == 0 {
+		closePermissionPrompt(id)
+		return hooksock.Response{}
+	}
+
+	select {
+	case resp := <-prompt.reply:
+		return resp
+	case <-time.After(b.config.PermissionTimeout):
+	}
+
+	if closePermissionPrompt(id) == nil {
+		// Answered just as the timer fired
+		return <-prompt.reply
+	}
+	decision := b.config.PermissionDefault
+	note := fmt.Sprintf("%s\n\nNo answer after %s: %s.", text, b.config.PermissionTimeout, timeoutOutcome(decision))
+	for _, m := range prompt.messages {
+		b.editMessageText(m.ChatID, m.MessageID, note)
+	}
+	return hooksock.Response{Decision: decision, Reason: "No answer from Telegram in time"}
+}
+
+// processPermissionCallback handles perm_allow/perm_deny/perm_always buttons.
+func (b *Bot) processPermissionCallback(cq *tgbotapi.CallbackQuery) {
+	action, id, ok := strings.Cut(cq.Data, ":")
+	if !ok {
+		return
+	}
+	prompt := closePermissionPrompt(id)
+	if prompt == nil {
+		b.answerCallback(cq.ID, "This request is no longer pending.")
+		return
+	}
+
+	who := cq.From.UserName
+	if who == "" {
+		who = cq.From.FirstName
+	}
+
+	var resp hooksock.Response
+	var outcome string
+	switch action {
+	case "perm_allow":
+		resp = hooksock.Response{Decision: hooksock.DecisionAllow, Reason: "Approved from Telegram by " + who}
+		outcome = "Approved by " + who
+	case "perm_always":
+		allowAlways(prompt.windowID, prompt.toolName)
+		resp = hooksock.Response{Decision: hooksock.DecisionAllow, Reason: "Always allowed from Telegram by " + who}
+		outcome = fmt.Sprintf("Approved by %s. %s is now always allowed in this session.", who, prompt.toolName)
+	default:
+		resp = hooksock.Response{Decision: hooksock.DecisionDeny, Reason: "Denied from Telegram by " + who}
+		outcome = "Denied by " + who
+	}
+	prompt.reply <- resp
+
+	for _, m := range prompt.messages {
+		b.editMessageText(m.ChatID, m.MessageID, fmt.Sprintf("%s: %s", prompt.toolName, outcome))
+	}
+}
+
+// openPermissionPrompt registers a prompt and returns its callback ID.
+func openPermissionPrompt(windowID, toolName string) (string, *permissionPrompt) {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	permissions.seq++
+	id := strconv.FormatInt(int64(permissions.seq), 36)
+	p := &permissionPrompt{windowID: windowID, toolName: toolName, reply: make(chan hooksock.Response, 1)}
+	permissions.pending[id] = p
+	return id, p
+}
+
+// closePermissionPrompt removes and returns a pending prompt, or nil if it was
+// already answered or timed out.
+func closePermissionPrompt(id string) *permissionPrompt {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	p := permissions.pending[id]
+	delete(permissions.pending, id)
+	return p
+}
+
+func allowAlways(windowID, toolName string) {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	if permissions.allowed[windowID] == nil {
+		permissions.allowed[windowID] = make(map[string]bool)
+	}
+	permissions.allowed[windowID][toolName] = true
+}
+
+func isAlwaysAllowed(windowID, toolName string) bool {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	return permissions.allowed[windowID][toolName]
+}
+
+// hasPendingPermission reports whether a window has a prompt waiting in Telegram.
+func hasPendingPermission(windowID string) bool {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	for _, p := range permissions.pending {
+		if p.windowID == windowID {
+			return true
+		}
+	}
+	return false
+}
+
+// clearWindowPermissions forgets "always allow" choices for a window.
+func clearWindowPermissions(windowID string) {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	delete(permissions.allowed, windowID)
+}
+
+// buildPermissionKeyboard builds the Approve/Deny/Always-allow keyboard.
+func buildPermissionKeyboard(id string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Approve", "perm_allow:"+id),
+			tgbotapi.NewInlineKeyboardButtonData("Deny", "perm_deny:"+id),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Always allow", "perm_always:"+id),
+		),
+	)
+}
+
+// formatPermissionPrompt describes a tool call for the approval message.
+func formatPermissionPrompt(toolName string, inputJSON json.RawMessage) string {
+	var input map[string]any
+	json.Unmarshal(inputJSON, &input)
+	str := func(key string) string {
+		s, _ := input[key].(string)
+		return s
+	}
+
+	var detail string
+	switch toolName {
+	case "Bash":
+		detail = truncateDetail(str("command"), 1000)
+		if desc := str("description"); desc != "" {
+			detail = desc + "\n\n" + detail
+		}
+	case "Edit", "MultiEdit", "Write":
+		detail = str("file_path")
+	case "NotebookEdit":
+		detail = str("notebook_path")
+	case "WebFetch":
+		detail = str("url")
+	default:
+		if len(inputJSON) > 0 {
+			detail = truncateDetail(string(inputJSON), 500)
+		}
+	}
+
+	text := "Permission request: " + toolName
+	if detail != "" {
+		text += "\n\n" + detail
+	}
+	return text
+}
+
+func truncateDetail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// timeoutOutcome describes what a timeout default means for the user.
+func timeoutOutcome(decision string) string {
+	switch decision {
+	case hooksock.DecisionAllow:
+		return "approved by default"
+	case hooksock.DecisionDeny:
+		return "denied by default"
+	}
+	return "falling back to the terminal prompt"
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestWindowForHook(t *testing.T) {
+	b := newTestBot(t)
+	b.state.SetWindowState("~1", state.WindowState{SessionID: "s-headless"})
+
+	tests := []struct {
+		name string
+		req  hooksock.Request
+		want string
+	}{
+		{"our tmux session", hooksock.Request{Window: "test-session:@5", SessionID: "s-x"}, "@5"},
+		{"foreign tmux session", hooksock.Request{Window: "other:@5", SessionID: "s-x"}, ""},
+		{"headless by session", hooksock.Request{SessionID: "s-headless"}, "~1"},
+		{"unknown", hooksock.Request{SessionID: "s-unknown"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.windowForHook(tt.req); got != tt.want {
+				t.Errorf("windowForHook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlePermissionRequest_Defers(t *testing.T) {
+	b := newTestBot(t)
+	b.state.BindThread("100", "42", "@1")
+
+	tests := []struct {
+		name string
+		req  hooksock.Request
+	}{
+		{"unbound window", hooksock.Request{Event: "PreToolUse", Window: "test-session:@9", ToolName: "Bash"}},
+		{"bypass mode", hooksock.Request{Event: "PreToolUse", Window: "test-session:@1", ToolName: "Bash", PermissionMode: "bypassPermissions"}},
+		{"accept edits", hooksock.Request{Event: "PreToolUse", Window: "test-session:@1", ToolName: "Edit", PermissionMode: "acceptEdits"}},
+		{"no chat ID", hooksock.Request{Event: "PreToolUse", Window: "test-session:@1", ToolName: "Bash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := b.HandleHookRequest(tt.req); resp.Decision != "" {
+				t.Errorf("expected no decision, got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestHandlePermissionRequest_AlwaysAllowed(t *testing.T) {
+	b := newTestBot(t)
+	allowAlways("@7", "Bash")
+	defer clearWindowPermissions("@7")
+
+	resp := b.HandleHookRequest(hooksock.Request{Event: "PreToolUse", Window: "test-session:@7", ToolName: "Bash"})
+	if resp.Decision != hooksock.DecisionAllow {
+		t.Errorf("expected allow, got %+v", resp)
+	}
+	if isAlwaysAllowed("@7", "Write") {
+		t.Error("always-allow should be per tool")
+	}
+}
+
+func TestPermissionPromptLifecycle(t *testing.T) {
+	id, p := openPermissionPrompt("@1", "Bash")
+	if got := closePermissionPrompt(id); got != p {
+		t.Fatal("first close should return the prompt")
+	}
+	if closePermissionPrompt(id) != nil {
+		t.Error("second close should return nil")
+	}
+}
+
+func TestBuildPermissionKeyboard(t *testing.T) {
+	kb := buildPermissionKeyboard("zz9")
+	var data []string
+	for _, row := range kb.InlineKeyboard {
+		for _, btn := range row {
+			data = append(data, *btn.CallbackData)
+		}
+	}
+	want := []string{"perm_allow:zz9", "perm_deny:zz9", "perm_always:zz9"}
+	if strings.Join(data, ",") != strings.Join(want, ",") {
+		t.Errorf("callbacks = %v, want %v", data, want)
+	}
+}
+
+func TestFormatPermissionPrompt(t *testing.T) {
+	text := formatPermissionPrompt("Bash", json.RawMessage(`{"command":"rm -rf build","description":"Clean build dir"}`))
+	if !strings.Contains(text, "Bash") || !strings.Contains(text, "Clean build dir") || !strings.Contains(text, "rm -rf build") {
+		t.Errorf("unexpected Bash prompt: %q", text)
+	}
+
+	text = formatPermissionPrompt("Write", json.RawMessage(`{"file_path":"/tmp/x.go","content":"package x"}`))
+	if !strings.Contains(text, "/tmp/x.go") || strings.Contains(text, "package x") {
+		t.Errorf("Write prompt should show the path only: %q", text)
+	}
+
+	long := `{"q":"` + strings.Repeat("a", 1000) + `"}`
+	text = formatPermissionPrompt("mcp__search", json.RawMessage(long))
+	if !strings.HasSuffix(text, "...") {
+		t.Errorf("long input should be truncated: %d chars", len(text))
+	}
+}
+
+func TestHasPendingPermission(t *testing.T) {
+	id, _ := openPermissionPrompt("@8", "Bash")
+	if !hasPendingPermission("@8") {
+		t.Error("open prompt should be pending")
+	}
+	closePermissionPrompt(id)
+	if hasPendingPermission("@8") {
+		t.Error("closed prompt should not be pending")
+	}
+}
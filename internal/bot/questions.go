@@ -0,0 +1,493 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
+	"github.com/otaviocarvalho/tramuntana/internal/monitor"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+// askQuestion is one entry of an AskUserQuestion tool call's input.
+type askQuestion struct {
+	Question    string      `json:"question"`
+	Header      string      `json:"header"`
+	Options     []askOption `json:"options"`
+	MultiSelect bool        `json:"multiSelect"`
+}
+
+type askOption struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// planChoice is an ExitPlanMode dialog option offered in Telegram. The
+// dialog's options differ between Claude Code versions, so the one to pick is
+// found on the pane by Match.
+type planChoice struct {
+	Label string
+	Match string // lowercase text of the dialog option
+}
+
+var planChoices = []planChoice{
+	{"Yes, auto-accept edits", "auto-accept edits"},
+	{"Yes, manually approve edits", "manually approve"},
+	{"No, keep planning", "keep planning"},
+}
+
+// questionPrompt is an AskUserQuestion or ExitPlanMode dialog mirrored into a
+// topic with one button per option. mu guards the fields below it and is held
+// while an answer is sent, so answers to one prompt don't interleave.
+type questionPrompt struct {
+	windowID  string
+	chatID    int64
+	threadID  int
+	questions []askQuestion // nil for a plan review
+
+	mu        sync.Mutex
+	messageID int
+	current   int          // question being answered
+	selected  map[int]bool // multi-select toggles for the current question
+}
+
+// questionState tracks open question prompts by callback ID.
+type questionState struct {
+	mu      sync.Mutex
+	seq     int
+	pending map[string]*questionPrompt
+}
+
+var questionPrompts = &questionState{pending: make(map[string]*questionPrompt)}
+
+// HandleQuestionFromMonitor renders an AskUserQuestion or ExitPlanMode tool
+// call from the transcript as a native keyboard. Returns false when the window
+// has no terminal to send keys to, so the monitor falls back to a summary.
+// Set as monitor.QuestionHandler by the serve command.
+func (b *Bot) HandleQuestionFromMonitor(userID int64, threadID int, chatID int64, windowID, toolName string, input json.RawMessage) bool {
+	if backend.IsHeadless(windowID) {
+		return false
+	}
+
+	switch toolName {
+	case "AskUserQuestion":
+		var in struct {
+			Questions []askQuestion `json:"questions"`
+		}
+		if err := json.Unmarshal(input, &in); err != nil || len(in.Questions) == 0 {
+			log.Printf("AskUserQuestion: unreadable input for %s: %v", windowID, err)
+			return false
+		}
+		b.showQuestion(chatID, threadID, windowID, in.Questions)
+	case "ExitPlanMode":
+		var in struct {
+			Plan string `json:"plan"`
+		}
+		if err := json.Unmarshal(input, &in); err != nil {
+			log.Printf("ExitPlanMode: unreadable input for %s: %v", windowID, err)
+			return false
+		}
+		b.showPlanReview(chatID, threadID, windowID, in.Plan)
+	default:
+		return false
+	}
+	return true
+}
+
+// showQuestion posts the first question of an AskUserQuestion call.
+func (b *Bot) showQuestion(chatID int64, threadID int, windowID string, questions []askQuestion) {
+	id, p := openQuestionPrompt(&questionPrompt{
+		windowID:  windowID,
+		chatID:    chatID,
+		threadID:  threadID,
+		questions: questions,
+		selected:  make(map[int]bool),
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	text, kb := p.render(id)
+	msg, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb)
+	if err != nil {
+		log.Printf("Error sending question for %s: %v", windowID, err)
+		closeQuestionPrompt(id)
+		return
+	}
+	p.messageID = msg.MessageID
+}
+
+// showPlanReview posts the full plan as MarkdownV2 followed by the approval
+// buttons. Long plans are split; the keyboard goes on the last part.
+func (b *Bot) showPlanReview(chatID int64, threadID int, windowID, plan string) {
+	id, p := openQuestionPrompt(&questionPrompt{windowID: windowID, chatID: chatID, threadID: threadID})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parts := render.SplitMessage("\U0001F4CB **Plan review**\n\n"+plan, 3000)
+	for _, part := range parts[:len(parts)-1] {
+		if _, err := b.sendMessageInThreadMD(chatID, threadID, render.ToMarkdownV2(part)); err != nil {
+			b.reply(chatID, threadID, render.ToPlainText(part))
+		}
+	}
+
+	last := parts[len(parts)-1]
+	kb := buildPlanKeyboard(id)
+	msg, err := b.sendMessageWithKeyboardMD(chatID, threadID, render.ToMarkdownV2(last), kb)
+	if err != nil {
+		msg, err = b.sendMessageWithKeyboard(chatID, threadID, render.ToPlainText(last), kb)
+	}
+	if err != nil {
+		log.Printf("Error sending plan review for %s: %v", windowID, err)
+		closeQuestionPrompt(id)
+		return
+	}
+	p.messageID = msg.MessageID
+}
+
+// render builds the text and keyboard for the prompt's current question.
+// Called with p.mu held.
+func (p *questionPrompt) render(id string) (string, tgbotapi.InlineKeyboardMarkup) {
+	q := p.questions[p.current]
+
+	var sb strings.Builder
+	sb.WriteString("❓ ")
+	if q.Header != "" {
+		sb.WriteString("[" + q.Header + "] ")
+	}
+	sb.WriteString(q.Question)
+	if len(p.questions) > 1 {
+		fmt.Fprintf(&sb, " (%d/%d)", p.current+1, len(p.questions))
+	}
+	sb.WriteString("\n")
+	for i, o := range q.Options {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, o.Label)
+		if o.Description != "" {
+			sb.WriteString(" — " + o.Description)
+		}
+	}
+	if q.MultiSelect {
+		sb.WriteString("\n\nSelect any, then Submit.")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, o := range q.Options {
+		label, data := o.Label, fmt.Sprintf("ask_opt:%s:%d", id, i)
+		if q.MultiSelect {
+			label = "☐ " + o.Label
+			if p.selected[i] {
+				label = "☑ " + o.Label
+			}
+			data = fmt.Sprintf("ask_tog:%s:%d", id, i)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, data)))
+	}
+	last := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("Other…", "ask_other:"+id),
+	}
+	if q.MultiSelect {
+		last = append(last, tgbotapi.NewInlineKeyboardButtonData("Submit", "ask_done:"+id))
+	}
+	last = append(last, tgbotapi.NewInlineKeyboardButtonData("Cancel", "ask_esc:"+id))
+	rows = append(rows, last)
+
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// buildPlanKeyboard builds the ExitPlanMode choice keyboard.
+func buildPlanKeyboard(id string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, c := range planChoices {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(c.Label, fmt.Sprintf("ask_plan:%s:%d", id, i)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// processQuestionCallback handles ask_opt/ask_tog/ask_done/ask_other/ask_esc/ask_plan buttons.
+func (b *Bot) processQuestionCallback(cq *tgbotapi.CallbackQuery) {
+	parts := strings.Split(cq.Data, ":")
+	if len(parts) < 2 {
+		return
+	}
+	action, id := parts[0], parts[1]
+	n := -1
+	if len(parts) > 2 {
+		n, _ = strconv.Atoi(parts[2])
+	}
+
+	p := getQuestionPrompt(id)
+	if p == nil {
+		b.answerCallback(cq.ID, "This question is no longer pending.")
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if getQuestionPrompt(id) != p {
+		// Answered while we waited for the lock
+		b.answerCallback(cq.ID, "This question is no longer pending.")
+		return
+	}
+	if p.questions == nil && action != "ask_plan" {
+		return
+	}
+
+	switch action {
+	case "ask_opt":
+		q := p.questions[p.current]
+		if n < 0 || n >= len(q.Options) {
+			return
+		}
+		b.answerQuestion(id, p, singleSelectKeys(n), "", q.Options[n].Label)
+	case "ask_tog":
+		if n < 0 || n >= len(p.questions[p.current].Options) {
+			return
+		}
+		p.selected[n] = !p.selected[n]
+		text, kb := p.render(id)
+		b.editMessageWithKeyboard(p.chatID, p.messageID, text, kb)
+	case "ask_done":
+		q := p.questions[p.current]
+		var labels []string
+		for i, o := range q.Options {
+			if p.selected[i] {
+				labels = append(labels, o.Label)
+			}
+		}
+		if len(labels) == 0 {
+			b.answerCallback(cq.ID, "Select at least one option.")
+			return
+		}
+		b.answerQuestion(id, p, multiSelectKeys(p.selected, len(q.Options)), "", strings.Join(labels, ", "))
+	case "ask_other":
+		b.setPendingInput(cq.From.ID, "ask_other:"+id, p.chatID, p.threadID)
+		b.reply(p.chatID, p.threadID, "Send your answer as a message.")
+	case "ask_esc":
+		closeQuestionPrompt(id)
+		if err := b.sendKeys(p.windowID, []string{"Escape"}); err != nil {
+			log.Printf("Question: sending Escape to %s: %v", p.windowID, err)
+		}
+		b.editMessageText(p.chatID, p.messageID, "Question dismissed.")
+	case "ask_plan":
+		if n < 0 || n >= len(planChoices) {
+			return
+		}
+		pane, err := b.backendFor(p.windowID).Capture(p.windowID, false)
+		if err != nil {
+			log.Printf("Plan review: capturing %s: %v", p.windowID, err)
+			b.reply(p.chatID, p.threadID, "Error: failed to read the plan dialog from the session.")
+			return
+		}
+		keys, ok := planChoiceKeys(pane, planChoices[n])
+		if !ok {
+			b.reply(p.chatID, p.threadID, "Couldn't find \""+planChoices[n].Label+"\" in the plan dialog; nothing was sent. Check /c_screenshot.")
+			return
+		}
+		closeQuestionPrompt(id)
+		if err := b.sendKeys(p.windowID, keys); err != nil {
+			log.Printf("Plan review: sending keys to %s: %v", p.windowID, err)
+			b.reply(p.chatID, p.threadID, "Error: failed to send the choice to the session.")
+			return
+		}
+		b.removeKeyboard(p.chatID, p.messageID)
+		outcome := "✅ " + planChoices[n].Label
+		if n == len(planChoices)-1 {
+			outcome = "✏️ Keep planning. Send your feedback as a message."
+		}
+		b.reply(p.chatID, p.threadID, outcome)
+	}
+}
+
+// answerQuestionOther answers the current question with free text typed into
+// the dialog's "Type something" entry.
+func (b *Bot) answerQuestionOther(msg *tgbotapi.Message, id, text string) {
+	p := getQuestionPrompt(id)
+	if p == nil {
+		b.reply(msg.Chat.ID, getThreadID(msg), "This question is no longer pending.")
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if getQuestionPrompt(id) != p {
+		b.reply(msg.Chat.ID, getThreadID(msg), "This question is no longer pending.")
+		return
+	}
+	b.answerQuestion(id, p, otherKeys(len(p.questions[p.current].Options)), text, text)
+}
+
+// answerQuestion sends the keys that pick an answer (then typed text, if any,
+// submitted with Enter) and moves the prompt on to the next question or closes
+// it. The dialog opens a review tab when it has several questions or a
+// multi-select one; a final Enter submits it. Called with p.mu held.
+func (b *Bot) answerQuestion(id string, p *questionPrompt, keys []string, typed, answer string) {
+	q := p.questions[p.current]
+	isLast := p.current == len(p.questions)-1
+
+	err := b.sendKeys(p.windowID, keys)
+	if err == nil && typed != "" {
+		err = b.backendFor(p.windowID).SendText(p.windowID, typed)
+	}
+	if err == nil && isLast && hasReviewTab(p.questions) {
+		err = b.sendKeys(p.windowID, []string{"Enter"})
+	}
+	if err != nil {
+		log.Printf("Question: sending answer to %s: %v", p.windowID, err)
+		b.reply(p.chatID, p.threadID, "Error: failed to send the answer to the session.")
+		return
+	}
+
+	summary := fmt.Sprintf("❓ %s\n→ %s", q.Question, answer)
+	if isLast {
+		closeQuestionPrompt(id)
+		b.editMessageText(p.chatID, p.messageID, summary)
+		return
+	}
+
+	p.current++
+	p.selected = make(map[int]bool)
+	text, kb := p.render(id)
+	b.editMessageWithKeyboard(p.chatID, p.messageID, summary+"\n\n"+text, kb)
+}
+
+// Key sequences for Claude Code's question dialog. The cursor starts on the
+// first option and "Type something" follows the last one. Enter picks an
+// option in single-select questions and toggles it in multi-select ones,
+// where Right moves on to the next tab.
+
+func singleSelectKeys(n int) []string {
+	keys := repeatKey("Down", n)
+	return append(keys, "Enter")
+}
+
+func multiSelectKeys(selected map[int]bool, numOptions int) []string {
+	var keys []string
+	cursor := 0
+	for i := 0; i < numOptions; i++ {
+		if !selected[i] {
+			continue
+		}
+		keys = append(keys, repeatKey("Down", i-cursor)...)
+		keys = append(keys, "Enter")
+		cursor = i
+	}
+	return append(keys, "Right")
+}
+
+func otherKeys(numOptions int) []string {
+	return repeatKey("Down", numOptions)
+}
+
+// planChoiceKeys finds a choice among the plan dialog's options on the pane
+// and returns the keys that move the cursor to it and pick it.
+func planChoiceKeys(pane string, choice planChoice) ([]string, bool) {
+	options, cursor, ok := monitor.ParseDialogOptions(pane)
+	if !ok {
+		return nil, false
+	}
+	target := slices.IndexFunc(options, func(o string) bool {
+		return strings.Contains(strings.ToLower(o), choice.Match)
+	})
+	if target < 0 {
+		return nil, false
+	}
+	var keys []string
+	if target < cursor {
+		keys = repeatKey("Up", cursor-target)
+	} else {
+		keys = repeatKey("Down", target-cursor)
+	}
+	return append(keys, "Enter"), true
+}
+
+func hasReviewTab(questions []askQuestion) bool {
+	if len(questions) > 1 {
+		return true
+	}
+	return len(questions) == 1 && questions[0].MultiSelect
+}
+
+func repeatKey(key string, n int) []string {
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// sendKeys sends a sequence of named keys to a window.
+func (b *Bot) sendKeys(windowID string, keys []string) error {
+	be := b.backendFor(windowID)
+	for _, k := range keys {
+		if err := be.SendKey(windowID, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeKeyboard strips the inline keyboard from a message.
+func (b *Bot) removeKeyboard(chatID int64, messageID int) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	if _, err := b.api.Request(edit); err != nil {
+		log.Printf("Error removing keyboard: %v", err)
+	}
+}
+
+// openQuestionPrompt registers a prompt and returns its callback ID. Earlier
+// prompts for the same window and topic are dropped: their dialog is gone.
+func openQuestionPrompt(p *questionPrompt) (string, *questionPrompt) {
+	questionPrompts.mu.Lock()
+	defer questionPrompts.mu.Unlock()
+	for id, old := range questionPrompts.pending {
+		if old.windowID == p.windowID && old.threadID == p.threadID {
+			delete(questionPrompts.pending, id)
+		}
+	}
+	questionPrompts.seq++
+	id := strconv.FormatInt(int64(questionPrompts.seq), 36)
+	questionPrompts.pending[id] = p
+	return id, p
+}
+
+func getQuestionPrompt(id string) *questionPrompt {
+	questionPrompts.mu.Lock()
+	defer questionPrompts.mu.Unlock()
+	return questionPrompts.pending[id]
+}
+
+func closeQuestionPrompt(id string) {
+	questionPrompts.mu.Lock()
+	defer questionPrompts.mu.Unlock()
+	delete(questionPrompts.pending, id)
+}
+
+// hasQuestionPrompt reports whether a window's dialog is already mirrored with
+// native buttons, in which case the pane-scraped keyboard is not shown.
+func hasQuestionPrompt(windowID string) bool {
+	questionPrompts.mu.Lock()
+	defer questionPrompts.mu.Unlock()
+	for _, p := range questionPrompts.pending {
+		if p.windowID == windowID {
+			return true
+		}
+	}
+	return false
+}
+
+// clearWindowQuestions drops open prompts for a window.
+func clearWindowQuestions(windowID string) {
+	questionPrompts.mu.Lock()
+	defer questionPrompts.mu.Unlock()
+	for id, p := range questionPrompts.pending {
+		if p.windowID == windowID {
+			delete(questionPrompts.pending, id)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestQuestionPromptRender(t *testing.T) {
+	p := &questionPrompt{
+		questions: []askQuestion{
+			{Question: "Which DB?", Header: "Storage", Options: []askOption{{Label: "Postgres", Description: "relational"}, {Label: "SQLite"}}},
+			{Question: "Which checks?", MultiSelect: true, Options: []askOption{{Label: "lint"}, {Label: "test"}}},
+		},
+		selected: make(map[int]bool),
+	}
+
+	text, kb := p.render("a1")
+	for _, want := range []string{"[Storage] Which DB? (1/2)", "1. Postgres — relational", "2. SQLite"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+	if got := callbackData(kb.InlineKeyboard); strings.Join(got, ",") != "ask_opt:a1:0,ask_opt:a1:1,ask_other:a1,ask_esc:a1" {
+		t.Errorf("single-select callbacks = %v", got)
+	}
+
+	p.current = 1
+	p.selected[1] = true
+	text, kb = p.render("a1")
+	if !strings.Contains(text, "Submit") {
+		t.Errorf("multi-select text should mention Submit: %q", text)
+	}
+	if got := callbackData(kb.InlineKeyboard); strings.Join(got, ",") != "ask_tog:a1:0,ask_tog:a1:1,ask_other:a1,ask_done:a1,ask_esc:a1" {
+		t.Errorf("multi-select callbacks = %v", got)
+	}
+	if kb.InlineKeyboard[1][0].Text != "☑ test" || kb.InlineKeyboard[0][0].Text != "☐ lint" {
+		t.Errorf("toggle labels = %q, %q", kb.InlineKeyboard[0][0].Text, kb.InlineKeyboard[1][0].Text)
+	}
+}
+
+func TestQuestionKeys(t *testing.T) {
+	if got := strings.Join(singleSelectKeys(2), ","); got != "Down,Down,Enter" {
+		t.Errorf("singleSelectKeys(2) = %s", got)
+	}
+	if got := strings.Join(singleSelectKeys(0), ","); got != "Enter" {
+		t.Errorf("singleSelectKeys(0) = %s", got)
+	}
+	if got := strings.Join(multiSelectKeys(map[int]bool{0: true, 2: true, 1: false}, 3), ","); got != "Enter,Down,Down,Enter,Right" {
+		t.Errorf("multiSelectKeys = %s", got)
+	}
+	if got := strings.Join(otherKeys(3), ","); got != "Down,Down,Down" {
+		t.Errorf("otherKeys(3) = %s", got)
+	}
+}
+
+func TestHasReviewTab(t *testing.T) {
+	single := askQuestion{Question: "a"}
+	multi := askQuestion{Question: "b", MultiSelect: true}
+	if hasReviewTab([]askQuestion{single}) {
+		t.Error("a single single-select question submits directly")
+	}
+	if !hasReviewTab([]askQuestion{multi}) || !hasReviewTab([]askQuestion{single, single}) {
+		t.Error("multi-select and multi-question dialogs end on a review tab")
+	}
+}
+
+func TestBuildPlanKeyboard(t *testing.T) {
+	got := callbackData(buildPlanKeyboard("z").InlineKeyboard)
+	if strings.Join(got, ",") != "ask_plan:z:0,ask_plan:z:1,ask_plan:z:2" {
+		t.Errorf("plan callbacks = %v", got)
+	}
+}
+
+func TestPlanChoiceKeys(t *testing.T) {
+	pane := "Would you like to proceed?\n\n" +
+		"  1. Yes, and bypass permissions\n" +
+		"❯ 2. Yes, and auto-accept edits\n" +
+		"  3. Yes, and manually approve edits\n" +
+		"  4. No, keep planning\n"
+	tests := []struct {
+		choice int
+		want   string
+	}{
+		{0, "Enter"},
+		{1, "Down,Enter"},
+		{2, "Down,Down,Enter"},
+	}
+	for _, tt := range tests {
+		keys, ok := planChoiceKeys(pane, planChoices[tt.choice])
+		if !ok || strings.Join(keys, ",") != tt.want {
+			t.Errorf("choice %d: keys = %v, %v; want %s", tt.choice, keys, ok, tt.want)
+		}
+	}
+
+	up := strings.Replace(strings.Replace(pane, "❯ 2.", "  2.", 1), "  4.", "❯ 4.", 1)
+	if keys, _ := planChoiceKeys(up, planChoices[0]); strings.Join(keys, ",") != "Up,Up,Enter" {
+		t.Errorf("cursor below the choice: keys = %v", keys)
+	}
+
+	older := "❯ 1. Yes\n  2. No, keep planning\n"
+	if _, ok := planChoiceKeys(older, planChoices[0]); ok {
+		t.Error("a dialog without the option should not be answered")
+	}
+	if _, ok := planChoiceKeys("no dialog here", planChoices[2]); ok {
+		t.Error("no dialog on the pane")
+	}
+}
+
+func TestQuestionPromptLifecycle(t *testing.T) {
+	id1, _ := openQuestionPrompt(&questionPrompt{windowID: "@1", threadID: 5})
+	if !hasQuestionPrompt("@1") {
+		t.Fatal("prompt should be open")
+	}
+	id2, _ := openQuestionPrompt(&questionPrompt{windowID: "@1", threadID: 5})
+	if getQuestionPrompt(id1) != nil {
+		t.Error("a new dialog in the same topic should replace the old prompt")
+	}
+	closeQuestionPrompt(id2)
+	if hasQuestionPrompt("@1") {
+		t.Error("prompt should be closed")
+	}
+
+	openQuestionPrompt(&questionPrompt{windowID: "@2"})
+	clearWindowQuestions("@2")
+	if hasQuestionPrompt("@2") {
+		t.Error("clearWindowQuestions should drop the window's prompts")
+	}
+}
+
+func TestHandleQuestionFromMonitor_Fallback(t *testing.T) {
+	b := newTestBot(t)
+	input := json.RawMessage(`{"questions":[{"question":"Which DB?","options":[{"label":"Postgres"}]}]}`)
+	if b.HandleQuestionFromMonitor(100, 5, -1001, "~1", "AskUserQuestion", input) {
+		t.Error("headless windows have no dialog to drive")
+	}
+	if b.HandleQuestionFromMonitor(100, 5, -1001, "@1", "AskUserQuestion", json.RawMessage(`{"questions":[]}`)) {
+		t.Error("empty questions should fall back to the summary")
+	}
+}
+
+func callbackData(rows [][]tgbotapi.InlineKeyboardButton) []string {
+	var data []string
+	for _, row := range rows {
+		for _, btn := range row {
+			data = append(data, *btn.CallbackData)
+		}
+	}
+	return data
+}
@@ -1,31 +1,67 @@
 package bot
 
 import (
+	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/agent"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 // ReconcileState cleans up stale bindings by checking against live tmux windows.
 // Called on startup to handle bot restarts where windows may have died.
+// Dead windows whose transcript still exists are kept for ResumeDeadWindows.
 func (b *Bot) ReconcileState() int {
-	return b.reconcileState()
+	total, pending := b.reconcileState()
+	b.mu.Lock()
+	b.startupResumes = pending
+	b.mu.Unlock()
+	return total
 }
 
-func (b *Bot) reconcileState() int {
-	session := b.config.TmuxSessionName
+// ResumeDeadWindows relaunches, with claude --resume, the dead windows found by
+// ReconcileState. Each can take as long as Claude's startup, so serve runs
+// this in the background once it is handling updates.
+func (b *Bot) ResumeDeadWindows() {
+	b.mu.Lock()
+	pending := b.startupResumes
+	b.startupResumes = nil
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	resumed := b.resumeDeadWindows(pending)
+	log.Printf("Recovery: %d resumable dead sessions, %d bindings restored", len(pending), resumed)
+}
 
+// pendingResume describes a dead window to relaunch with its previous conversation.
+type pendingResume struct {
+	OldWindowID string
+	CWD         string
+	DisplayName string
+	Backend     string
+	Launch      state.LaunchProfile
+	Resume      resumeInfo
+	Bindings    []state.UserThread
+	ChatIDs     map[state.UserThread]int64
+}
+
+func (b *Bot) reconcileState() (int, []pendingResume) {
 	// Build map of live windows: windowID → Window
-	windows, err := tmux.ListWindows(session)
+	windows, err := b.liveWindows()
 	if err != nil {
 		log.Printf("Recovery: cannot list windows: %v", err)
-		return 0
+		return 0, nil
 	}
 
+	var pending []pendingResume
+
 	liveIDs := make(map[string]bool)
 	nameToID := make(map[string]string) // window_name → window_id
 	for _, w := range windows {
@@ -57,6 +93,27 @@ func (b *Bot) reconcileState() int {
 			}
 		}
 
+		// Resumable: remember bindings, then relaunch after cleanup
+		if ri := b.resumableSession(windowID); ri != nil {
+			ws, _ := b.state.GetWindowState(windowID)
+			pr := pendingResume{
+				OldWindowID: windowID,
+				CWD:         ws.CWD,
+				DisplayName: displayName,
+				Backend:     ws.Backend,
+				Launch:      ws.LaunchProfile,
+				Resume:      *ri,
+				Bindings:    b.state.FindUsersForWindow(windowID),
+				ChatIDs:     make(map[state.UserThread]int64),
+			}
+			for _, ut := range pr.Bindings {
+				if cid, ok := b.state.GetGroupChatID(ut.UserID, ut.ThreadID); ok {
+					pr.ChatIDs[ut] = cid
+				}
+			}
+			pending = append(pending, pr)
+		}
+
 		// Unresolvable: clean up everything for this window
 		cleanupDeadWindow(b, windowID)
 		dropped++
@@ -80,7 +137,187 @@ func (b *Bot) reconcileState() int {
 	log.Printf("Recovery: %d live bindings, %d re-resolved, %d dropped",
 		total, reresolved, dropped)
 
-	return total
+	return total, pending
+}
+
+// resumeDeadWindows relaunches dead windows in parallel and restores their
+// thread bindings. A topic bound to a new session while this ran keeps it.
+// Returns the number of bindings restored.
+func (b *Bot) resumeDeadWindows(pending []pendingResume) int {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	restored := 0
+	for _, pr := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := b.resumeDeadWindow(pr)
+			mu.Lock()
+			restored += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	b.saveState()
+	return restored
+}
+
+// resumeDeadWindow relaunches one dead window and rebinds the topics that
+// haven't been bound elsewhere meanwhile. Returns the bindings restored.
+func (b *Bot) resumeDeadWindow(pr pendingResume) int {
+	unbound := func() []state.UserThread {
+		var uts []state.UserThread
+		for _, ut := range pr.Bindings {
+			if _, bound := b.state.GetWindowForThread(ut.UserID, ut.ThreadID); !bound {
+				uts = append(uts, ut)
+			}
+		}
+		return uts
+	}
+	if len(unbound()) == 0 {
+		return 0
+	}
+
+	ri := pr.Resume
+	windowID, err := b.spawnWindow(pr.CWD, spawnOptions{Name: pr.DisplayName, Backend: pr.Backend, Resume: &ri, Launch: pr.Launch})
+	if err != nil {
+		log.Printf("Recovery: resuming %s in %s failed: %v", ri.SessionID, pr.CWD, err)
+		return 0
+	}
+	if pr.DisplayName != "" {
+		b.state.SetWindowDisplayName(windowID, pr.DisplayName)
+	}
+
+	bindings := unbound()
+	if len(bindings) == 0 {
+		log.Printf("Recovery: every topic of %s was rebound while resuming, closing %s", pr.OldWindowID, windowID)
+		if err := b.backendFor(windowID).Kill(windowID); err != nil {
+			log.Printf("Recovery: killing %s: %v", windowID, err)
+		}
+		cleanupDeadWindow(b, windowID)
+		return 0
+	}
+	for _, ut := range bindings {
+		b.state.BindThread(ut.UserID, ut.ThreadID, windowID)
+		if cid, ok := pr.ChatIDs[ut]; ok {
+			b.state.SetGroupChatID(ut.UserID, ut.ThreadID, cid)
+			tid, _ := strconv.Atoi(ut.ThreadID)
+			b.reply(cid, tid, "Session restored after restart, conversation resumed.")
+		}
+	}
+	log.Printf("Recovery: %s resumed session %s as %s", pr.OldWindowID, ri.SessionID, windowID)
+	return len(bindings)
+}
+
+// resumeInfo identifies a previous Claude conversation and where the monitor
+// had read its transcript up to.
+type resumeInfo struct {
+	SessionID string
+	FilePath  string
+	Offset    int64
+}
+
+// resumeCommand appends --resume to the Claude launch command.
+func resumeCommand(claudeCmd, sessionID string) string {
+	return fmt.Sprintf("%s --resume %s", claudeCmd, sessionID)
+}
+
+// resumableSession returns resume info for a (dead) window if its Claude session
+// transcript still exists on disk. Must be called before cleanupDeadWindow,
+// which drops the session_map and monitor state this relies on.
+func (b *Bot) resumableSession(windowID string) *resumeInfo {
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.SessionID == "" || ws.CWD == "" || !agent.Get(ws.Agent).CanResume {
+		return nil
+	}
+	if info, err := os.Stat(ws.CWD); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	ri := &resumeInfo{SessionID: ws.SessionID, Offset: -1}
+
+	// Prefer the monitor's offset for this window's session key
+	if b.monitorState != nil {
+		sessionMapPath := filepath.Join(b.config.TramuntanaDir, "session_map.json")
+		if sm, err := state.LoadSessionMap(sessionMapPath); err == nil {
+			for key := range sm {
+				if windowIDFromKey(key) != windowID {
+					continue
+				}
+				if tracked, ok := b.monitorState.GetTracked(key); ok && tracked.SessionID == ws.SessionID {
+					ri.FilePath = tracked.FilePath
+					ri.Offset = tracked.LastByteOffset
+				}
+			}
+		}
+	}
+
+	if ri.FilePath == "" {
+		ri.FilePath = b.findJSONLForSession(ws.SessionID)
+	}
+	if ri.FilePath == "" {
+		return nil
+	}
+	info, err := os.Stat(ri.FilePath)
+	if err != nil {
+		return nil
+	}
+	// Never tracked (or offset lost): skip everything already in the transcript
+	if ri.Offset < 0 || ri.Offset > info.Size() {
+		ri.Offset = info.Size()
+	}
+	return ri
+}
+
+// relinkResumedOffset points the monitor at the resumed transcript for the new
+// window, starting where it left off, so nothing is replayed.
+func (b *Bot) relinkResumedOffset(windowID string, ri resumeInfo) {
+	if b.monitorState == nil {
+		return
+	}
+	key := b.config.TmuxSessionName + ":" + windowID
+	b.monitorState.UpdateOffset(key, ri.SessionID, ri.FilePath, ri.Offset)
+}
+
+// relinkForkedSession handles Claude reporting a different session ID for a
+// resumed window (a forked transcript that repeats the old history): the new
+// transcript is followed from its current end.
+func (b *Bot) relinkForkedSession(key, sessionID string) {
+	if b.monitorState == nil {
+		return
+	}
+	path := b.findJSONLForSession(sessionID)
+	if path == "" {
+		// Not written yet — drop the stale offset and let the monitor start fresh
+		b.monitorState.RemoveSession(key)
+		return
+	}
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	log.Printf("Resumed window %s forked into session %s, following from offset %d", key, sessionID, size)
+	b.monitorState.UpdateOffset(key, sessionID, path, size)
+}
+
+// seedSessionMap writes a session_map entry for a resumed window when the hook
+// hasn't reported one yet. An entry written by the hook is never overwritten.
+func (b *Bot) seedSessionMap(windowID, cwd, name, sessionID string) {
+	key := b.config.TmuxSessionName + ":" + windowID
+	sessionMapPath := filepath.Join(b.config.TramuntanaDir, "session_map.json")
+	err := state.ReadModifyWriteSessionMap(sessionMapPath, func(data map[string]state.SessionMapEntry) {
+		if _, ok := data[key]; ok {
+			return
+		}
+		data[key] = state.SessionMapEntry{SessionID: sessionID, CWD: cwd, WindowName: name}
+	})
+	if err != nil {
+		log.Printf("Error seeding session map for %s: %v", key, err)
+		return
+	}
+	if _, ok := b.state.GetWindowState(windowID); !ok {
+		b.state.SetWindowState(windowID, state.WindowState{SessionID: sessionID, CWD: cwd, WindowName: name})
+	}
 }
 
 // reResolveWindow updates all references from oldID to newID.
@@ -142,11 +379,17 @@ func (b *Bot) handleDeadWindow(msg *tgbotapi.Message, windowID, pendingText stri
 	}
 
 	// Save info we need before cleanup
-	var cwd string
+	var cwd, backendName, agentName string
 	var projectBinding string
+	var launch state.LaunchProfile
 	if ws, ok := b.state.GetWindowState(windowID); ok {
 		cwd = ws.CWD
+		backendName = ws.Backend
+		agentName = ws.Agent
+		launch = ws.LaunchProfile
 	}
+	displayName, _ := b.state.GetWindowDisplayName(windowID)
+	resume := b.resumableSession(windowID)
 	if proj, ok := b.state.GetProject(threadID); ok {
 		projectBinding = proj
 	}
@@ -190,11 +433,20 @@ func (b *Bot) handleDeadWindow(msg *tgbotapi.Message, windowID, pendingText stri
 		return true
 	}
 
-	// Auto-recreate in the same directory
-	log.Printf("Dead window %s: auto-recreating in %s", windowID, cwd)
-	b.reply(chatID, threadIDInt, "Session died. Restarting...")
-
-	result, err := b.createWindowForDir(cwd, msg.From.ID, chatID, threadIDInt)
+	// Auto-recreate in the same directory, resuming the conversation if possible
+	var result *createWindowResult
+	var err error
+	if resume != nil {
+		log.Printf("Dead window %s: resuming session %s in %s", windowID, resume.SessionID, cwd)
+		b.reply(chatID, threadIDInt, "Session died. Resuming conversation...")
+		result, err = b.createWindowForDirWith(cwd, msg.From.ID, chatID, threadIDInt,
+			spawnOptions{Name: displayName, Backend: backendName, Resume: resume, Launch: launch})
+	} else {
+		log.Printf("Dead window %s: auto-recreating in %s", windowID, cwd)
+		b.reply(chatID, threadIDInt, "Session died. Restarting...")
+		result, err = b.createWindowForDirWith(cwd, msg.From.ID, chatID, threadIDInt,
+			spawnOptions{Backend: backendName, Agent: agentName, Launch: launch})
+	}
 	if err != nil {
 		log.Printf("Error auto-recreating window in %s: %v", cwd, err)
 		b.reply(chatID, threadIDInt, "Failed to restart. Send a message to try again.")
@@ -212,7 +464,7 @@ func (b *Bot) handleDeadWindow(msg *tgbotapi.Message, windowID, pendingText stri
 
 	// Send pending text to new session
 	if pendingText != "" {
-		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, result.WindowID, pendingText, 500); err != nil {
+		if err := b.backendFor(result.WindowID).SendText(result.WindowID, pendingText); err != nil {
 			log.Printf("Error sending pending text after recovery: %v", err)
 		}
 	}
@@ -232,6 +484,9 @@ func cleanupDeadWindow(b *Bot, windowID string) {
 
 	// Remove window state and display name
 	b.state.RemoveWindowState(windowID)
+	clearWindowPermissions(windowID)
+	clearWindowQuestions(windowID)
+	clearAutoRun(windowID)
 
 	// Remove monitor state and session_map entries
 	sessionMapPath := filepath.Join(b.config.TramuntanaDir, "session_map.json")
@@ -1,6 +1,8 @@
 package bot
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/otaviocarvalho/tramuntana/internal/state"
@@ -147,3 +149,97 @@ func TestFindUsersForWindow(t *testing.T) {
 		t.Error("should find both user1 and user2")
 	}
 }
+
+func TestResumeCommand(t *testing.T) {
+	got := resumeCommand("claude --dangerously-skip-permissions", "abc-123")
+	want := "claude --dangerously-skip-permissions --resume abc-123"
+	if got != want {
+		t.Errorf("resumeCommand = %q, want %q", got, want)
+	}
+}
+
+func TestResumableSession_UsesTrackedOffset(t *testing.T) {
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	b.monitorState = state.NewMonitorState()
+
+	cwd := t.TempDir()
+	jsonl := filepath.Join(t.TempDir(), "sess1.jsonl")
+	os.WriteFile(jsonl, []byte("{}\n{}\n{}\n"), 0o644)
+
+	b.state.SetWindowState("@dead", state.WindowState{SessionID: "sess1", CWD: cwd})
+	state.WriteSessionMap(filepath.Join(b.config.TramuntanaDir, "session_map.json"), map[string]state.SessionMapEntry{
+		"test-session:@dead": {SessionID: "sess1", CWD: cwd},
+	})
+	b.monitorState.UpdateOffset("test-session:@dead", "sess1", jsonl, 3)
+
+	ri := b.resumableSession("@dead")
+	if ri == nil {
+		t.Fatal("expected window to be resumable")
+	}
+	if ri.SessionID != "sess1" || ri.FilePath != jsonl || ri.Offset != 3 {
+		t.Errorf("resumeInfo = %+v, want sess1 at offset 3", *ri)
+	}
+
+	// Re-linking carries the offset over to the new window's key
+	b.relinkResumedOffset("@new", *ri)
+	tracked, ok := b.monitorState.GetTracked("test-session:@new")
+	if !ok || tracked.LastByteOffset != 3 || tracked.SessionID != "sess1" {
+		t.Errorf("relinked tracked = %+v %v, want sess1 at offset 3", tracked, ok)
+	}
+}
+
+func TestResumableSession_UntrackedStartsAtEnd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	projectDir := filepath.Join(home, ".claude", "projects", "-tmp-proj")
+	os.MkdirAll(projectDir, 0o755)
+	content := []byte("{}\n{}\n")
+	os.WriteFile(filepath.Join(projectDir, "sess2.jsonl"), content, 0o644)
+
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	b.state.SetWindowState("@dead", state.WindowState{SessionID: "sess2", CWD: t.TempDir()})
+
+	ri := b.resumableSession("@dead")
+	if ri == nil {
+		t.Fatal("expected window to be resumable")
+	}
+	if ri.Offset != int64(len(content)) {
+		t.Errorf("offset = %d, want %d (already-sent history must not replay)", ri.Offset, len(content))
+	}
+}
+
+func TestResumableSession_NoTranscript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	b.state.SetWindowState("@dead", state.WindowState{SessionID: "missing", CWD: t.TempDir()})
+
+	if ri := b.resumableSession("@dead"); ri != nil {
+		t.Errorf("expected nil without transcript, got %+v", *ri)
+	}
+}
+
+func TestSeedSessionMap_KeepsHookEntry(t *testing.T) {
+	b := newTestBot(t)
+	b.config.TramuntanaDir = t.TempDir()
+	path := filepath.Join(b.config.TramuntanaDir, "session_map.json")
+	state.WriteSessionMap(path, map[string]state.SessionMapEntry{
+		"test-session:@5": {SessionID: "from-hook"},
+	})
+
+	b.seedSessionMap("@5", "/tmp", "w", "resumed")
+	b.seedSessionMap("@6", "/tmp", "w", "resumed")
+
+	sm, err := state.LoadSessionMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm["test-session:@5"].SessionID != "from-hook" {
+		t.Error("hook entry should not be overwritten")
+	}
+	if sm["test-session:@6"].SessionID != "resumed" {
+		t.Error("missing entry should be seeded")
+	}
+}
@@ -0,0 +1,279 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+	"github.com/robfig/cron/v3"
+)
+
+const scheduleUsage = "Usage: /c_schedule <when> <prompt>\n" +
+	"e.g. /c_schedule 0 9 * * 1-5 Summarise yesterday's commits\n" +
+	"     /c_schedule @every 2h Run the test suite and report failures\n" +
+	"     /c_schedule CRON_TZ=Europe/Madrid 30 7 * * * Check the nightly build\n\n" +
+	"<when> is a 5-field cron expression (minute hour day month weekday), " +
+	"@hourly/@daily/@weekly/@monthly or @every <duration>.\n" +
+	"Send /c_schedule alone to list, pause and delete schedules."
+
+// scheduleTick is how often the scheduler checks for due schedules. Cron's
+// finest unit is a minute, so a run lands within this much of its time.
+const scheduleTick = 20 * time.Second
+
+// Scheduler sends each topic's scheduled prompts to its session when they
+// come due. A run is skipped, with a notice in the topic, if Claude is still
+// working on a turn; missed runs aren't caught up after downtime.
+type Scheduler struct {
+	bot *Bot
+}
+
+// NewScheduler creates a scheduler for the bot's stored schedules.
+func NewScheduler(b *Bot) *Scheduler {
+	return &Scheduler{bot: b}
+}
+
+// Run checks for due schedules until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Println("Scheduler starting...")
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Scheduler stopping.")
+			return
+		case now := <-ticker.C:
+			for _, sc := range dueSchedules(s.bot.state.AllSchedules(), last, now) {
+				s.bot.runSchedule(sc, now)
+			}
+			last = now
+		}
+	}
+}
+
+// dueSchedules returns the active schedules with a run in (from, to]. An
+// @every schedule counts from its last run, or from when it was added.
+func dueSchedules(list []state.Schedule, from, to time.Time) []state.Schedule {
+	var due []state.Schedule
+	for _, sc := range list {
+		if sc.Paused {
+			continue
+		}
+		sched, err := cron.ParseStandard(sc.Spec)
+		if err != nil {
+			log.Printf("Schedule %s: bad spec %q: %v", sc.ID, sc.Spec, err)
+			continue
+		}
+		base := from
+		if _, every := sched.(cron.ConstantDelaySchedule); every {
+			base = sc.LastRun
+			if base.IsZero() {
+				base = sc.Created
+			}
+		}
+		if next := sched.Next(base); !next.IsZero() && !next.After(to) {
+			due = append(due, sc)
+		}
+	}
+	return due
+}
+
+// runSchedule sends a due schedule's prompt to the session bound to its topic.
+func (b *Bot) runSchedule(sc state.Schedule, now time.Time) {
+	b.state.RecordScheduleRun(sc.ID, now)
+	b.saveState()
+
+	windowID, bound := b.state.GetWindowForThread(sc.User, sc.Thread)
+	if !bound {
+		log.Printf("Schedule %s: topic %s isn't bound to a session, skipping", sc.ID, sc.Thread)
+		return
+	}
+	chatID, ok := b.state.GetGroupChatID(sc.User, sc.Thread)
+	if !ok {
+		log.Printf("Schedule %s: no chat for topic %s, skipping", sc.ID, sc.Thread)
+		return
+	}
+	threadID, _ := strconv.Atoi(sc.Thread)
+	prompt := truncateDetail(strings.Join(strings.Fields(sc.Prompt), " "), 200)
+
+	if sp := b.statusPoller; sp != nil {
+		if sp.Busy(windowID) {
+			b.reply(chatID, threadID, "⏰ Skipped scheduled prompt, Claude is busy: "+prompt)
+			return
+		}
+		sp.MarkBusy(windowID)
+	}
+
+	b.reply(chatID, threadID, "⏰ Scheduled: "+prompt)
+	if err := b.backendFor(windowID).SendText(windowID, sc.Prompt); err != nil {
+		log.Printf("Schedule %s: sending to %s: %v", sc.ID, windowID, err)
+		switch {
+		case backend.IsDead(err):
+			b.reply(chatID, threadID, "The session isn't running; send a message to restart it. The schedule stays in place.")
+		case errors.Is(err, tmux.ErrPasteUnconfirmed):
+			b.reply(chatID, threadID, "The scheduled prompt was pasted but didn't show up in Claude's input, so it wasn't submitted.")
+		default:
+			b.reply(chatID, threadID, "Error: failed to send the scheduled prompt.")
+		}
+	}
+}
+
+// splitSchedule splits "/c_schedule" arguments into the cron spec and the
+// prompt. The spec is five fields, an @descriptor, or "@every <duration>",
+// optionally preceded by a CRON_TZ= or TZ= time zone.
+func splitSchedule(args string) (spec, prompt string, err error) {
+	fields := strings.Fields(args)
+	n := 0
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "CRON_TZ=") || strings.HasPrefix(fields[0], "TZ=")) {
+		n++
+	}
+	switch {
+	case n >= len(fields):
+		return "", "", errors.New("missing schedule")
+	case fields[n] == "@every":
+		n += 2
+	case strings.HasPrefix(fields[n], "@"):
+		n++
+	default:
+		n += 5
+	}
+	if n > len(fields) {
+		return "", "", errors.New("incomplete schedule")
+	}
+	spec = strings.Join(fields[:n], " ")
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return "", "", fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	// Keep the prompt's own line breaks: cut the spec off the raw text
+	prompt = strings.TrimSpace(args)
+	for _, f := range fields[:n] {
+		prompt = strings.TrimSpace(strings.TrimPrefix(prompt, f))
+	}
+	if prompt == "" {
+		return "", "", errors.New("missing prompt")
+	}
+	return spec, prompt, nil
+}
+
+// handleScheduleCommand lists the topic's schedules or adds one.
+func (b *Bot) handleScheduleCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	threadIDStr := strconv.Itoa(threadID)
+	userID := strconv.FormatInt(msg.From.ID, 10)
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		text, kb := b.buildScheduleList(threadIDStr)
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+			log.Printf("Error sending schedules: %v", err)
+		}
+		return
+	}
+
+	spec, prompt, err := splitSchedule(args)
+	if err != nil {
+		b.reply(chatID, threadID, "Error: "+err.Error()+".\n\n"+scheduleUsage)
+		return
+	}
+	if _, bound := b.state.GetWindowForThread(userID, threadIDStr); !bound {
+		b.reply(chatID, threadID, "This topic isn't bound to a session. Send a text message to start one first.")
+		return
+	}
+	b.state.SetGroupChatID(userID, threadIDStr, chatID)
+
+	sc, added := b.state.AddSchedule(state.Schedule{
+		Thread: threadIDStr, User: userID, Spec: spec, Prompt: prompt, Created: time.Now(),
+	})
+	if !added {
+		b.reply(chatID, threadID, "Schedule already exists: "+describeSchedule(sc))
+		return
+	}
+	b.saveState()
+	b.reply(chatID, threadID, "Scheduled "+describeSchedule(sc))
+}
+
+// processScheduleCallback handles sched_pause/sched_resume/sched_del:<id>
+// and sched_close buttons.
+func (b *Bot) processScheduleCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	if cq.Data == "sched_close" {
+		b.editMessageText(chatID, messageID, "Schedules closed.")
+		return
+	}
+
+	action, id, _ := strings.Cut(strings.TrimPrefix(cq.Data, "sched_"), ":")
+	var changed bool
+	switch action {
+	case "pause":
+		changed = b.state.SetSchedulePaused(id, true)
+	case "resume":
+		changed = b.state.SetSchedulePaused(id, false)
+	case "del":
+		changed = b.state.RemoveSchedule(id)
+	}
+	if changed {
+		b.saveState()
+	}
+
+	text, kb := b.buildScheduleList(strconv.Itoa(getThreadID(cq.Message)))
+	if err := b.editMessageWithKeyboard(chatID, messageID, text, kb); err != nil {
+		log.Printf("Error refreshing schedules: %v", err)
+	}
+}
+
+// buildScheduleList renders a topic's schedules with pause/resume and
+// delete buttons for each.
+func (b *Bot) buildScheduleList(threadID string) (string, tgbotapi.InlineKeyboardMarkup) {
+	schedules := b.state.TopicSchedules(threadID)
+
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	fmt.Fprintf(&sb, "Schedules (%d)", len(schedules))
+	for i, sc := range schedules {
+		n := strconv.Itoa(i + 1)
+		fmt.Fprintf(&sb, "\n%s. %s", n, describeSchedule(sc))
+		if !sc.LastRun.IsZero() {
+			fmt.Fprintf(&sb, " — last run %s", sc.LastRun.Format("Jan 2 15:04"))
+		}
+
+		toggle := tgbotapi.NewInlineKeyboardButtonData("⏸ "+n, "sched_pause:"+sc.ID)
+		if sc.Paused {
+			toggle = tgbotapi.NewInlineKeyboardButtonData("▶ "+n, "sched_resume:"+sc.ID)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			toggle,
+			tgbotapi.NewInlineKeyboardButtonData("✖ "+n, "sched_del:"+sc.ID),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Close", "sched_close"),
+	))
+
+	if len(schedules) == 0 {
+		return "No schedules for this topic.\n\n" + scheduleUsage, tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// describeSchedule renders a schedule as "[0 9 * * 1-5] Summarise… (paused)".
+func describeSchedule(sc state.Schedule) string {
+	s := "[" + sc.Spec + "] " + truncateDetail(strings.Join(strings.Fields(sc.Prompt), " "), 60)
+	if sc.Paused {
+		s += " (paused)"
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestSplitSchedule(t *testing.T) {
+	tests := []struct {
+		args       string
+		wantSpec   string
+		wantPrompt string
+		wantErr    bool
+	}{
+		{"0 9 * * 1-5 Summarise yesterday's commits", "0 9 * * 1-5", "Summarise yesterday's commits", false},
+		{"@every 2h run the tests", "@every 2h", "run the tests", false},
+		{"@daily check the build", "@daily", "check the build", false},
+		{"CRON_TZ=Europe/Madrid 30 7 * * * nightly", "CRON_TZ=Europe/Madrid 30 7 * * *", "nightly", false},
+		{"*/15 * * * * first line\nsecond line", "*/15 * * * *", "first line\nsecond line", false},
+		{"0 9 * * 1-5", "", "", true},
+		{"0 9 * *", "", "", true},
+		{"61 9 * * * bad minute", "", "", true},
+		{"@every soon do it", "", "", true},
+		{"@sometimes do it", "", "", true},
+		{"CRON_TZ=Nowhere/Land 0 9 * * * tz", "", "", true},
+		{"", "", "", true},
+	}
+	for _, tt := range tests {
+		spec, prompt, err := splitSchedule(tt.args)
+		if (err != nil) != tt.wantErr || spec != tt.wantSpec || prompt != tt.wantPrompt {
+			t.Errorf("splitSchedule(%q) = %q, %q, %v", tt.args, spec, prompt, err)
+		}
+	}
+}
+
+func TestDueSchedules(t *testing.T) {
+	start := time.Date(2026, 3, 4, 8, 59, 40, 0, time.Local)
+	list := []state.Schedule{
+		{ID: "nine", Spec: "0 9 * * *"},
+		{ID: "paused", Spec: "0 9 * * *", Paused: true},
+		{ID: "ten", Spec: "0 10 * * *"},
+		{ID: "every", Spec: "@every 30m", Created: start.Add(-29 * time.Minute)},
+		{ID: "bad", Spec: "not a spec"},
+	}
+	ids := func(due []state.Schedule) string {
+		var out []string
+		for _, sc := range due {
+			out = append(out, sc.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(dueSchedules(list, start, start.Add(20*time.Second))); got != "nine" {
+		t.Errorf("due at 09:00 = %q, want nine", got)
+	}
+	// @every counts from when the schedule was added, then from its last run
+	if got := ids(dueSchedules(list, start.Add(40*time.Second), start.Add(60*time.Second))); got != "every" {
+		t.Errorf("due at 09:00:40 = %q, want every", got)
+	}
+	list[3].LastRun = start.Add(60 * time.Second)
+	if got := ids(dueSchedules(list[3:4], start.Add(60*time.Second), start.Add(29*time.Minute))); got != "" {
+		t.Errorf("@every 30m fired again within 30m: %q", got)
+	}
+	list[3].LastRun = time.Time{}
+	next := start.Add(20 * time.Second)
+	if got := ids(dueSchedules(list, next, next.Add(20*time.Second))); got != "" {
+		t.Errorf("09:00 run should not repeat on the next tick, got %q", got)
+	}
+	// A long gap fires each schedule at most once
+	if got := ids(dueSchedules(list, start, start.Add(3*time.Hour))); got != "nine,ten,every" {
+		t.Errorf("due over 3h = %q, want nine,ten,every", got)
+	}
+}
+
+func TestBuildScheduleList(t *testing.T) {
+	b := newTestBot(t)
+	sc, _ := b.state.AddSchedule(state.Schedule{Thread: "42", User: "1", Spec: "@daily", Prompt: "report"})
+	b.state.AddSchedule(state.Schedule{Thread: "42", User: "1", Spec: "@hourly", Prompt: "tests", Paused: true})
+	b.state.AddSchedule(state.Schedule{Thread: "99", User: "1", Spec: "@daily", Prompt: "other"})
+
+	text, kb := b.buildScheduleList("42")
+	if !strings.Contains(text, "Schedules (2)") || !strings.Contains(text, "[@hourly] tests (paused)") {
+		t.Errorf("unexpected list text: %q", text)
+	}
+	if len(kb.InlineKeyboard) != 3 {
+		t.Fatalf("expected 2 schedule rows and Close, got %d rows", len(kb.InlineKeyboard))
+	}
+	if got := *kb.InlineKeyboard[0][0].CallbackData; got != "sched_pause:"+sc.ID {
+		t.Errorf("first row toggle = %q", got)
+	}
+	if got := *kb.InlineKeyboard[1][0].CallbackData; !strings.HasPrefix(got, "sched_resume:") {
+		t.Errorf("paused schedule should offer resume, got %q", got)
+	}
+
+	if text, _ := b.buildScheduleList("7"); !strings.HasPrefix(text, "No schedules") {
+		t.Errorf("empty list text = %q", text)
+	}
+}
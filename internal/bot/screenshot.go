@@ -2,6 +2,7 @@ package bot
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -9,8 +10,8 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/render"
-	"github.com/otaviocarvalho/tramuntana/internal/tmux"
 )
 
 // screenshotState tracks the current screenshot message per (user, thread).
@@ -84,12 +85,16 @@ func (b *Bot) handleScreenshotCommand(msg *tgbotapi.Message) {
 		return
 	}
 
-	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, true)
+	paneText, err := b.backendFor(windowID).Capture(windowID, true)
 	if err != nil {
-		if tmux.IsWindowDead(err) {
+		if backend.IsDead(err) {
 			b.handleDeadWindow(msg, windowID, "")
 			return
 		}
+		if errors.Is(err, backend.ErrNoTerminal) {
+			b.reply(chatID, threadID, "Screenshots are not available for headless sessions.")
+			return
+		}
 		log.Printf("Error capturing pane for screenshot: %v", err)
 		b.reply(chatID, threadID, "Error: failed to capture pane.")
 		return
@@ -145,9 +150,9 @@ func (b *Bot) handleScreenshotCB(cq *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	// Send key to tmux
-	if err := tmux.SendSpecialKey(b.config.TmuxSessionName, windowID, tmuxKey); err != nil {
-		if tmux.IsWindowDead(err) {
+	// Send key to the session
+	if err := b.backendFor(windowID).SendKey(windowID, tmuxKey); err != nil {
+		if backend.IsDead(err) {
 			log.Printf("Screenshot callback: window %s is dead", windowID)
 		} else {
 			log.Printf("Error sending key %s to %s: %v", tmuxKey, windowID, err)
@@ -164,9 +169,9 @@ func (b *Bot) handleScreenshotCB(cq *tgbotapi.CallbackQuery) {
 
 // refreshScreenshot captures, renders, and edits the screenshot message.
 func (b *Bot) refreshScreenshot(cq *tgbotapi.CallbackQuery, windowID string) {
-	paneText, err := tmux.CapturePane(b.config.TmuxSessionName, windowID, true)
+	paneText, err := b.backendFor(windowID).Capture(windowID, true)
 	if err != nil {
-		if tmux.IsWindowDead(err) {
+		if backend.IsDead(err) {
 			log.Printf("Screenshot refresh: window %s is dead", windowID)
 		} else {
 			log.Printf("Error capturing pane for refresh: %v", err)
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxSlashCommandName keeps "cmds_run:<name>" within Telegram's 64-byte
+// callback data limit.
+const maxSlashCommandName = 50
+
+var (
+	// reSlashCommandName matches the command names this bot can forward:
+	// a command file's base name.
+	reSlashCommandName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// rePositionalArg matches $1…$9 in a command body.
+	rePositionalArg = regexp.MustCompile(`\$[1-9]\b`)
+)
+
+// slashCommand is a custom Claude Code command defined in a .claude/commands
+// Markdown file.
+type slashCommand struct {
+	Name        string
+	Description string
+	ArgHint     string
+	TakesArgs   bool
+	Scope       string // "project" or "user"
+	Namespace   string // subdirectory under .claude/commands, e.g. "frontend"
+}
+
+// discoverSlashCommands returns the commands defined under cwd/.claude/commands
+// and home/.claude/commands, sorted by name. As in Claude Code, a command is
+// named after its file, and a subdirectory only namespaces its description:
+// frontend/component.md is /component. A project command hides a user command
+// of the same name; within a scope the first file found wins.
+func discoverSlashCommands(cwd, home string) []slashCommand {
+	byName := make(map[string]slashCommand)
+	for _, src := range []struct{ root, scope string }{{home, "user"}, {cwd, "project"}} {
+		if src.root == "" {
+			continue
+		}
+		dir := filepath.Join(src.root, ".claude", "commands")
+		seen := make(map[string]bool)
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			name := strings.TrimSuffix(d.Name(), ".md")
+			if len(name) > maxSlashCommandName || !reSlashCommandName.MatchString(name) || seen[name] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Printf("Error reading command %s: %v", path, err)
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, filepath.Dir(path))
+			cmd := parseSlashCommand(string(data))
+			cmd.Name, cmd.Scope = name, src.scope
+			if rel != "." {
+				cmd.Namespace = strings.ReplaceAll(filepath.ToSlash(rel), "/", ":")
+			}
+			seen[name] = true
+			byName[name] = cmd
+			return nil
+		})
+	}
+
+	out := make([]slashCommand, 0, len(byName))
+	for _, c := range byName {
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b slashCommand) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// parseSlashCommand reads a command file's frontmatter description and
+// argument-hint. Without a description, the first line of the body is used.
+// The command takes arguments if its body uses $ARGUMENTS or positional
+// $1…$9, or it declares an argument-hint.
+func parseSlashCommand(content string) slashCommand {
+	var cmd slashCommand
+	body := content
+	if rest, ok := strings.CutPrefix(content, "---\n"); ok {
+		if front, after, found := strings.Cut(rest, "\n---"); found {
+			body = after
+			sc := bufio.NewScanner(strings.NewReader(front))
+			for sc.Scan() {
+				key, value, ok := strings.Cut(sc.Text(), ":")
+				if !ok {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"'`)
+				switch strings.TrimSpace(key) {
+				case "description":
+					cmd.Description = value
+				case "argument-hint":
+					cmd.ArgHint = value
+				}
+			}
+		}
+	}
+
+	if cmd.Description == "" {
+		for _, line := range strings.Split(body, "\n") {
+			if line = strings.TrimSpace(strings.TrimLeft(line, "# ")); line != "" && line != "-" {
+				cmd.Description = truncateDetail(line, 80)
+				break
+			}
+		}
+	}
+	cmd.TakesArgs = cmd.ArgHint != "" || strings.Contains(body, "$ARGUMENTS") || rePositionalArg.MatchString(body)
+	return cmd
+}
+
+// topicSlashCommands returns the commands available to the session bound to
+// a topic, from its working directory and the user's home.
+func (b *Bot) topicSlashCommands(msg *tgbotapi.Message) ([]slashCommand, bool) {
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		return nil, false
+	}
+	var cwd string
+	if ws, ok := b.state.GetWindowState(windowID); ok {
+		cwd = ws.CWD
+	}
+	home, _ := os.UserHomeDir()
+	return discoverSlashCommands(cwd, home), true
+}
+
+// handleCmdsCommand lists the session's custom commands, or runs one:
+// /c_cmds <name> [args].
+func (b *Bot) handleCmdsCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	cmds, bound := b.topicSlashCommands(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session. Send a message to bind.")
+		return
+	}
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	if args == "" {
+		text, kb := buildSlashCommandList(cmds)
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+			log.Printf("Error sending command list: %v", err)
+		}
+		return
+	}
+
+	name, cmdArgs, _ := strings.Cut(args, " ")
+	name = strings.TrimPrefix(name, "/")
+	i := slices.IndexFunc(cmds, func(c slashCommand) bool { return c.Name == name })
+	if i < 0 {
+		b.reply(chatID, threadID, "No command /"+name+" in this project. Send /c_cmds to list them.")
+		return
+	}
+	b.runSlashCommand(msg, cmds[i], strings.TrimSpace(cmdArgs))
+}
+
+// buildSlashCommandList renders the commands with their descriptions, one
+// button per command.
+func buildSlashCommandList(cmds []slashCommand) (string, tgbotapi.InlineKeyboardMarkup) {
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	fmt.Fprintf(&sb, "Custom commands (%d). Tap one to run it.", len(cmds))
+	for _, c := range cmds {
+		fmt.Fprintf(&sb, "\n/%s", c.Name)
+		if c.ArgHint != "" {
+			sb.WriteString(" " + c.ArgHint)
+		}
+		if c.Description != "" {
+			sb.WriteString(" — " + c.Description)
+		}
+		switch {
+		case c.Scope == "user" && c.Namespace != "":
+			sb.WriteString(" (user:" + c.Namespace + ")")
+		case c.Scope == "user":
+			sb.WriteString(" (user)")
+		case c.Namespace != "":
+			sb.WriteString(" (" + c.Namespace + ")")
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("/"+c.Name, "cmds_run:"+c.Name),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Close", "cmds_close"),
+	))
+
+	if len(cmds) == 0 {
+		return "No custom commands found in .claude/commands of the session's directory or your home.", tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// processCmdsCallback handles cmds_run:<name> and cmds_close buttons.
+func (b *Bot) processCmdsCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+
+	name, ok := strings.CutPrefix(cq.Data, "cmds_run:")
+	if !ok {
+		b.editMessageText(chatID, cq.Message.MessageID, "Custom commands closed.")
+		return
+	}
+
+	// Act as if the user had typed /c_cmds <name>
+	msg := *cq.Message
+	msg.From = cq.From
+	cmds, bound := b.topicSlashCommands(&msg)
+	i := slices.IndexFunc(cmds, func(c slashCommand) bool { return c.Name == name })
+	if !bound || i < 0 {
+		b.reply(chatID, getThreadID(cq.Message), "Command /"+name+" is no longer available.")
+		return
+	}
+	b.runSlashCommand(&msg, cmds[i], "")
+}
+
+// runSlashCommand forwards a command to the session, first asking for its
+// arguments if it takes some and none were given.
+func (b *Bot) runSlashCommand(msg *tgbotapi.Message, cmd slashCommand, args string) {
+	if args == "" && cmd.TakesArgs {
+		prompt := "Arguments for /" + cmd.Name
+		if cmd.ArgHint != "" {
+			prompt += " " + cmd.ArgHint
+		}
+		b.reply(msg.Chat.ID, getThreadID(msg), prompt+":")
+		b.setPendingInput(msg.From.ID, "c_cmds:"+cmd.Name, msg.Chat.ID, getThreadID(msg))
+		return
+	}
+	text := cmd.Name
+	if args != "" {
+		text += " " + args
+	}
+	b.forwardCommand(msg, text)
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	tests := []struct {
+		content  string
+		wantDesc string
+		wantHint string
+		wantArgs bool
+	}{
+		{"---\ndescription: \"Review a PR\"\nargument-hint: [pr-number]\n---\nReview PR #$1.", "Review a PR", "[pr-number]", true},
+		{"---\ndescription: Run the linters\n---\nRun make lint and fix what it reports.", "Run the linters", "", false},
+		{"# Fix issue\n\nFix the issue described in $ARGUMENTS.", "Fix issue", "", true},
+		{"Summarise the changes on this branch.", "Summarise the changes on this branch.", "", false},
+		{"Costs $100 to run.", "Costs $100 to run.", "", false},
+	}
+	for _, tt := range tests {
+		got := parseSlashCommand(tt.content)
+		if got.Description != tt.wantDesc || got.ArgHint != tt.wantHint || got.TakesArgs != tt.wantArgs {
+			t.Errorf("parseSlashCommand(%q) = %+v", tt.content, got)
+		}
+	}
+}
+
+func TestDiscoverSlashCommands(t *testing.T) {
+	cwd, home := t.TempDir(), t.TempDir()
+	write := func(root, rel, content string) {
+		path := filepath.Join(root, ".claude", "commands", rel)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(cwd, "review.md", "---\ndescription: Project review\n---\nReview $ARGUMENTS")
+	write(cwd, "frontend/component.md", "Create a component")
+	write(cwd, "notes.txt", "not a command")
+	write(home, "review.md", "User review")
+	write(home, "standup.md", "Write my standup")
+
+	cmds := discoverSlashCommands(cwd, home)
+	var names []string
+	for _, c := range cmds {
+		names = append(names, c.Name+"/"+c.Scope)
+	}
+	if got := strings.Join(names, ","); got != "component/project,review/project,standup/user" {
+		t.Fatalf("discovered %s", got)
+	}
+	if cmds[0].Namespace != "frontend" || cmds[1].Namespace != "" {
+		t.Errorf("unexpected namespaces %q, %q", cmds[0].Namespace, cmds[1].Namespace)
+	}
+	if cmds[1].Description != "Project review" || !cmds[1].TakesArgs {
+		t.Errorf("project review should hide the user one, got %+v", cmds[1])
+	}
+
+	if got := discoverSlashCommands(t.TempDir(), ""); len(got) != 0 {
+		t.Errorf("expected no commands, got %+v", got)
+	}
+}
+
+func TestBuildSlashCommandList(t *testing.T) {
+	cmds := []slashCommand{
+		{Name: "review", Description: "Review a PR", ArgHint: "[pr]", Scope: "project"},
+		{Name: "standup", Description: "Write my standup", Scope: "user"},
+		{Name: "component", Description: "Create a component", Scope: "project", Namespace: "frontend"},
+	}
+	text, kb := buildSlashCommandList(cmds)
+	if !strings.Contains(text, "/review [pr] — Review a PR") || !strings.Contains(text, "/standup — Write my standup (user)") ||
+		!strings.Contains(text, "/component — Create a component (frontend)") {
+		t.Errorf("unexpected list text: %q", text)
+	}
+	if len(kb.InlineKeyboard) != 4 || *kb.InlineKeyboard[0][0].CallbackData != "cmds_run:review" {
+		t.Errorf("unexpected keyboard: %+v", kb.InlineKeyboard)
+	}
+}
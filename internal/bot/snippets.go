@@ -0,0 +1,402 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
+)
+
+const snipUsage = "Usage:\n" +
+	"/c_snip — list snippets\n" +
+	"/c_snip <name> [key=value ...] — send a snippet, asking for missing {{params}}\n" +
+	"/c_snip add <name> <text> — save a snippet (for the bound project, or shared)\n" +
+	"/c_snip rm <name> — delete a snippet\n\n" +
+	"Values with spaces go in quotes: /c_snip review focus=\"error handling\""
+
+// snippetsDir holds snippets under TRAMUNTANA_DIR: shared ones at the top
+// level, a project's in a subdirectory named after it.
+const snippetsDir = "snippets"
+
+var (
+	// reSnippetName matches names usable as a file name and in callback data.
+	reSnippetName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,40}$`)
+	// rePlaceholder matches a {{param}} in a snippet body.
+	rePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+)
+
+// snippet is a saved prompt with {{placeholders}}.
+type snippet struct {
+	Name    string
+	Body    string
+	Project string // "" for shared snippets
+}
+
+// snippetFill is a snippet waiting for the user to supply its missing params.
+type snippetFill struct {
+	Snippet snippet
+	Params  map[string]string
+	Missing []string
+}
+
+// projectSnippetsDir returns the directory of a project's snippets, or the
+// shared directory when project is empty.
+func projectSnippetsDir(root, project string) string {
+	dir := filepath.Join(root, snippetsDir)
+	if project != "" {
+		dir = filepath.Join(dir, sanitizeUploadName(project))
+	}
+	return dir
+}
+
+// loadSnippets returns the shared snippets and the project's, sorted by name.
+// A project snippet hides a shared one of the same name.
+func loadSnippets(root, project string) ([]snippet, error) {
+	byName := make(map[string]snippet)
+	dirs := []string{""}
+	if project != "" {
+		dirs = append(dirs, project)
+	}
+	for _, p := range dirs {
+		entries, err := os.ReadDir(projectSnippetsDir(root, p))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name, ok := strings.CutSuffix(e.Name(), ".md")
+			if e.IsDir() || !ok || !reSnippetName.MatchString(name) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(projectSnippetsDir(root, p), e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			byName[name] = snippet{Name: name, Body: strings.TrimSpace(string(data)), Project: p}
+		}
+	}
+
+	out := make([]snippet, 0, len(byName))
+	for _, s := range byName {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b snippet) int { return strings.Compare(a.Name, b.Name) })
+	return out, nil
+}
+
+// findSnippet returns the named snippet visible to a project.
+func findSnippet(root, project, name string) (snippet, bool, error) {
+	all, err := loadSnippets(root, project)
+	if err != nil {
+		return snippet{}, false, err
+	}
+	i := slices.IndexFunc(all, func(s snippet) bool { return s.Name == name })
+	if i < 0 {
+		return snippet{}, false, nil
+	}
+	return all[i], true, nil
+}
+
+// saveSnippet writes a snippet for a project (or shared), replacing any
+// snippet of the same name there.
+func saveSnippet(root, project, name, body string) error {
+	dir := projectSnippetsDir(root, project)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".md"), []byte(strings.TrimSpace(body)+"\n"), 0o644)
+}
+
+// removeSnippet deletes a project's snippet, or a shared one if the project
+// has none of that name. Returns false if neither exists.
+func removeSnippet(root, project, name string) (bool, error) {
+	s, ok, err := findSnippet(root, project, name)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := os.Remove(filepath.Join(projectSnippetsDir(root, s.Project), name+".md")); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// snippetParams returns the distinct placeholder names of a body, in order
+// of first use.
+func snippetParams(body string) []string {
+	var params []string
+	for _, m := range rePlaceholder.FindAllStringSubmatch(body, -1) {
+		if !slices.Contains(params, m[1]) {
+			params = append(params, m[1])
+		}
+	}
+	return params
+}
+
+// expandSnippet replaces each {{param}} with its value. Placeholders without
+// a value are left as they are.
+func expandSnippet(body string, params map[string]string) string {
+	return rePlaceholder.ReplaceAllStringFunc(body, func(m string) string {
+		if v, ok := params[rePlaceholder.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// parseSnippetArgs parses `<name> [key=value ...]`, where a value may be
+// double-quoted to hold spaces. Returns false on a malformed argument.
+func parseSnippetArgs(args string) (string, map[string]string, bool) {
+	name, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	params := make(map[string]string)
+	rest = strings.TrimSpace(rest)
+	for rest != "" {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t\n") {
+			return "", nil, false
+		}
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end < 0 {
+				return "", nil, false
+			}
+			value, rest = after[1:end+1], after[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(after, " ")
+		}
+		params[key] = value
+		rest = strings.TrimSpace(rest)
+	}
+	return name, params, true
+}
+
+// handleSnipCommand lists, sends, saves or deletes snippets.
+func (b *Bot) handleSnipCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	project, _ := b.state.GetProject(strconv.Itoa(threadID))
+	root := b.config.TramuntanaDir
+
+	args := strings.TrimSpace(msg.CommandArguments())
+	verb, rest := cutWord(args)
+	switch verb {
+	case "":
+		text, kb, err := b.buildSnippetList(project)
+		if err != nil {
+			log.Printf("Error loading snippets: %v", err)
+			b.reply(chatID, threadID, "Error: failed to load snippets.")
+			return
+		}
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+			log.Printf("Error sending snippets: %v", err)
+		}
+
+	case "add":
+		name, body := cutWord(rest)
+		body = strings.TrimSpace(body)
+		if !reSnippetName.MatchString(name) || body == "" {
+			b.reply(chatID, threadID, "Snippet names use letters, digits, - and _ (up to 40), followed by the text.\n\n"+snipUsage)
+			return
+		}
+		if err := saveSnippet(root, project, name, body); err != nil {
+			log.Printf("Error saving snippet %s: %v", name, err)
+			b.reply(chatID, threadID, "Error: failed to save the snippet.")
+			return
+		}
+		scope := "shared"
+		if project != "" {
+			scope = "project " + project
+		}
+		reply := fmt.Sprintf("Saved snippet %s (%s).", name, scope)
+		if params := snippetParams(body); len(params) > 0 {
+			reply += " Params: " + strings.Join(params, ", ")
+		}
+		b.reply(chatID, threadID, reply)
+
+	case "rm":
+		name := strings.TrimSpace(rest)
+		removed, err := removeSnippet(root, project, name)
+		switch {
+		case err != nil:
+			log.Printf("Error removing snippet %s: %v", name, err)
+			b.reply(chatID, threadID, "Error: failed to delete the snippet.")
+		case !removed:
+			b.reply(chatID, threadID, "No snippet named "+name+".")
+		default:
+			b.reply(chatID, threadID, "Deleted snippet "+name+".")
+		}
+
+	default:
+		name, params, ok := parseSnippetArgs(args)
+		if !ok {
+			b.reply(chatID, threadID, "Couldn't read the params.\n\n"+snipUsage)
+			return
+		}
+		s, found, err := findSnippet(root, project, name)
+		if err != nil {
+			log.Printf("Error loading snippet %s: %v", name, err)
+			b.reply(chatID, threadID, "Error: failed to load the snippet.")
+			return
+		}
+		if !found {
+			b.reply(chatID, threadID, "No snippet named "+name+". Send /c_snip to list them.")
+			return
+		}
+		b.startSnippet(msg, s, params)
+	}
+}
+
+// buildSnippetList renders the snippets visible to a project as buttons.
+func (b *Bot) buildSnippetList(project string) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	snippets, err := loadSnippets(b.config.TramuntanaDir, project)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, s := range snippets {
+		label := s.Name
+		if params := snippetParams(s.Body); len(params) > 0 {
+			label += " (" + strings.Join(params, ", ") + ")"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "snip_run:"+s.Name),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Close", "snip_close"),
+	))
+
+	if len(snippets) == 0 {
+		return "No snippets yet.\n\n" + snipUsage, tgbotapi.NewInlineKeyboardMarkup(rows...), nil
+	}
+	return fmt.Sprintf("Snippets (%d). Tap one to send it.", len(snippets)), tgbotapi.NewInlineKeyboardMarkup(rows...), nil
+}
+
+// processSnippetCallback handles snip_run:<name> and snip_close buttons.
+func (b *Bot) processSnippetCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	messageID := cq.Message.MessageID
+
+	name, ok := strings.CutPrefix(cq.Data, "snip_run:")
+	if !ok {
+		b.editMessageText(chatID, messageID, "Snippets closed.")
+		return
+	}
+
+	project, _ := b.state.GetProject(strconv.Itoa(getThreadID(cq.Message)))
+	s, found, err := findSnippet(b.config.TramuntanaDir, project, name)
+	if err != nil || !found {
+		b.reply(chatID, getThreadID(cq.Message), "Snippet "+name+" is gone.")
+		return
+	}
+	// Act as if the user had typed /c_snip <name>
+	msg := *cq.Message
+	msg.From = cq.From
+	b.startSnippet(&msg, s, nil)
+}
+
+// startSnippet sends a snippet if every param has a value, or asks for the
+// first missing one.
+func (b *Bot) startSnippet(msg *tgbotapi.Message, s snippet, params map[string]string) {
+	if params == nil {
+		params = make(map[string]string)
+	}
+	var missing []string
+	for _, p := range snippetParams(s.Body) {
+		if _, ok := params[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		b.sendSnippet(msg, s, params)
+		return
+	}
+
+	b.mu.Lock()
+	if b.snippetFills == nil {
+		b.snippetFills = make(map[int64]*snippetFill)
+	}
+	b.snippetFills[msg.From.ID] = &snippetFill{Snippet: s, Params: params, Missing: missing}
+	b.mu.Unlock()
+	b.askSnippetParam(msg, s.Name, missing)
+}
+
+// askSnippetParam prompts for the next missing param.
+func (b *Bot) askSnippetParam(msg *tgbotapi.Message, name string, missing []string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	b.reply(chatID, threadID, fmt.Sprintf("%s: value for {{%s}}? (%d left)", name, missing[0], len(missing)))
+	b.setPendingInput(msg.From.ID, "c_snip", chatID, threadID)
+}
+
+// continueSnippet is the pending input dispatch target: it records the
+// answer for the current param and sends the snippet once all are filled.
+func (b *Bot) continueSnippet(msg *tgbotapi.Message, text string) {
+	b.mu.Lock()
+	fill, ok := b.snippetFills[msg.From.ID]
+	if ok {
+		fill.Params[fill.Missing[0]] = strings.TrimSpace(text)
+		fill.Missing = fill.Missing[1:]
+		if len(fill.Missing) == 0 {
+			delete(b.snippetFills, msg.From.ID)
+		}
+	}
+	b.mu.Unlock()
+
+	if !ok {
+		b.reply(msg.Chat.ID, getThreadID(msg), "That snippet expired; send /c_snip again.")
+		return
+	}
+	if len(fill.Missing) > 0 {
+		b.askSnippetParam(msg, fill.Snippet.Name, fill.Missing)
+		return
+	}
+	b.sendSnippet(msg, fill.Snippet, fill.Params)
+}
+
+// cutWord splits s at its first whitespace, space or newline, into the first
+// word and the rest with its leading whitespace dropped. The rest keeps its
+// own line breaks.
+func cutWord(s string) (word, rest string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+}
+
+// sendSnippet expands a snippet and sends it to the topic's session.
+func (b *Bot) sendSnippet(msg *tgbotapi.Message, s snippet, params map[string]string) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+
+	windowID, bound := b.resolveWindow(msg)
+	if !bound {
+		b.reply(chatID, threadID, "Topic not bound to a session.")
+		return
+	}
+
+	if err := b.sendPromptToTmux(windowID, expandSnippet(s.Body, params)); err != nil {
+		if backend.IsDead(err) {
+			b.handleDeadWindow(msg, windowID, "")
+			return
+		}
+		log.Printf("Error sending snippet %s to tmux: %v", s.Name, err)
+		b.reply(chatID, threadID, "Error: failed to send prompt.")
+		return
+	}
+	b.reply(chatID, threadID, "📎 Sent snippet "+s.Name)
+}
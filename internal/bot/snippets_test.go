@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnippetParams(t *testing.T) {
+	body := "Review {{file}} for {{ focus }}. Then check {{file}} again, ignoring {{}} and {x}."
+	if got := snippetParams(body); !reflect.DeepEqual(got, []string{"file", "focus"}) {
+		t.Errorf("snippetParams = %v", got)
+	}
+}
+
+func TestExpandSnippet(t *testing.T) {
+	body := "Review {{file}} for {{ focus }} in {{lang}}."
+	got := expandSnippet(body, map[string]string{"file": "main.go", "focus": "error handling"})
+	if want := "Review main.go for error handling in {{lang}}."; got != want {
+		t.Errorf("expandSnippet = %q, want %q", got, want)
+	}
+}
+
+func TestParseSnippetArgs(t *testing.T) {
+	tests := []struct {
+		args       string
+		wantName   string
+		wantParams map[string]string
+		wantOK     bool
+	}{
+		{"review", "review", map[string]string{}, true},
+		{"review file=main.go", "review", map[string]string{"file": "main.go"}, true},
+		{`review focus="error handling" file=a.go`, "review", map[string]string{"focus": "error handling", "file": "a.go"}, true},
+		{"review empty=", "review", map[string]string{"empty": ""}, true},
+		{"review some text", "", nil, false},
+		{`review focus="unterminated`, "", nil, false},
+		{"review =x", "", nil, false},
+	}
+	for _, tt := range tests {
+		name, params, ok := parseSnippetArgs(tt.args)
+		if name != tt.wantName || ok != tt.wantOK || (ok && !reflect.DeepEqual(params, tt.wantParams)) {
+			t.Errorf("parseSnippetArgs(%q) = %q, %v, %v", tt.args, name, params, ok)
+		}
+	}
+}
+
+func TestCutWord(t *testing.T) {
+	tests := []struct{ in, word, rest string }{
+		{"add review focus on errors", "add", "review focus on errors"},
+		{"review\nLine one\n\nLine two\n", "review", "Line one\n\nLine two\n"},
+		{"  rm\tname", "rm", "name"},
+		{"list", "list", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if word, rest := cutWord(tt.in); word != tt.word || rest != tt.rest {
+			t.Errorf("cutWord(%q) = %q, %q; want %q, %q", tt.in, word, rest, tt.word, tt.rest)
+		}
+	}
+}
+
+func TestSnippetStore(t *testing.T) {
+	root := t.TempDir()
+	if err := saveSnippet(root, "", "review", "Review {{file}}"); err != nil {
+		t.Fatal(err)
+	}
+	saveSnippet(root, "", "notes", "Write release notes")
+	saveSnippet(root, "proj", "review", "Review {{file}} against our style guide")
+	saveSnippet(root, "other", "tests", "Write tests")
+	// Files that aren't snippets are ignored
+	os.WriteFile(filepath.Join(root, snippetsDir, "README.txt"), []byte("x"), 0o644)
+
+	all, err := loadSnippets(root, "proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Name != "notes" || all[1].Name != "review" || all[1].Project != "proj" {
+		t.Fatalf("expected shared notes and project review, got %+v", all)
+	}
+	if shared, _ := loadSnippets(root, ""); len(shared) != 2 || shared[1].Body != "Review {{file}}" {
+		t.Errorf("expected the shared snippets without a project, got %+v", shared)
+	}
+
+	// Removing the project snippet uncovers the shared one
+	if removed, err := removeSnippet(root, "proj", "review"); !removed || err != nil {
+		t.Fatalf("removeSnippet = %v, %v", removed, err)
+	}
+	s, found, _ := findSnippet(root, "proj", "review")
+	if !found || s.Project != "" {
+		t.Errorf("expected the shared review snippet, got %+v, %v", s, found)
+	}
+	if removed, _ := removeSnippet(root, "proj", "missing"); removed {
+		t.Error("removing a missing snippet should report false")
+	}
+
+	if none, err := loadSnippets(t.TempDir(), "proj"); err != nil || len(none) != 0 {
+		t.Errorf("empty store = %v, %v", none, err)
+	}
+}
@@ -8,9 +8,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/otaviocarvalho/tramuntana/internal/backend"
 	"github.com/otaviocarvalho/tramuntana/internal/monitor"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
-	"github.com/otaviocarvalho/tramuntana/internal/tmux"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
 // statusKey is a composite key for per-(user, thread) status tracking.
@@ -22,7 +23,7 @@ type statusKey struct {
 // animFrames are the cycling emoji markers prepended to status messages.
 var animFrames = []string{"☕", "⏳", "✨", "🔮"}
 
-// StatusPoller polls Claude's terminal for status line changes and sends updates.
+// StatusPoller polls each session's backend for status line changes and sends updates.
 type StatusPoller struct {
 	bot          *Bot
 	queue        *queue.Queue
@@ -31,6 +32,11 @@ type StatusPoller struct {
 	lastStatus   map[statusKey]string // last status text per user+thread
 	missCount    map[string]int       // windowID → consecutive miss count
 	animFrame    map[statusKey]int    // animation frame per user+thread
+	paneLines    map[string][]string  // windowID → last scraped output (pane-scraped agents)
+	hooked       map[string]bool      // windowIDs that have sent Stop hook events
+	turnEnded    map[string]time.Time // windowID → last Stop hook
+	working      map[string]bool      // windowID → a turn is running (status seen, not yet ended)
+	submitted    map[string]time.Time // windowID → last message sent by the bot
 	pollInterval time.Duration
 }
 
@@ -38,6 +44,19 @@ type StatusPoller struct {
 // before we consider it truly cleared (prevents flicker from unreliable detection).
 const missThreshold = 3
 
+// hookedMissThreshold replaces missThreshold for windows whose Stop hook
+// clears the status. Only turns that end without a Stop (interrupted with
+// Escape) fall back to it.
+const hookedMissThreshold = 30
+
+// submitGrace counts a window as busy for a moment after a message is sent
+// to it, before its status line shows up.
+const submitGrace = 5 * time.Second
+
+// stopGrace ignores status lines for a moment after a Stop hook, while the
+// pane may still show the final spinner frame.
+const stopGrace = 2 * time.Second
+
 // NewStatusPoller creates a new StatusPoller.
 func NewStatusPoller(bot *Bot, q *queue.Queue, mon *monitor.Monitor) *StatusPoller {
 	return &StatusPoller{
@@ -47,6 +66,11 @@ func NewStatusPoller(bot *Bot, q *queue.Queue, mon *monitor.Monitor) *StatusPoll
 		lastStatus:   make(map[statusKey]string),
 		missCount:    make(map[string]int),
 		animFrame:    make(map[statusKey]int),
+		paneLines:    make(map[string][]string),
+		hooked:       make(map[string]bool),
+		turnEnded:    make(map[string]time.Time),
+		working:      make(map[string]bool),
+		submitted:    make(map[string]time.Time),
 		pollInterval: 1 * time.Second,
 	}
 }
@@ -69,6 +93,8 @@ func (sp *StatusPoller) Run(ctx context.Context) {
 }
 
 func (sp *StatusPoller) poll() {
+	sp.bot.checkBudgets()
+
 	// Get all bound window IDs
 	boundWindows := sp.bot.state.AllBoundWindowIDs()
 
@@ -79,10 +105,10 @@ func (sp *StatusPoller) poll() {
 			continue
 		}
 
-		// Capture pane (plain text, no ANSI)
-		paneText, err := tmux.CapturePane(sp.bot.config.TmuxSessionName, windowID, false)
+		// Ask the backend what the session is doing
+		st, err := sp.bot.backendFor(windowID).Status(windowID)
 		if err != nil {
-			if tmux.IsWindowDead(err) {
+			if backend.IsDead(err) {
 				log.Printf("Status poller: window %s is dead, cleaning up", windowID)
 				// Save chat IDs before cleanup removes them
 				type notifyTarget struct {
@@ -107,6 +133,13 @@ func (sp *StatusPoller) poll() {
 					delete(sp.lastStatus, statusKey{uid, tid})
 					sp.mu.Unlock()
 				}
+				sp.mu.Lock()
+				delete(sp.paneLines, windowID)
+				delete(sp.hooked, windowID)
+				delete(sp.turnEnded, windowID)
+				delete(sp.working, windowID)
+				delete(sp.submitted, windowID)
+				sp.mu.Unlock()
 				cleanupDeadWindow(sp.bot, windowID)
 				for _, t := range targets {
 					sp.bot.reply(t.chatID, t.threadID, "Session died. Send a message to restart.")
@@ -115,14 +148,19 @@ func (sp *StatusPoller) poll() {
 			continue
 		}
 
+		// Agents without a transcript: forward output from the pane
+		if sp.bot.agentFor(windowID).ScrapesPane() {
+			sp.scrapePaneOutput(windowID, users)
+		}
+
 		// Check interactive UI once per pane
-		isInteractive := monitor.IsInteractiveUI(paneText)
+		isInteractive := st.Interactive
 
 		// Extract status line (only if not interactive)
 		var statusText string
 		var hasStatus bool
 		if !isInteractive {
-			statusText, hasStatus = monitor.ExtractStatusLine(paneText)
+			statusText, hasStatus = st.Text, st.Text != ""
 
 			if hasStatus {
 				sp.mu.Lock()
@@ -135,6 +173,9 @@ func (sp *StatusPoller) poll() {
 			}
 		}
 
+		sp.updateWorking(windowID, hasStatus, isInteractive)
+		sp.bot.deliverQueuedInput(windowID)
+
 		// Update for each observing user
 		for _, ut := range users {
 			userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
@@ -171,11 +212,16 @@ func (sp *StatusPoller) poll() {
 			sp.mu.RLock()
 			lastText := sp.lastStatus[key]
 			misses := sp.missCount[windowID]
+			threshold := missThreshold
+			if sp.hooked[windowID] {
+				threshold = hookedMissThreshold
+			}
+			justEnded := time.Since(sp.turnEnded[windowID]) < stopGrace
 			sp.mu.RUnlock()
 
 			if hasStatus {
 				// Deduplicate: skip if same text
-				if statusText == lastText {
+				if statusText == lastText || justEnded {
 					continue
 				}
 
@@ -191,7 +237,7 @@ func (sp *StatusPoller) poll() {
 				sp.mu.Unlock()
 
 				displayText := animFrames[frame] + " " + statusText
-				if sp.queue != nil {
+				if sp.queue != nil && sp.bot.state.VerbosityFor(ut.UserID, ut.ThreadID) != state.VerbositySilent {
 					sp.queue.Enqueue(queue.MessageTask{
 						UserID:      userID,
 						ThreadID:    threadID,
@@ -201,54 +247,139 @@ func (sp *StatusPoller) poll() {
 						WindowID:    windowID,
 					})
 				}
-			} else if lastText != "" && misses >= missThreshold {
+			} else if lastText != "" && misses >= threshold {
 				// Status cleared — only after consecutive misses to avoid flicker
-				sp.mu.Lock()
-				delete(sp.lastStatus, key)
-				delete(sp.animFrame, key)
-				sp.mu.Unlock()
+				endAutoRun(windowID)
+				timingText := sp.turnTiming(windowID)
+				sp.clearStatus(userID, threadID, chatID, windowID, timingText)
+			}
+		}
+	}
+}
 
-				// Check for turn timing
-				var timingText string
-				if sp.monitor != nil {
-					if start, ok := sp.monitor.GetAndClearTurnStart(windowID); ok {
-						elapsed := time.Since(start)
-						timingText = formatDuration(elapsed)
-					}
-				}
+// FinishTurn is called when Claude's Stop hook fires: it posts the turn
+// duration and clears the status message right away, without waiting for the
+// status line to disappear from the pane.
+func (sp *StatusPoller) FinishTurn(windowID string) {
+	sp.mu.Lock()
+	sp.hooked[windowID] = true
+	sp.turnEnded[windowID] = time.Now()
+	sp.missCount[windowID] = 0
+	sp.working[windowID] = false
+	delete(sp.submitted, windowID)
+	sp.mu.Unlock()
 
-				if sp.queue != nil {
-					if timingText != "" {
-						// Send timing as content before clearing status
-						sp.queue.Enqueue(queue.MessageTask{
-							UserID:      userID,
-							ThreadID:    threadID,
-							ChatID:      chatID,
-							Parts:       []string{timingText},
-							ContentType: "content",
-							WindowID:    windowID,
-						})
-					}
-					sp.queue.Enqueue(queue.MessageTask{
-						UserID:      userID,
-						ThreadID:    threadID,
-						ChatID:      chatID,
-						ContentType: "status_clear",
-						WindowID:    windowID,
-					})
-				}
-			}
+	endAutoRun(windowID)
+	timingText := sp.turnTiming(windowID)
+	for _, ut := range sp.bot.state.FindUsersForWindow(windowID) {
+		chatID, ok := sp.bot.state.GetGroupChatID(ut.UserID, ut.ThreadID)
+		if !ok {
+			continue
 		}
+		userID, _ := strconv.ParseInt(ut.UserID, 10, 64)
+		threadID, _ := strconv.Atoi(ut.ThreadID)
+		sp.clearStatus(userID, threadID, chatID, windowID, timingText)
+	}
+	sp.bot.deliverQueuedInput(windowID)
+}
+
+// updateWorking tracks whether a window's turn is running: from the first
+// status line or interactive prompt until Stop, or until the status line has
+// been gone for the miss threshold.
+func (sp *StatusPoller) updateWorking(windowID string, hasStatus, interactive bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	threshold := missThreshold
+	if sp.hooked[windowID] {
+		threshold = hookedMissThreshold
+	}
+	switch {
+	case interactive, hasStatus && time.Since(sp.turnEnded[windowID]) >= stopGrace:
+		sp.working[windowID] = true
+	case !hasStatus && sp.missCount[windowID] >= threshold:
+		sp.working[windowID] = false
+	}
+}
+
+// Busy reports whether Claude is working in a window, or was just sent a
+// message it hasn't started on yet.
+func (sp *StatusPoller) Busy(windowID string) bool {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.working[windowID] || time.Since(sp.submitted[windowID]) < submitGrace
+}
+
+// MarkBusy records that a message was just sent to a window.
+func (sp *StatusPoller) MarkBusy(windowID string) {
+	sp.mu.Lock()
+	sp.submitted[windowID] = time.Now()
+	sp.mu.Unlock()
+}
+
+// KeepAlive resets the miss counter for a window whose turn is still running
+// (e.g. a subagent just finished), so a blank status line doesn't end it.
+func (sp *StatusPoller) KeepAlive(windowID string) {
+	sp.mu.Lock()
+	sp.missCount[windowID] = 0
+	sp.mu.Unlock()
+}
+
+// turnTiming returns the "Turn finished in … · 152k tokens · $0.42" text for
+// a window's current turn, or "" if no turn start was recorded. Consumes the
+// turn start and the turn's token usage.
+func (sp *StatusPoller) turnTiming(windowID string) string {
+	if sp.monitor == nil {
+		return ""
+	}
+	var usage string
+	if sp.bot.usage != nil {
+		usage = turnUsageSummary(sp.bot.config.Prices, sp.bot.usage.TakeTurn(windowID))
+	}
+	start, ok := sp.monitor.GetAndClearTurnStart(windowID)
+	if !ok {
+		return ""
+	}
+	return formatDuration(time.Since(start)) + usage
+}
+
+// clearStatus forgets a user's status, sends the turn timing (if any) and
+// removes the status message.
+func (sp *StatusPoller) clearStatus(userID int64, threadID int, chatID int64, windowID, timingText string) {
+	sp.mu.Lock()
+	delete(sp.lastStatus, statusKey{userID, threadID})
+	delete(sp.animFrame, statusKey{userID, threadID})
+	sp.mu.Unlock()
+
+	if sp.queue == nil {
+		return
+	}
+	if timingText != "" {
+		// Send timing as content before clearing status
+		sp.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{timingText},
+			ContentType: "content",
+			WindowID:    windowID,
+		})
 	}
+	sp.queue.Enqueue(queue.MessageTask{
+		UserID:      userID,
+		ThreadID:    threadID,
+		ChatID:      chatID,
+		ContentType: "status_clear",
+		WindowID:    windowID,
+	})
 }
 
-// formatDuration formats a duration as "Brewed for Xm Ys" or "Brewed for Ys".
+// formatDuration formats a duration as "Turn finished in XmYs" or "Turn finished in Ys".
 func formatDuration(d time.Duration) string {
 	secs := int(d.Seconds())
 	if secs < 60 {
-		return fmt.Sprintf("Brewed for %ds", secs)
+		return fmt.Sprintf("Turn finished in %ds", secs)
 	}
 	mins := secs / 60
 	secs = secs % 60
-	return fmt.Sprintf("Brewed for %dm %ds", mins, secs)
+	return fmt.Sprintf("Turn finished in %dm%02ds", mins, secs)
 }
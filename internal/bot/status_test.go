@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{42 * time.Second, "Turn finished in 42s"},
+		{3*time.Minute + 12*time.Second, "Turn finished in 3m12s"},
+		{10*time.Minute + 5*time.Second, "Turn finished in 10m05s"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFinishTurn_ClearsStatus(t *testing.T) {
+	b := newTestBot(t)
+	b.state.BindThread("100", "42", "@1")
+	b.state.SetGroupChatID("100", "42", -1001)
+	sp := NewStatusPoller(b, nil, nil)
+	sp.lastStatus[statusKey{100, 42}] = "Reading files"
+	sp.missCount["@1"] = 2
+
+	sp.FinishTurn("@1")
+
+	if _, ok := sp.lastStatus[statusKey{100, 42}]; ok {
+		t.Error("status should be cleared by Stop")
+	}
+	if !sp.hooked["@1"] || sp.missCount["@1"] != 0 {
+		t.Errorf("window should be marked hooked with misses reset, got hooked=%v misses=%d", sp.hooked["@1"], sp.missCount["@1"])
+	}
+}
+
+func TestHandleHookRequest_StopReachesPoller(t *testing.T) {
+	b := newTestBot(t)
+	sp := NewStatusPoller(b, nil, nil)
+	b.SetStatusPoller(sp)
+
+	b.HandleHookRequest(hooksock.Request{Event: "Stop", Window: "test-session:@3"})
+
+	if !sp.hooked["@3"] {
+		t.Error("Stop from our tmux session should finish the window's turn")
+	}
+	b.HandleHookRequest(hooksock.Request{Event: "Stop", Window: "other:@4"})
+	if sp.hooked["@4"] {
+		t.Error("Stop from a foreign tmux session should be ignored")
+	}
+}
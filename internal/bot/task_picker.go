@@ -241,6 +241,7 @@ func (b *Bot) executePickTask(chatID int64, threadID int, userID int64, taskID s
 		return
 	}
 
+	b.trackTask(windowID, taskID)
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s...", taskID))
 }
 
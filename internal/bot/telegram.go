@@ -204,6 +204,29 @@ func (b *Bot) sendMessageWithKeyboard(chatID int64, threadID int, text string, k
 	return msg, nil
 }
 
+// sendMessageWithKeyboardMD sends a MarkdownV2 message with inline keyboard in a thread.
+func (b *Bot) sendMessageWithKeyboardMD(chatID int64, threadID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	kbJSON, _ := json.Marshal(keyboard)
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonEmpty("text", text)
+	params.AddNonEmpty("parse_mode", "MarkdownV2")
+	if threadID != 0 {
+		params.AddNonZero("message_thread_id", threadID)
+	}
+	params["reply_markup"] = string(kbJSON)
+
+	resp, err := b.api.MakeRequest("sendMessage", params)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var msg tgbotapi.Message
+	json.Unmarshal(resp.Result, &msg)
+	return msg, nil
+}
+
 // editMessageWithKeyboard edits a message with new text and keyboard.
 func (b *Bot) editMessageWithKeyboard(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
 	kbJSON, _ := json.Marshal(keyboard)
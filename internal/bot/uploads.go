@@ -0,0 +1,359 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxUploadNameLen caps a sanitised upload filename, extension included.
+	maxUploadNameLen = 100
+	// maxInlineUploadSize caps a .md/.txt document sent as the prompt itself.
+	maxInlineUploadSize = 64 * 1024
+	// uploadTimeout bounds downloading one file from Telegram.
+	uploadTimeout = 2 * time.Minute
+	// albumWait is how long an album waits for more items after the last one
+	// was saved. Telegram sends each item of an album as its own message.
+	albumWait = 2 * time.Second
+)
+
+// errUploadTooLarge is returned when a download outgrows the configured limit.
+var errUploadTooLarge = errors.New("file too large")
+
+// upload is a photo or document attached to a message.
+type upload struct {
+	fileID string
+	name   string
+	size   int
+}
+
+// pendingAlbum collects the files of an album until it's complete.
+type pendingAlbum struct {
+	msg      *tgbotapi.Message // the first item, to submit the prompt as
+	windowID string
+	caption  string
+	paths    []string // by arrival, "" for items that failed or are still saving
+	saving   int      // items being downloaded
+	timer    *time.Timer
+}
+
+// messageUpload returns the photo or document of a message, if any. Photos
+// are taken at their largest size.
+func messageUpload(msg *tgbotapi.Message) (upload, bool) {
+	switch {
+	case len(msg.Photo) > 0:
+		p := msg.Photo[len(msg.Photo)-1]
+		return upload{
+			fileID: p.FileID,
+			name:   fmt.Sprintf("photo-%s.jpg", time.Unix(int64(msg.Date), 0).Format("20060102-150405")),
+			size:   p.FileSize,
+		}, true
+	case msg.Document != nil:
+		return upload{fileID: msg.Document.FileID, name: msg.Document.FileName, size: msg.Document.FileSize}, true
+	}
+	return upload{}, false
+}
+
+// handleUpload saves a photo or document sent to a bound topic under the
+// session's uploads directory and sends Claude a prompt pointing at it, with
+// the caption as the instruction. With UPLOAD_INLINE_TEXT, .md and .txt
+// documents are sent as the prompt text instead. The items of an album are
+// always saved, and sent as one prompt listing them all.
+func (b *Bot) handleUpload(msg *tgbotapi.Message, up upload) {
+	userID := strconv.FormatInt(msg.From.ID, 10)
+	threadID := getThreadID(msg)
+	chatID := msg.Chat.ID
+
+	b.state.SetGroupChatID(userID, strconv.Itoa(threadID), chatID)
+	b.saveState()
+
+	windowID, bound := b.state.GetWindowForThread(userID, strconv.Itoa(threadID))
+	if !bound {
+		b.reply(chatID, threadID, "This topic isn't bound to a session. Send a text message to start one, then resend the file.")
+		return
+	}
+	ws, ok := b.state.GetWindowState(windowID)
+	if !ok || ws.CWD == "" {
+		b.reply(chatID, threadID, "Error: the session's working directory is unknown.")
+		return
+	}
+
+	limit := b.config.UploadMaxSize
+	if int64(up.size) > limit {
+		b.reply(chatID, threadID, fmt.Sprintf("File too large: %d MB, the limit is %d MB.", up.size>>20, limit>>20))
+		return
+	}
+
+	// Downloads can take a while; don't hold up other updates
+	if album := msg.MediaGroupID; album != "" {
+		slot := b.startAlbumItem(album, msg, windowID)
+		go func() {
+			shown, _ := b.saveMessageUpload(chatID, threadID, up, sanitizeUploadName(up.name), ws.CWD, nil)
+			b.finishAlbumItem(album, slot, shown, strings.TrimSpace(msg.Caption))
+		}()
+		return
+	}
+	go b.receiveUpload(msg, windowID, ws.CWD, up)
+}
+
+// receiveUpload downloads an upload and sends Claude its prompt: the text of
+// an inlined document, or the path it was saved to.
+func (b *Bot) receiveUpload(msg *tgbotapi.Message, windowID, cwd string, up upload) {
+	name := sanitizeUploadName(up.name)
+	caption := strings.TrimSpace(msg.Caption)
+	var data []byte
+	if b.config.UploadInlineText && inlinesAsText(name) && up.size <= maxInlineUploadSize {
+		var err error
+		data, err = b.downloadUpload(up.fileID, maxInlineUploadSize)
+		if err == nil && utf8.Valid(data) {
+			b.submitText(msg, windowID, inlineUploadPrompt(string(data), caption))
+			return
+		}
+		// Binary or oversized after all: save it like any other file
+		if err != nil {
+			data = nil
+		}
+	}
+	if shown, ok := b.saveMessageUpload(msg.Chat.ID, getThreadID(msg), up, name, cwd, data); ok {
+		b.submitText(msg, windowID, uploadPrompt(shown, caption))
+	}
+}
+
+// saveMessageUpload downloads an upload, unless its data is already at hand,
+// and saves it under the uploads directory. It returns the path to show
+// Claude, relative to cwd where possible; failures are reported in the topic.
+func (b *Bot) saveMessageUpload(chatID int64, threadID int, up upload, name, cwd string, data []byte) (string, bool) {
+	limit := b.config.UploadMaxSize
+	if data == nil {
+		var err error
+		data, err = b.downloadUpload(up.fileID, limit)
+		if err != nil {
+			log.Printf("Error downloading %s: %v", name, err)
+			if errors.Is(err, errUploadTooLarge) {
+				b.reply(chatID, threadID, fmt.Sprintf("File too large, the limit is %d MB.", limit>>20))
+			} else {
+				b.reply(chatID, threadID, "Error: failed to download the file.")
+			}
+			return "", false
+		}
+	}
+
+	dir := b.config.UploadsDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	path, err := saveUpload(dir, name, data)
+	if err != nil {
+		log.Printf("Error saving upload to %s: %v", dir, err)
+		b.reply(chatID, threadID, "Error: failed to save the file.")
+		return "", false
+	}
+
+	// Claude runs in the CWD, so a relative path reads better in the prompt
+	if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel, true
+	}
+	return path, true
+}
+
+// startAlbumItem registers an album item that is being saved, holding the
+// album's prompt back until it's done. It returns the item's slot, so the
+// files are listed in the album's order whichever download finishes first.
+func (b *Bot) startAlbumItem(album string, msg *tgbotapi.Message, windowID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.albums == nil {
+		b.albums = make(map[string]*pendingAlbum)
+	}
+	a, ok := b.albums[album]
+	if !ok {
+		a = &pendingAlbum{msg: msg, windowID: windowID}
+		b.albums[album] = a
+	}
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.saving++
+	a.paths = append(a.paths, "")
+	return len(a.paths) - 1
+}
+
+// finishAlbumItem records the path of a saved album item, "" if it failed,
+// and sends the album's prompt once no more items arrive for albumWait.
+func (b *Bot) finishAlbumItem(album string, slot int, path, caption string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	a, ok := b.albums[album]
+	if !ok {
+		return
+	}
+	a.saving--
+	a.paths[slot] = path
+	// Telegram puts an album's caption on one of its items
+	if a.caption == "" {
+		a.caption = caption
+	}
+	if a.saving == 0 {
+		a.timer = time.AfterFunc(albumWait, func() { b.flushAlbum(album, a) })
+	}
+}
+
+// flushAlbum sends the prompt for a complete album.
+func (b *Bot) flushAlbum(album string, a *pendingAlbum) {
+	b.mu.Lock()
+	if b.albums[album] != a || a.saving > 0 {
+		// Another item arrived meanwhile; it reschedules the flush
+		b.mu.Unlock()
+		return
+	}
+	delete(b.albums, album)
+	b.mu.Unlock()
+
+	paths := slices.DeleteFunc(a.paths, func(p string) bool { return p == "" })
+	if len(paths) == 0 {
+		return
+	}
+	b.submitText(a.msg, a.windowID, albumPrompt(paths, a.caption))
+}
+
+// downloadUpload fetches a file from Telegram, failing past limit bytes.
+func (b *Bot) downloadUpload(fileID string, limit int64) ([]byte, error) {
+	fileURL, err := b.api.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("getting file: %w", err)
+	}
+	client := &http.Client{Timeout: uploadTimeout}
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		// The URL holds the bot token; keep it out of logs
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("downloading file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading file: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading file: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, errUploadTooLarge
+	}
+	return data, nil
+}
+
+// saveUpload writes data to dir under name, adding a -N suffix instead of
+// overwriting an existing file. Returns the file's path.
+func saveUpload(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; i < 100; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		path := filepath.Join(dir, candidate)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("too many files named %s", name)
+}
+
+// sanitizeUploadName reduces a Telegram filename to a safe base name: no
+// directories, only letters, digits, '.', '-' and '_', no leading dot.
+func sanitizeUploadName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	name = strings.TrimLeft(sb.String(), "._")
+	if name == "" {
+		name = "file"
+	}
+	if len(name) > maxUploadNameLen {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = name[:maxUploadNameLen-len(ext)] + ext
+	}
+	return name
+}
+
+// inlinesAsText reports whether a document can be sent as the prompt text.
+func inlinesAsText(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".txt":
+		return true
+	}
+	return false
+}
+
+// uploadPrompt tells Claude about a saved upload, with the caption as the instruction.
+func uploadPrompt(path, caption string) string {
+	if caption == "" {
+		return "I've uploaded " + path + ". Take a look at it."
+	}
+	return caption + "\n\n(Attached file: " + path + ")"
+}
+
+// albumPrompt tells Claude about the saved files of an album.
+func albumPrompt(paths []string, caption string) string {
+	if len(paths) == 1 {
+		return uploadPrompt(paths[0], caption)
+	}
+	list := strings.Join(paths, ", ")
+	if caption == "" {
+		return "I've uploaded " + list + ". Take a look at them."
+	}
+	return caption + "\n\n(Attached files: " + list + ")"
+}
+
+// inlineUploadPrompt is the prompt for a text document sent inline.
+func inlineUploadPrompt(text, caption string) string {
+	text = strings.TrimSpace(text)
+	if caption == "" {
+		return text
+	}
+	return caption + "\n\n" + text
+}
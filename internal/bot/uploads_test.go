@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSanitizeUploadName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\notes.txt`, "notes.txt"},
+		{".bashrc", "bashrc"},
+		{"my file (1).png", "my_file__1_.png"},
+		{"résumé.docx", "r_sum_.docx"},
+		{"", "file"},
+		{"..", "file"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeUploadName(tt.in); got != tt.want {
+			t.Errorf("sanitizeUploadName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := sanitizeUploadName(strings.Repeat("a", 300) + ".log")
+	if len(long) != maxUploadNameLen || !strings.HasSuffix(long, ".log") {
+		t.Errorf("long name = %q (%d bytes)", long, len(long))
+	}
+}
+
+func TestSaveUpload_DoesNotOverwrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "uploads")
+
+	first, err := saveUpload(dir, "log.txt", []byte("one"))
+	if err != nil {
+		t.Fatalf("saveUpload: %v", err)
+	}
+	second, err := saveUpload(dir, "log.txt", []byte("two"))
+	if err != nil {
+		t.Fatalf("saveUpload: %v", err)
+	}
+	if filepath.Base(first) != "log.txt" || filepath.Base(second) != "log-1.txt" {
+		t.Errorf("paths = %s, %s", first, second)
+	}
+	if data, _ := os.ReadFile(first); string(data) != "one" {
+		t.Errorf("first file = %q, want one", data)
+	}
+}
+
+func TestMessageUpload(t *testing.T) {
+	if _, ok := messageUpload(&tgbotapi.Message{Text: "hi"}); ok {
+		t.Error("text message should have no upload")
+	}
+
+	photo := &tgbotapi.Message{Date: 0, Photo: []tgbotapi.PhotoSize{
+		{FileID: "small", FileSize: 100},
+		{FileID: "large", FileSize: 5000},
+	}}
+	up, ok := messageUpload(photo)
+	if !ok || up.fileID != "large" || up.size != 5000 || !strings.HasSuffix(up.name, ".jpg") {
+		t.Errorf("photo upload = %+v", up)
+	}
+
+	doc := &tgbotapi.Message{Document: &tgbotapi.Document{FileID: "d1", FileName: "app.log", FileSize: 42}}
+	if up, ok := messageUpload(doc); !ok || up.name != "app.log" || up.size != 42 {
+		t.Errorf("document upload = %+v", up)
+	}
+}
+
+func TestUploadPrompt(t *testing.T) {
+	if got := uploadPrompt("uploads/bug.jpg", "why is this button misaligned?"); got != "why is this button misaligned?\n\n(Attached file: uploads/bug.jpg)" {
+		t.Errorf("with caption: %q", got)
+	}
+	if got := uploadPrompt("uploads/bug.jpg", ""); !strings.Contains(got, "uploads/bug.jpg") {
+		t.Errorf("without caption: %q", got)
+	}
+	if got := inlineUploadPrompt("# Spec\n\nDo it.\n", "implement this"); got != "implement this\n\n# Spec\n\nDo it." {
+		t.Errorf("inline: %q", got)
+	}
+	if !inlinesAsText("NOTES.MD") || inlinesAsText("data.csv") {
+		t.Error("inlinesAsText should match .md and .txt only")
+	}
+}
+
+func TestAlbumPrompt(t *testing.T) {
+	paths := []string{"uploads/a.jpg", "uploads/b.jpg"}
+	if got := albumPrompt(paths, "compare these"); got != "compare these\n\n(Attached files: uploads/a.jpg, uploads/b.jpg)" {
+		t.Errorf("with caption: %q", got)
+	}
+	if got := albumPrompt(paths, ""); !strings.Contains(got, "uploads/a.jpg, uploads/b.jpg") {
+		t.Errorf("without caption: %q", got)
+	}
+	if got := albumPrompt(paths[:1], "look"); got != uploadPrompt("uploads/a.jpg", "look") {
+		t.Errorf("single file: %q", got)
+	}
+}
+
+func TestAlbumItems_Grouped(t *testing.T) {
+	b := newTestBot(t)
+	first := &tgbotapi.Message{MessageID: 1}
+	s1 := b.startAlbumItem("g1", first, "@1")
+	s2 := b.startAlbumItem("g1", &tgbotapi.Message{MessageID: 2}, "@1")
+
+	// Downloads run concurrently; the second item may finish first
+	b.finishAlbumItem("g1", s2, "uploads/b.jpg", "compare these")
+	a := b.albums["g1"]
+	if a.timer != nil {
+		t.Error("the album shouldn't be sent while an item is still saving")
+	}
+	b.finishAlbumItem("g1", s1, "uploads/a.jpg", "")
+	if a.timer == nil {
+		t.Fatal("the album should be sent once every item is saved")
+	}
+	a.timer.Stop()
+
+	if a.msg != first || a.caption != "compare these" || strings.Join(a.paths, ",") != "uploads/a.jpg,uploads/b.jpg" {
+		t.Errorf("album = %+v", a)
+	}
+
+	// A failed item adds no path; an album with none sends nothing
+	slot := b.startAlbumItem("g2", first, "@1")
+	b.finishAlbumItem("g2", slot, "", "")
+	g2 := b.albums["g2"]
+	g2.timer.Stop()
+	b.flushAlbum("g2", g2)
+	if _, ok := b.albums["g2"]; ok {
+		t.Error("a flushed album should be dropped")
+	}
+}
@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+// usageWeekDays is how many days (today included) the weekly breakdown covers.
+const usageWeekDays = 7
+
+// SetUsageState sets the token usage totals (called by serve command).
+func (b *Bot) SetUsageState(us *state.UsageState) {
+	b.usage = us
+}
+
+// trackTask attributes the window's upcoming token usage to a Minuano task ("" for none).
+func (b *Bot) trackTask(windowID, taskID string) {
+	if b.usage != nil {
+		b.usage.SetWindowTask(windowID, taskID)
+	}
+}
+
+// handleUsageCommand shows token usage and cost for the topic, its project and its tasks.
+func (b *Bot) handleUsageCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	if b.usage == nil {
+		b.reply(chatID, threadID, "Usage accounting is not enabled.")
+		return
+	}
+	threadIDStr := strconv.Itoa(threadID)
+	project, _ := b.state.GetProject(threadIDStr)
+	b.reply(chatID, threadID, buildUsageReport(b.usage, b.config.Prices, threadIDStr, project, time.Now()))
+}
+
+// buildUsageReport renders the /c_usage text as of now.
+func buildUsageReport(us *state.UsageState, prices config.PriceTable, threadID, project string, now time.Time) string {
+	today := now.Format("2006-01-02")
+	weekStart := now.AddDate(0, 0, -(usageWeekDays - 1))
+
+	inTopic := func(r state.UsageRecord) bool { return r.Thread == threadID }
+	topicWeek := us.Matching(weekStart, inTopic)
+
+	var sb strings.Builder
+	sb.WriteString("📊 Token usage — this topic\n")
+	fmt.Fprintf(&sb, "Today: %s\n", usageLine(prices, onDay(topicWeek, today)))
+	fmt.Fprintf(&sb, "Last %d days: %s\n", usageWeekDays, usageLine(prices, topicWeek))
+	for i := usageWeekDays - 1; i >= 0; i-- {
+		day := now.AddDate(0, 0, -i).Format("2006-01-02")
+		if recs := onDay(topicWeek, day); len(recs) > 0 {
+			fmt.Fprintf(&sb, "  %s: %s\n", day, usageLine(prices, recs))
+		}
+	}
+	fmt.Fprintf(&sb, "All time: %s\n", usageLine(prices, us.Matching(time.Time{}, inTopic)))
+
+	if project != "" {
+		projWeek := us.Matching(weekStart, func(r state.UsageRecord) bool { return r.Project == project })
+		fmt.Fprintf(&sb, "\nProject %s\n", project)
+		fmt.Fprintf(&sb, "Today: %s\n", usageLine(prices, onDay(projWeek, today)))
+		fmt.Fprintf(&sb, "Last %d days: %s\n", usageWeekDays, usageLine(prices, projWeek))
+
+		byTask := make(map[string][]state.UsageRecord)
+		for _, r := range projWeek {
+			if r.Task != "" {
+				byTask[r.Task] = append(byTask[r.Task], r)
+			}
+		}
+		if len(byTask) > 0 {
+			tasks := make([]string, 0, len(byTask))
+			for id := range byTask {
+				tasks = append(tasks, id)
+			}
+			cost := func(id string) float64 { c, _ := usageCost(prices, state.SumByModel(byTask[id])); return c }
+			sort.Slice(tasks, func(i, j int) bool { return cost(tasks[i]) > cost(tasks[j]) })
+			fmt.Fprintf(&sb, "\nTasks (last %d days)\n", usageWeekDays)
+			for _, id := range tasks {
+				fmt.Fprintf(&sb, "  %s: %s\n", id, usageLine(prices, byTask[id]))
+			}
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// onDay filters records to a single day.
+func onDay(records []state.UsageRecord, day string) []state.UsageRecord {
+	var out []state.UsageRecord
+	for _, r := range records {
+		if r.Day == day {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// usageLine summarizes records as "152k tokens (in 1.2k · out 8k · cache 143k) · $0.42".
+func usageLine(prices config.PriceTable, records []state.UsageRecord) string {
+	byModel := state.SumByModel(records)
+	var total state.TokenUsage
+	for _, u := range byModel {
+		total.Add(u)
+	}
+	if total.Total() == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%s tokens (in %s · out %s · cache %s) · %s",
+		formatTokens(total.Total()), formatTokens(total.Input), formatTokens(total.Output),
+		formatTokens(total.CacheWrite+total.CacheRead), formatCost(usageCost(prices, byModel)))
+}
+
+// turnUsageSummary returns " · 152k tokens · $0.42" for a finished turn, or "" if it used none.
+func turnUsageSummary(prices config.PriceTable, byModel map[string]state.TokenUsage) string {
+	var total int64
+	for _, u := range byModel {
+		total += u.Total()
+	}
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" · %s tokens · %s", formatTokens(total), formatCost(usageCost(prices, byModel)))
+}
+
+// usageCost prices usage by model. complete is false if some model had no price.
+func usageCost(prices config.PriceTable, byModel map[string]state.TokenUsage) (cost float64, complete bool) {
+	complete = true
+	for model, u := range byModel {
+		p, ok := prices.Lookup(model)
+		if !ok {
+			if u.Total() > 0 {
+				complete = false
+			}
+			continue
+		}
+		cost += (float64(u.Input)*p.Input + float64(u.Output)*p.Output +
+			float64(u.CacheWrite)*p.CacheWrite + float64(u.CacheRead)*p.CacheRead) / 1e6
+	}
+	return cost, complete
+}
+
+// formatCost renders a dollar amount, marking it as a lower bound when some usage was unpriced.
+func formatCost(cost float64, complete bool) string {
+	s := fmt.Sprintf("$%.2f", cost)
+	if !complete {
+		s = "≥" + s
+	}
+	return s
+}
+
+// formatTokens renders a token count compactly: 950, 12.3k, 1.5M.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e6), ".0") + "M"
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e3), ".0") + "k"
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestFormatTokens(t *testing.T) {
+	tests := map[int64]string{0: "0", 950: "950", 1200: "1.2k", 1000: "1k", 152_345: "152k", 1_500_000: "1.5M", 2_000_000: "2M"}
+	for n, want := range tests {
+		if got := formatTokens(n); got != want {
+			t.Errorf("formatTokens(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	byModel := map[string]state.TokenUsage{
+		"claude-sonnet-4-5": {Input: 1_000_000, Output: 100_000, CacheRead: 1_000_000},
+	}
+	cost, complete := usageCost(config.DefaultPrices, byModel)
+	if !complete || cost < 4.79 || cost > 4.81 {
+		t.Errorf("cost = %v (complete %v), want 4.80", cost, complete)
+	}
+
+	byModel["mystery"] = state.TokenUsage{Output: 10}
+	if _, complete := usageCost(config.DefaultPrices, byModel); complete {
+		t.Error("unpriced models should make the cost a lower bound")
+	}
+	if got := turnUsageSummary(config.DefaultPrices, byModel); got != " · 2.1M tokens · ≥$4.80" {
+		t.Errorf("turn summary = %q", got)
+	}
+	if turnUsageSummary(config.DefaultPrices, nil) != "" {
+		t.Error("a turn without usage has no summary")
+	}
+}
+
+func TestBuildUsageReport(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	us := state.NewUsageState()
+	add := func(at time.Time, thread, task string, out int64) {
+		us.Add("@1", at, state.UsageRecord{Thread: thread, Project: "web", Task: task, Model: "claude-haiku-4-5", TokenUsage: state.TokenUsage{Output: out}})
+	}
+	add(now, "5", "T-1", 200_000)
+	add(now.AddDate(0, 0, -2), "5", "T-2", 1000)
+	add(now.AddDate(0, 0, -30), "5", "", 50)
+	add(now, "9", "T-3", 400_000)
+
+	report := buildUsageReport(us, config.DefaultPrices, "5", "web", now)
+	for _, want := range []string{
+		"Today: 200k tokens",
+		"Last 7 days: 201k tokens",
+		"2026-03-08: 1k tokens",
+		"All time: 201k tokens",
+		"Project web",
+		"Last 7 days: 601k tokens",
+		"T-3: 400k tokens (in 0 · out 400k · cache 0) · $2.00",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Index(report, "T-3:") > strings.Index(report, "T-1:") {
+		t.Errorf("tasks should be sorted by cost:\n%s", report)
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+const verbosityUsage = "Usage: /c_verbosity [me] <full|normal|quiet|silent>\n" +
+	"     /c_verbosity me default — drop your override\n\n" +
+	"full — everything, thinking included\n" +
+	"normal — text and tool summaries, failed tool results\n" +
+	"quiet — Claude's replies and errors\n" +
+	"silent — turn-complete notices only"
+
+// handleVerbosityCommand shows or sets the topic's verbosity, or the user's override.
+func (b *Bot) handleVerbosityCommand(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	threadID := getThreadID(msg)
+	userIDStr := strconv.FormatInt(msg.From.ID, 10)
+	threadIDStr := strconv.Itoa(threadID)
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) == 0 {
+		text, kb := b.buildVerbosityMenu(userIDStr, threadIDStr)
+		if _, err := b.sendMessageWithKeyboard(chatID, threadID, text, kb); err != nil {
+			log.Printf("Error sending verbosity menu: %v", err)
+		}
+		return
+	}
+
+	personal := args[0] == "me"
+	if personal {
+		args = args[1:]
+	}
+	if len(args) != 1 || !(state.IsVerbosityLevel(args[0]) || personal && args[0] == "default") {
+		b.reply(chatID, threadID, verbosityUsage)
+		return
+	}
+	b.reply(chatID, threadID, b.setVerbosity(userIDStr, threadIDStr, personal, args[0]))
+}
+
+// setVerbosity applies a level ("default" clears a personal override) and describes the result.
+func (b *Bot) setVerbosity(userID, threadID string, personal bool, level string) string {
+	if !personal {
+		b.state.SetTopicVerbosity(threadID, level)
+		b.saveState()
+		return "Topic verbosity: " + level
+	}
+	if level == "default" {
+		b.state.SetUserVerbosity(userID, threadID, "")
+		b.saveState()
+		return "Using the topic's verbosity: " + b.state.TopicVerbosityLevel(threadID)
+	}
+	b.state.SetUserVerbosity(userID, threadID, level)
+	b.saveState()
+	return "Your verbosity in this topic: " + level
+}
+
+// buildVerbosityMenu renders the topic's level and the user's override with a
+// button row for each.
+func (b *Bot) buildVerbosityMenu(userID, threadID string) (string, tgbotapi.InlineKeyboardMarkup) {
+	topic := b.state.TopicVerbosityLevel(threadID)
+	own, hasOwn := b.state.UserVerbosityOverride(userID, threadID)
+
+	text := "Verbosity for this topic: " + topic
+	if hasOwn {
+		text += fmt.Sprintf("\nYour override: %s", own)
+	}
+	text += "\n\n" + verbosityUsage
+
+	topicRow := make([]tgbotapi.InlineKeyboardButton, 0, len(state.VerbosityLevels))
+	ownRow := make([]tgbotapi.InlineKeyboardButton, 0, len(state.VerbosityLevels)+1)
+	for _, level := range state.VerbosityLevels {
+		label := level
+		if level == topic {
+			label = "• " + level
+		}
+		topicRow = append(topicRow, tgbotapi.NewInlineKeyboardButtonData(label, "verb_t:"+level))
+
+		label = level
+		if hasOwn && level == own {
+			label = "• " + level
+		}
+		ownRow = append(ownRow, tgbotapi.NewInlineKeyboardButtonData(label, "verb_u:"+level))
+	}
+	defaultLabel := "topic"
+	if !hasOwn {
+		defaultLabel = "• topic"
+	}
+	ownRow = append(ownRow, tgbotapi.NewInlineKeyboardButtonData(defaultLabel, "verb_u:default"))
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("── Topic ──", "noop")),
+		topicRow,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("── Just me ──", "noop")),
+		ownRow,
+	)
+}
+
+// processVerbosityCallback handles verb_t:<level> and verb_u:<level> buttons.
+func (b *Bot) processVerbosityCallback(cq *tgbotapi.CallbackQuery) {
+	scope, level, ok := strings.Cut(strings.TrimPrefix(cq.Data, "verb_"), ":")
+	if !ok || !(state.IsVerbosityLevel(level) || scope == "u" && level == "default") {
+		return
+	}
+	userIDStr := strconv.FormatInt(cq.From.ID, 10)
+	threadIDStr := strconv.Itoa(getThreadID(cq.Message))
+
+	b.answerCallback(cq.ID, b.setVerbosity(userIDStr, threadIDStr, scope == "u", level))
+	text, kb := b.buildVerbosityMenu(userIDStr, threadIDStr)
+	if err := b.editMessageWithKeyboard(cq.Message.Chat.ID, cq.Message.MessageID, text, kb); err != nil {
+		log.Printf("Error refreshing verbosity menu: %v", err)
+	}
+}
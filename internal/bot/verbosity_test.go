@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestBuildVerbosityMenu(t *testing.T) {
+	b := newTestBot(t)
+	b.state.SetTopicVerbosity("5", state.VerbosityQuiet)
+
+	text, kb := b.buildVerbosityMenu("100", "5")
+	if !strings.Contains(text, "Verbosity for this topic: quiet") || strings.Contains(text, "Your override") {
+		t.Errorf("text = %q", text)
+	}
+	want := "noop,verb_t:full,verb_t:normal,verb_t:quiet,verb_t:silent,noop,verb_u:full,verb_u:normal,verb_u:quiet,verb_u:silent,verb_u:default"
+	if got := strings.Join(callbackData(kb.InlineKeyboard), ","); got != want {
+		t.Errorf("callbacks = %s", got)
+	}
+	if kb.InlineKeyboard[1][2].Text != "• quiet" || kb.InlineKeyboard[3][4].Text != "• topic" {
+		t.Errorf("current levels not marked: %q, %q", kb.InlineKeyboard[1][2].Text, kb.InlineKeyboard[3][4].Text)
+	}
+
+	b.state.SetUserVerbosity("100", "5", state.VerbosityFull)
+	text, kb = b.buildVerbosityMenu("100", "5")
+	if !strings.Contains(text, "Your override: full") || kb.InlineKeyboard[3][0].Text != "• full" {
+		t.Errorf("override not shown: %q / %q", text, kb.InlineKeyboard[3][0].Text)
+	}
+}
@@ -0,0 +1,220 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// transcribeTimeout bounds one run of the STT command.
+const transcribeTimeout = 3 * time.Minute
+
+// maxVoiceDrafts caps the transcripts awaiting Send; older ones expire.
+const maxVoiceDrafts = 100
+
+// voiceMessage returns the voice note or audio file of a message, if any.
+func voiceMessage(msg *tgbotapi.Message) (upload, bool) {
+	switch {
+	case msg.Voice != nil:
+		return upload{fileID: msg.Voice.FileID, name: "voice.ogg", size: msg.Voice.FileSize}, true
+	case msg.Audio != nil:
+		name := msg.Audio.FileName
+		if name == "" {
+			name = "audio" + audioExt(msg.Audio.MimeType)
+		}
+		return upload{fileID: msg.Audio.FileID, name: name, size: msg.Audio.FileSize}, true
+	}
+	return upload{}, false
+}
+
+// audioExt guesses a file extension from an audio MIME type.
+func audioExt(mimeType string) string {
+	switch mimeType {
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4", "audio/x-m4a":
+		return ".m4a"
+	case "audio/wav", "audio/x-wav":
+		return ".wav"
+	}
+	return ".ogg"
+}
+
+// handleVoice transcribes a voice note with STT_COMMAND and echoes the text
+// with Send/Edit/Cancel buttons; nothing reaches the session until Send.
+func (b *Bot) handleVoice(msg *tgbotapi.Message, up upload) {
+	userID := strconv.FormatInt(msg.From.ID, 10)
+	threadID := getThreadID(msg)
+	chatID := msg.Chat.ID
+
+	b.state.SetGroupChatID(userID, strconv.Itoa(threadID), chatID)
+	b.saveState()
+
+	if b.config.STTCommand == "" {
+		b.reply(chatID, threadID, "Voice notes need a transcriber: set STT_COMMAND.")
+		return
+	}
+	if _, bound := b.state.GetWindowForThread(userID, strconv.Itoa(threadID)); !bound {
+		b.reply(chatID, threadID, "This topic isn't bound to a session. Send a text message to start one, then resend the voice note.")
+		return
+	}
+	if int64(up.size) > b.config.UploadMaxSize {
+		b.reply(chatID, threadID, fmt.Sprintf("Voice note too large, the limit is %d MB.", b.config.UploadMaxSize>>20))
+		return
+	}
+
+	// Transcription can take a while; don't hold up other updates
+	go b.transcribeVoice(msg, up)
+}
+
+// transcribeVoice downloads and transcribes a voice note, then posts the
+// transcript with its Send/Edit/Cancel keyboard.
+func (b *Bot) transcribeVoice(msg *tgbotapi.Message, up upload) {
+	threadID := getThreadID(msg)
+	chatID := msg.Chat.ID
+
+	data, err := b.downloadUpload(up.fileID, b.config.UploadMaxSize)
+	if err != nil {
+		log.Printf("Error downloading voice note: %v", err)
+		b.reply(chatID, threadID, "Error: failed to download the voice note.")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "tramuntana-voice-")
+	if err != nil {
+		log.Printf("Error creating temp dir: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, sanitizeUploadName(up.name))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		log.Printf("Error writing voice note: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcribeTimeout)
+	defer cancel()
+	text, err := transcribe(ctx, b.config.STTCommand, path)
+	if err != nil {
+		log.Printf("Error transcribing voice note: %v", err)
+		b.reply(chatID, threadID, fmt.Sprintf("Error: transcription failed: %v", err))
+		return
+	}
+	if text == "" {
+		b.reply(chatID, threadID, "Couldn't make out any words in the voice note.")
+		return
+	}
+
+	b.postVoiceDraft(chatID, threadID, text)
+}
+
+// postVoiceDraft posts a transcript with its Send/Edit/Cancel keyboard.
+func (b *Bot) postVoiceDraft(chatID int64, threadID int, text string) {
+	id := b.addVoiceDraft(text)
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Send", "voice_send:"+id),
+		tgbotapi.NewInlineKeyboardButtonData("Edit", "voice_edit:"+id),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", "voice_cancel:"+id),
+	))
+	if _, err := b.sendMessageWithKeyboard(chatID, threadID, "🎙 "+text, kb); err != nil {
+		log.Printf("Error sending transcript: %v", err)
+	}
+}
+
+// transcribe runs the STT command on an audio file and returns its trimmed
+// stdout. The command goes through sh -c, with {file} replaced by the quoted
+// path, or the path appended when there's no placeholder.
+func transcribe(ctx context.Context, command, path string) (string, error) {
+	quoted := shellQuote(path)
+	if strings.Contains(command, "{file}") {
+		command = strings.ReplaceAll(command, "{file}", quoted)
+	} else {
+		command += " " + quoted
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			lines := strings.Split(msg, "\n")
+			return "", fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// addVoiceDraft stores a transcript and returns its id for callback data,
+// keeping the newest maxVoiceDrafts.
+func (b *Bot) addVoiceDraft(text string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.voiceDrafts == nil {
+		b.voiceDrafts = make(map[string]string)
+	}
+	b.nextVoiceDraft++
+	id := strconv.FormatUint(b.nextVoiceDraft, 36)
+	b.voiceDrafts[id] = text
+	b.voiceDraftOrder = append(b.voiceDraftOrder, id)
+	if len(b.voiceDraftOrder) > maxVoiceDrafts {
+		delete(b.voiceDrafts, b.voiceDraftOrder[0])
+		b.voiceDraftOrder = b.voiceDraftOrder[1:]
+	}
+	return id
+}
+
+// takeVoiceDraft removes and returns a transcript, so each is acted on once.
+func (b *Bot) takeVoiceDraft(id string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text, ok := b.voiceDrafts[id]
+	delete(b.voiceDrafts, id)
+	return text, ok
+}
+
+// processVoiceCallback handles the Send/Edit/Cancel buttons of a transcript.
+func (b *Bot) processVoiceCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	msgID := cq.Message.MessageID
+	action, id, _ := strings.Cut(strings.TrimPrefix(cq.Data, "voice_"), ":")
+
+	text, ok := b.takeVoiceDraft(id)
+	if !ok {
+		b.editMessageText(chatID, msgID, cq.Message.Text+"\n\n(expired)")
+		return
+	}
+
+	switch action {
+	case "send":
+		b.editMessageText(chatID, msgID, "🎙 "+text)
+		userID := strconv.FormatInt(cq.From.ID, 10)
+		threadID := strconv.Itoa(getThreadID(cq.Message))
+		windowID, bound := b.state.GetWindowForThread(userID, threadID)
+		if !bound {
+			b.reply(chatID, getThreadID(cq.Message), "This topic isn't bound to a session anymore.")
+			return
+		}
+		// Forward as if the user had typed it
+		msg := *cq.Message
+		msg.From = cq.From
+		msg.Text = text
+		b.submitText(&msg, windowID, text)
+	case "edit":
+		// The correction comes back as a new draft, so it's confirmed with Send too
+		b.editMessageText(chatID, msgID, "✏️ Send the corrected prompt as a message:\n\n"+text)
+		b.setPendingInput(cq.From.ID, "voice_edit", chatID, getThreadID(cq.Message))
+	default:
+		b.editMessageText(chatID, msgID, "🎙 "+text+"\n\n(cancelled)")
+	}
+}
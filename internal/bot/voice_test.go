@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestTranscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "it's a note.ogg")
+	os.WriteFile(path, []byte("  fix the login bug\n"), 0o600)
+	ctx := context.Background()
+
+	got, err := transcribe(ctx, "cat {file}", path)
+	if err != nil || got != "fix the login bug" {
+		t.Errorf("placeholder: %q, %v", got, err)
+	}
+	got, err = transcribe(ctx, "cat", path)
+	if err != nil || got != "fix the login bug" {
+		t.Errorf("appended path: %q, %v", got, err)
+	}
+
+	_, err = transcribe(ctx, "echo 'model not found' >&2; exit 1", path)
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("failure should carry stderr: %v", err)
+	}
+}
+
+func TestVoiceMessage(t *testing.T) {
+	if _, ok := voiceMessage(&tgbotapi.Message{Text: "hi"}); ok {
+		t.Error("text message should have no voice note")
+	}
+	up, ok := voiceMessage(&tgbotapi.Message{Voice: &tgbotapi.Voice{FileID: "v1", FileSize: 10}})
+	if !ok || up.fileID != "v1" || up.name != "voice.ogg" {
+		t.Errorf("voice = %+v", up)
+	}
+	up, ok = voiceMessage(&tgbotapi.Message{Audio: &tgbotapi.Audio{FileID: "a1", MimeType: "audio/mpeg"}})
+	if !ok || up.name != "audio.mp3" {
+		t.Errorf("audio = %+v", up)
+	}
+}
+
+func TestVoiceDrafts_TakenOnce(t *testing.T) {
+	b := &Bot{}
+	id := b.addVoiceDraft("run the tests")
+	if text, ok := b.takeVoiceDraft(id); !ok || text != "run the tests" {
+		t.Errorf("take = %q, %v", text, ok)
+	}
+	if _, ok := b.takeVoiceDraft(id); ok {
+		t.Error("a draft should only be acted on once")
+	}
+}
+
+func TestVoiceDrafts_Capped(t *testing.T) {
+	b := &Bot{}
+	first := b.addVoiceDraft("oldest")
+	for i := 0; i < maxVoiceDrafts; i++ {
+		b.addVoiceDraft("newer")
+	}
+	if len(b.voiceDrafts) != maxVoiceDrafts {
+		t.Errorf("drafts = %d, want %d", len(b.voiceDrafts), maxVoiceDrafts)
+	}
+	if _, ok := b.takeVoiceDraft(first); ok {
+		t.Error("the oldest draft should have expired")
+	}
+}
@@ -141,7 +141,7 @@ func (b *Bot) handleWinBind(cq *tgbotapi.CallbackQuery, wps *windowPickerState,
 
 	// Send pending text
 	if pendingText != "" {
-		if err := tmux.SendKeysWithDelay(b.config.TmuxSessionName, window.ID, pendingText, 500); err != nil {
+		if err := b.backendFor(window.ID).SendText(window.ID, pendingText); err != nil {
 			log.Printf("Error sending pending text: %v", err)
 		}
 	}
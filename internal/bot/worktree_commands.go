@@ -102,6 +102,7 @@ func (b *Bot) executePickwTask(chatID int64, threadID int, userID int64, taskID
 		return
 	}
 
+	b.trackTask(windowID, taskID)
 	b.reply(chatID, threadID, fmt.Sprintf("Working on task %s in worktree (branch: %s)", taskID, branch))
 }
 
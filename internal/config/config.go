@@ -6,8 +6,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/otaviocarvalho/tramuntana/internal/hooksock"
 )
 
 type Config struct {
@@ -17,7 +19,11 @@ type Config struct {
 	TramuntanaDir       string
 	TmuxSessionName     string
 	ClaudeCommand       string
+	AiderCommand        string
+	DefaultBackend      string // "tmux" or "stream-json"
 	MonitorPollInterval float64
+	PermissionTimeout   time.Duration // how long a Telegram permission prompt waits
+	PermissionDefault   string        // decision on timeout: "ask", "allow" or "deny"
 	MinuanoBin          string
 	MinuanoDB           string
 	MinuanoScriptsDir   string
@@ -25,6 +31,11 @@ type Config struct {
 	ApprovalsTopicID    int64
 	DefaultProject      string
 	PlannerPromptPath   string
+	Prices              PriceTable // USD per million tokens, by model substring
+	UploadsDir          string     // where Telegram uploads are saved, relative to the session's CWD
+	UploadMaxSize       int64      // bytes
+	UploadInlineText    bool       // send .md/.txt uploads as the prompt text instead of saving them
+	STTCommand          string     // speech-to-text command for voice notes; prints the transcript
 }
 
 func Load(envFile ...string) (*Config, error) {
@@ -74,6 +85,19 @@ func Load(envFile ...string) (*Config, error) {
 		claudeCmd = "claude"
 	}
 
+	aiderCmd := os.Getenv("AIDER_COMMAND")
+	if aiderCmd == "" {
+		aiderCmd = "aider"
+	}
+
+	defaultBackend := os.Getenv("TRAMUNTANA_BACKEND")
+	if defaultBackend == "" {
+		defaultBackend = "tmux"
+	}
+	if defaultBackend != "tmux" && defaultBackend != "stream-json" {
+		return nil, fmt.Errorf("invalid TRAMUNTANA_BACKEND %q (want tmux or stream-json)", defaultBackend)
+	}
+
 	pollInterval := 2.0
 	if p := os.Getenv("MONITOR_POLL_INTERVAL"); p != "" {
 		pollInterval, err = strconv.ParseFloat(p, 64)
@@ -82,6 +106,25 @@ func Load(envFile ...string) (*Config, error) {
 		}
 	}
 
+	permissionTimeout := 300
+	if p := os.Getenv("PERMISSION_TIMEOUT"); p != "" {
+		permissionTimeout, err = strconv.Atoi(p)
+		if err != nil || permissionTimeout <= 0 {
+			return nil, fmt.Errorf("invalid PERMISSION_TIMEOUT %q (want seconds > 0)", p)
+		}
+		if permissionTimeout >= hooksock.PermissionHookTimeout {
+			return nil, fmt.Errorf("PERMISSION_TIMEOUT %d must be below the hook's own timeout of %d seconds", permissionTimeout, hooksock.PermissionHookTimeout)
+		}
+	}
+
+	permissionDefault := os.Getenv("PERMISSION_DEFAULT")
+	if permissionDefault == "" {
+		permissionDefault = "ask"
+	}
+	if permissionDefault != "ask" && permissionDefault != "allow" && permissionDefault != "deny" {
+		return nil, fmt.Errorf("invalid PERMISSION_DEFAULT %q (want ask, allow or deny)", permissionDefault)
+	}
+
 	minuanoBin := os.Getenv("MINUANO_BIN")
 	if minuanoBin == "" {
 		minuanoBin = "minuano"
@@ -106,6 +149,32 @@ func Load(envFile ...string) (*Config, error) {
 		plannerPromptPath = "/home/otavio/code/minuano/claude/planner-system-prompt.md"
 	}
 
+	prices, err := loadPrices(os.Getenv("USAGE_PRICES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid USAGE_PRICES: %w", err)
+	}
+
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "uploads"
+	}
+
+	uploadMaxMB := 20 // the Bot API's download limit
+	if u := os.Getenv("UPLOAD_MAX_MB"); u != "" {
+		uploadMaxMB, err = strconv.Atoi(u)
+		if err != nil || uploadMaxMB <= 0 {
+			return nil, fmt.Errorf("invalid UPLOAD_MAX_MB %q (want megabytes > 0)", u)
+		}
+	}
+
+	var inlineText bool
+	if v := os.Getenv("UPLOAD_INLINE_TEXT"); v != "" {
+		inlineText, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UPLOAD_INLINE_TEXT %q (want true or false)", v)
+		}
+	}
+
 	return &Config{
 		TelegramBotToken:    token,
 		AllowedUsers:        users,
@@ -113,7 +182,11 @@ func Load(envFile ...string) (*Config, error) {
 		TramuntanaDir:       dir,
 		TmuxSessionName:     sessionName,
 		ClaudeCommand:       claudeCmd,
+		AiderCommand:        aiderCmd,
+		DefaultBackend:      defaultBackend,
 		MonitorPollInterval: pollInterval,
+		PermissionTimeout:   time.Duration(permissionTimeout) * time.Second,
+		PermissionDefault:   permissionDefault,
 		MinuanoBin:          minuanoBin,
 		MinuanoDB:           os.Getenv("MINUANO_DB"),
 		MinuanoScriptsDir:   minuanoScriptsDir,
@@ -121,6 +194,11 @@ func Load(envFile ...string) (*Config, error) {
 		ApprovalsTopicID:    approvalsTopicID,
 		DefaultProject:      defaultProject,
 		PlannerPromptPath:   plannerPromptPath,
+		Prices:              prices,
+		UploadsDir:          uploadsDir,
+		UploadMaxSize:       int64(uploadMaxMB) << 20,
+		UploadInlineText:    inlineText,
+		STTCommand:          os.Getenv("STT_COMMAND"),
 	}, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func clearEnv() {
@@ -11,6 +12,9 @@ func clearEnv() {
 		"TELEGRAM_BOT_TOKEN", "ALLOWED_USERS", "ALLOWED_GROUPS",
 		"TRAMUNTANA_DIR", "TMUX_SESSION_NAME", "CLAUDE_COMMAND",
 		"MONITOR_POLL_INTERVAL", "MINUANO_BIN", "MINUANO_DB",
+		"TRAMUNTANA_BACKEND", "AIDER_COMMAND", "PERMISSION_TIMEOUT", "PERMISSION_DEFAULT",
+		"USAGE_PRICES", "UPLOADS_DIR", "UPLOAD_MAX_MB", "UPLOAD_INLINE_TEXT",
+		"STT_COMMAND",
 	} {
 		os.Unsetenv(key)
 	}
@@ -58,12 +62,24 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.ClaudeCommand != "claude" {
 		t.Errorf("claude command = %q, want %q", cfg.ClaudeCommand, "claude")
 	}
+	if cfg.PermissionTimeout != 5*time.Minute || cfg.PermissionDefault != "ask" {
+		t.Errorf("permission defaults = %v/%q, want 5m/ask", cfg.PermissionTimeout, cfg.PermissionDefault)
+	}
+	if cfg.AiderCommand != "aider" {
+		t.Errorf("aider command = %q, want %q", cfg.AiderCommand, "aider")
+	}
 	if cfg.MonitorPollInterval != 2.0 {
 		t.Errorf("poll interval = %f, want 2.0", cfg.MonitorPollInterval)
 	}
 	if cfg.MinuanoBin != "minuano" {
 		t.Errorf("minuano bin = %q, want %q", cfg.MinuanoBin, "minuano")
 	}
+	if cfg.DefaultBackend != "tmux" {
+		t.Errorf("default backend = %q, want %q", cfg.DefaultBackend, "tmux")
+	}
+	if cfg.UploadsDir != "uploads" || cfg.UploadMaxSize != 20<<20 || cfg.UploadInlineText {
+		t.Errorf("upload defaults = %q/%d/%v, want uploads/20MB/false", cfg.UploadsDir, cfg.UploadMaxSize, cfg.UploadInlineText)
+	}
 }
 
 func TestLoad_AllowedGroups(t *testing.T) {
@@ -94,6 +110,8 @@ func TestLoad_CustomValues(t *testing.T) {
 	os.Setenv("MONITOR_POLL_INTERVAL", "5.0")
 	os.Setenv("MINUANO_BIN", "/usr/bin/minuano")
 	os.Setenv("MINUANO_DB", "/tmp/minuano.db")
+	os.Setenv("UPLOAD_MAX_MB", "5")
+	os.Setenv("UPLOAD_INLINE_TEXT", "true")
 
 	cfg, err := Load()
 	if err != nil {
@@ -111,6 +129,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	if cfg.MinuanoDB != "/tmp/minuano.db" {
 		t.Errorf("db = %q", cfg.MinuanoDB)
 	}
+	if cfg.UploadMaxSize != 5<<20 || !cfg.UploadInlineText {
+		t.Errorf("uploads = %d/%v", cfg.UploadMaxSize, cfg.UploadInlineText)
+	}
 }
 
 func TestLoad_CreatesTramuntanaDir(t *testing.T) {
@@ -142,6 +163,58 @@ func TestLoad_InvalidPollInterval(t *testing.T) {
 	}
 }
 
+func TestLoad_InvalidBackend(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	os.Setenv("TRAMUNTANA_BACKEND", "screen")
+	defer os.Unsetenv("TRAMUNTANA_BACKEND")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+}
+
+func TestLoad_InvalidPermissionSettings(t *testing.T) {
+	for key, value := range map[string]string{
+		"PERMISSION_TIMEOUT": "soon",
+		"PERMISSION_DEFAULT": "maybe",
+		"UPLOAD_MAX_MB":      "0",
+		"UPLOAD_INLINE_TEXT": "sometimes",
+	} {
+		clearEnv()
+		os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+		os.Setenv("ALLOWED_USERS", "1")
+		os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+		os.Setenv(key, value)
+
+		if _, err := Load(); err == nil {
+			t.Errorf("expected error for %s=%s", key, value)
+		}
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoad_PermissionTimeoutAboveHookTimeout(t *testing.T) {
+	clearEnv()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "tok")
+	os.Setenv("ALLOWED_USERS", "1")
+	os.Setenv("TRAMUNTANA_DIR", t.TempDir())
+	os.Setenv("PERMISSION_TIMEOUT", "3600")
+	defer os.Unsetenv("PERMISSION_TIMEOUT")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for PERMISSION_TIMEOUT not below the hook timeout")
+	}
+
+	os.Setenv("PERMISSION_TIMEOUT", "3599")
+	if _, err := Load(); err != nil {
+		t.Fatalf("PERMISSION_TIMEOUT below the hook timeout should load: %v", err)
+	}
+}
+
 func TestIsAllowedUser(t *testing.T) {
 	cfg := &Config{AllowedUsers: []int64{100, 200, 300}}
 
@@ -225,3 +298,58 @@ func TestLoad_FromEnvFile(t *testing.T) {
 		t.Errorf("token = %q, want file-token", cfg.TelegramBotToken)
 	}
 }
+
+func TestPriceTableLookup(t *testing.T) {
+	p, ok := DefaultPrices.Lookup("claude-opus-4-5-20251101")
+	if !ok || p.Input != 5 {
+		t.Errorf("opus-4-5 should win over opus: %+v", p)
+	}
+	for model, input := range map[string]float64{
+		"claude-opus-4-6":          5,
+		"claude-opus-4-7":          5,
+		"claude-opus-4-1-20250805": 15,
+		"claude-opus-4-20250514":   15,
+		"claude-3-opus-20240229":   15,
+		"claude-haiku-4-5":         1,
+		"claude-3-5-haiku-latest":  0.8,
+		"claude-3-haiku-20240307":  0.25,
+	} {
+		if p, ok := DefaultPrices.Lookup(model); !ok || p.Input != input {
+			t.Errorf("%s input price = %+v, want %v", model, p, input)
+		}
+	}
+	p, ok = DefaultPrices.Lookup("claude-sonnet-4-20250514")
+	if !ok || p.Output != 15 {
+		t.Errorf("sonnet price = %+v", p)
+	}
+	if _, ok := DefaultPrices.Lookup("<synthetic>"); ok {
+		t.Error("unknown models have no price")
+	}
+}
+
+func TestLoad_UsagePrices(t *testing.T) {
+	clearEnv()
+	tmpDir := t.TempDir()
+	pricesFile := filepath.Join(tmpDir, "prices.json")
+	os.WriteFile(pricesFile, []byte(`{"sonnet": {"input": 2, "output": 10}, "local": {"input": 0.1}}`), 0644)
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	os.Setenv("ALLOWED_USERS", "123")
+	os.Setenv("TRAMUNTANA_DIR", tmpDir)
+	os.Setenv("USAGE_PRICES", pricesFile)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Prices["sonnet"].Input != 2 || cfg.Prices["local"].Input != 0.1 {
+		t.Errorf("custom prices not applied: %+v", cfg.Prices)
+	}
+	if cfg.Prices["opus"] != DefaultPrices["opus"] {
+		t.Error("defaults should be kept for models the file doesn't mention")
+	}
+
+	os.Setenv("USAGE_PRICES", filepath.Join(tmpDir, "missing.json"))
+	if _, err := Load(); err == nil {
+		t.Error("expected error for a missing price file")
+	}
+}
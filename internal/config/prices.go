@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ModelPrice is the USD price per million tokens of a model.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// PriceTable maps a model name substring (e.g. "sonnet", "opus-4-5") to its price.
+type PriceTable map[string]ModelPrice
+
+// DefaultPrices are Anthropic's list prices; cache writes are the 5-minute tier.
+// The bare family names carry the current generation's price, so newer models
+// are priced like it; older ones are listed by their IDs.
+var DefaultPrices = PriceTable{
+	"opus":      {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"opus-4-5":  {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"opus-4-6":  {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"opus-4-1":  {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"opus-4-20": {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5}, // claude-opus-4-20250514
+	"3-opus":    {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"sonnet":    {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"haiku":     {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"haiku-4-5": {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"3-5-haiku": {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"3-haiku":   {Input: 0.25, Output: 1.25, CacheWrite: 0.3, CacheRead: 0.03},
+}
+
+// Lookup returns the price of the longest key contained in model.
+func (pt PriceTable) Lookup(model string) (ModelPrice, bool) {
+	var best string
+	for key := range pt {
+		if strings.Contains(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return pt[best], true
+}
+
+// loadPrices returns DefaultPrices overridden by the entries of the JSON file at path.
+func loadPrices(path string) (PriceTable, error) {
+	prices := make(PriceTable, len(DefaultPrices))
+	for k, v := range DefaultPrices {
+		prices[k] = v
+	}
+	if path == "" {
+		return prices, nil
+	}
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	var custom PriceTable
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for k, v := range custom {
+		prices[k] = v
+	}
+	return prices, nil
+}
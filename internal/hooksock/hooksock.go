@@ -0,0 +1,145 @@
+// Package hooksock carries Claude Code hook events from `tramuntana hook` to the
+// running serve process over a unix socket in TRAMUNTANA_DIR. Each connection
+// holds one JSON request and one JSON response.
+package hooksock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketName is the socket file name inside TRAMUNTANA_DIR.
+const SocketName = "hook.sock"
+
+// Permission decisions, as understood by Claude Code's PreToolUse hook output.
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+	DecisionAsk   = "ask"
+)
+
+// PermissionHookTimeout is Claude Code's timeout (seconds) for the blocking
+// PreToolUse hook. PERMISSION_TIMEOUT must stay below it so serve's default
+// applies before Claude Code gives up on the hook.
+const PermissionHookTimeout = 3600
+
+// Request is a hook event forwarded to the serve process.
+type Request struct {
+	Event          string          `json:"event"`
+	SessionID      string          `json:"session_id"`
+	CWD            string          `json:"cwd,omitempty"`
+	Window         string          `json:"window,omitempty"` // "session:@id" when the hook runs inside tmux
+	PermissionMode string          `json:"permission_mode,omitempty"`
+	ToolName       string          `json:"tool_name,omitempty"`
+	ToolInput      json.RawMessage `json:"tool_input,omitempty"`
+	ToolUseID      string          `json:"tool_use_id,omitempty"`
+
+	Message          string `json:"message,omitempty"`           // Notification text
+	NotificationType string `json:"notification_type,omitempty"` // e.g. "permission_prompt", "idle_prompt"
+}
+
+// Response is the serve process's answer. An empty Decision means no opinion:
+// Claude Code carries on with its normal permission flow.
+type Response struct {
+	Decision string `json:"decision,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SocketPath returns the socket path inside a tramuntana dir.
+func SocketPath(dir string) string {
+	return filepath.Join(dir, SocketName)
+}
+
+// Send delivers a request and waits for the response. There is no deadline:
+// permission requests block until answered, and Claude Code's hook timeout
+// bounds the wait.
+func Send(path string, req Request) (Response, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return Response{}, fmt.Errorf("connecting to %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("sending request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+	return resp, nil
+}
+
+// Server accepts hook connections and answers them with a handler.
+type Server struct {
+	ln      net.Listener
+	path    string
+	handler func(Request) Response
+}
+
+// Listen binds the socket, replacing a stale socket file left by a previous
+// process. Fails if another serve process is still listening.
+func Listen(path string, handler func(Request) Response) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		os.Remove(path)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("restricting %s: %w", path, err)
+	}
+	return &Server{ln: ln, path: path, handler: handler}, nil
+}
+
+// Serve accepts connections until ctx is cancelled, then removes the socket.
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.ln.Close()
+	}()
+	defer os.Remove(s.path)
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Hook socket: accept: %v", err)
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.Printf("Hook socket: bad request: %v", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	resp := s.handler(req)
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Printf("Hook socket: writing %s response: %v", req.Event, err)
+	}
+}
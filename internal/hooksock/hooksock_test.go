@@ -0,0 +1,77 @@
+package hooksock
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestSendRoundTrip(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	srv, err := Listen(path, func(req Request) Response {
+		if req.Event != "PreToolUse" || req.ToolName != "Bash" {
+			return Response{}
+		}
+		return Response{Decision: DecisionDeny, Reason: "no " + req.ToolName}
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.Serve(ctx)
+		close(done)
+	}()
+
+	resp, err := Send(path, Request{
+		Event:     "PreToolUse",
+		SessionID: "s1",
+		ToolName:  "Bash",
+		ToolInput: json.RawMessage(`{"command":"ls"}`),
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if resp.Decision != DecisionDeny || resp.Reason != "no Bash" {
+		t.Errorf("got %+v", resp)
+	}
+
+	cancel()
+	<-done
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("socket should be removed after shutdown")
+	}
+}
+
+func TestSend_NoServer(t *testing.T) {
+	if _, err := Send(SocketPath(t.TempDir()), Request{Event: "PreToolUse"}); err == nil {
+		t.Error("expected error without a server")
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := Listen(path, func(Request) Response { return Response{} })
+	if err != nil {
+		t.Fatalf("Listen over stale file: %v", err)
+	}
+	srv.ln.Close()
+}
+
+func TestListen_InUse(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	srv, err := Listen(path, func(Request) Response { return Response{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.ln.Close()
+
+	if _, err := Listen(path, func(Request) Response { return Response{} }); err == nil {
+		t.Error("second Listen should fail while the first is serving")
+	}
+}
@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+// ExpandCallbackPrefix prefixes the callback data of "Show more" buttons: exp_<ref>.
+const ExpandCallbackPrefix = "exp_"
+
+// maxExpandRefs is how many buttons of each kind stay usable; older refs are dropped.
+const maxExpandRefs = 2000
+
+// ErrExpandGone is returned for refs that were dropped, or that predate a restart.
+var ErrExpandGone = errors.New("no longer available")
+
+// expandRef locates a truncated block in its transcript. The full text is
+// re-read on demand instead of being held in memory.
+type expandRef struct {
+	path      string
+	offset    int64
+	block     int
+	format    TranscriptFormat
+	blockType string // "tool_result" or "thinking"
+	toolUseID string
+	toolName  string
+	toolInput string
+}
+
+// refRegistry maps short refs (fit in callback data) to values, keeping the
+// newest maxExpandRefs.
+type refRegistry[T any] struct {
+	mu    sync.Mutex
+	next  uint64
+	refs  map[string]T
+	order []string
+}
+
+func (r *refRegistry[T]) add(v T) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs == nil {
+		r.refs = make(map[string]T)
+	}
+	r.next++
+	id := strconv.FormatUint(r.next, 36)
+	r.refs[id] = v
+	r.order = append(r.order, id)
+	if len(r.order) > maxExpandRefs {
+		delete(r.refs, r.order[0])
+		r.order = r.order[1:]
+	}
+	return id
+}
+
+func (r *refRegistry[T]) get(id string) (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.refs[id]
+	return v, ok
+}
+
+// registerExpandable gives each tool_result and thinking entry that renders
+// truncated an ExpandID, so it can be sent with a "Show more" button.
+func (m *Monitor) registerExpandable(jsonlPath, windowID string, parsed []ParsedEntry) {
+	format := m.formatFor(windowID)
+	for i := range parsed {
+		pe := &parsed[i]
+		switch {
+		case pe.ContentType == "tool_result" && render.ToolResultTruncated(pe.ToolName, pe.Text, pe.IsError):
+		case pe.ContentType == "thinking" && render.ThinkingTruncated(pe.Text):
+		default:
+			continue
+		}
+		pe.ExpandID = m.expands.add(expandRef{
+			path:      jsonlPath,
+			offset:    pe.Offset,
+			block:     pe.Block,
+			format:    format,
+			blockType: pe.ContentType,
+			toolUseID: pe.ToolUseID,
+			toolName:  pe.ToolName,
+			toolInput: pe.ToolInput,
+		})
+	}
+}
+
+// entryKeyboard returns the "Show more", "Subagent log" and "Send file" buttons of an entry, or nil.
+func entryKeyboard(pe ParsedEntry) *tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if pe.ExpandID != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Show more", ExpandCallbackPrefix+pe.ExpandID))
+	}
+	if pe.SubagentLog != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Subagent log", SubagentCallbackPrefix+pe.SubagentLog))
+	}
+	if pe.SendFile != "" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Send file", FileCallbackPrefix+pe.SendFile))
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	kb := tgbotapi.NewInlineKeyboardMarkup(row)
+	return &kb
+}
+
+// Expanded re-reads the full text of a block registered by ref. title names
+// the block, e.g. "Bash(go test ./...)" or "Thinking".
+func (m *Monitor) Expanded(id string) (title, text string, err error) {
+	ref, ok := m.expands.get(id)
+	if !ok {
+		return "", "", ErrExpandGone
+	}
+
+	f, err := os.Open(ref.path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	if _, err := f.Seek(ref.offset, 0); err != nil {
+		return "", "", err
+	}
+	line, _, err := NewLineReader(f).Next()
+	if err != nil {
+		return "", "", fmt.Errorf("reading transcript: %w", err)
+	}
+
+	// Claude lines are re-parsed without degrading oversized ones
+	var entry *Entry
+	if _, ok := ref.format.(claudeTranscript); ok {
+		entry, err = parseLine(line)
+	} else {
+		entry, err = ref.format.ParseLine(line)
+	}
+	if err != nil || entry == nil || ref.block >= len(entry.Blocks) {
+		return "", "", ErrExpandGone // transcript was rewritten, e.g. by /clear
+	}
+
+	block := entry.Blocks[ref.block]
+	if block.Type != ref.blockType || block.ToolUseID != ref.toolUseID {
+		return "", "", ErrExpandGone
+	}
+	if block.Type == "thinking" {
+		return "Thinking", block.Text, nil
+	}
+	return toolHeaderText(ref.toolName, ref.toolInput), block.Content, nil
+}
+
+// toolHeaderText is FormatToolUseSummary without markdown.
+func toolHeaderText(name, input string) string {
+	return name + "(" + input + ")"
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestExpanded_ReadsFullBlocksFromTranscript(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+
+	output := strings.Repeat("line\n", 20)
+	thinking := strings.Repeat("hmm ", 200)
+	outputJSON, _ := json.Marshal(output)
+	thinkingJSON, _ := json.Marshal(thinking)
+	lines := []string{
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"seq 20"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":` + string(outputJSON) + `}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"short"},{"type":"thinking","thinking":` + string(thinkingJSON) + `}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"thinking","thinking":"brief"}]}}`,
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}, state.NewState(), state.NewMonitorState(), nil)
+	m.processSession("test:@1", "test-session", "@1", path)
+
+	if len(m.expands.order) != 2 {
+		t.Fatalf("registered %d refs, want 2 (long output and long thinking)", len(m.expands.order))
+	}
+
+	title, text, err := m.Expanded(m.expands.order[0])
+	if err != nil {
+		t.Fatalf("Expanded: %v", err)
+	}
+	if title != "Bash(seq 20)" || text != output {
+		t.Errorf("Expanded = %q, %q", title, text)
+	}
+
+	title, text, err = m.Expanded(m.expands.order[1])
+	if err != nil {
+		t.Fatalf("Expanded: %v", err)
+	}
+	if title != "Thinking" || text != thinking {
+		t.Errorf("Expanded = %q, %q", title, text)
+	}
+
+	if _, _, err := m.Expanded("nope"); !errors.Is(err, ErrExpandGone) {
+		t.Errorf("unknown ref: err = %v, want ErrExpandGone", err)
+	}
+
+	// /clear rewrites the transcript; old refs must not return other content
+	os.WriteFile(path, []byte(lines[0]+"\n"+lines[0]+"\n"), 0o644)
+	if _, _, err := m.Expanded(m.expands.order[0]); !errors.Is(err, ErrExpandGone) {
+		t.Errorf("rewritten transcript: err = %v, want ErrExpandGone", err)
+	}
+}
+
+func TestRefRegistry_DropsOldest(t *testing.T) {
+	var r refRegistry[expandRef]
+	first := r.add(expandRef{path: "a"})
+	for i := 0; i < maxExpandRefs; i++ {
+		r.add(expandRef{path: "b"})
+	}
+	if _, ok := r.get(first); ok {
+		t.Error("oldest ref should have been dropped")
+	}
+	if len(r.refs) != maxExpandRefs {
+		t.Errorf("len(refs) = %d, want %d", len(r.refs), maxExpandRefs)
+	}
+	if len(ExpandCallbackPrefix+r.order[len(r.order)-1]) > 64 {
+		t.Error("callback data exceeds Telegram's 64 bytes")
+	}
+}
+
+func TestEntryKeyboard(t *testing.T) {
+	if entryKeyboard(ParsedEntry{}) != nil {
+		t.Error("no refs should mean no keyboard")
+	}
+	kb := entryKeyboard(ParsedEntry{ExpandID: "1a"})
+	if kb == nil || *kb.InlineKeyboard[0][0].CallbackData != "exp_1a" {
+		t.Errorf("keyboard = %+v", kb)
+	}
+	kb = entryKeyboard(ParsedEntry{ExpandID: "1a", SubagentLog: "2"})
+	if kb == nil || len(kb.InlineKeyboard[0]) != 2 || *kb.InlineKeyboard[0][1].CallbackData != "sub_2" {
+		t.Errorf("keyboard = %+v", kb)
+	}
+}
+
+func TestWrittenFile(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"file_path":"/work/out/report.pdf","content":"x"}`, "/work/out/report.pdf"},
+		{`{"file_path":"chart.PNG","content":"x"}`, "/work/chart.PNG"},
+		{`{"file_path":"/work/main.go","content":"x"}`, ""},
+		{`{"file_path":"/etc/data.csv","content":"x"}`, ""},
+		{`{"file_path":"/work/../secret.zip","content":"x"}`, ""},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		got, ok := writtenFile("/work", []byte(tt.input))
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("writtenFile(%s) = %q, %v, want %q", tt.input, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRegisterWrittenFiles(t *testing.T) {
+	st := state.NewState()
+	st.SetWindowState("@1", state.WindowState{CWD: "/work"})
+	m := New(&config.Config{TramuntanaDir: t.TempDir(), MonitorPollInterval: 2.0}, st, state.NewMonitorState(), nil)
+
+	parsed := []ParsedEntry{
+		{ContentType: "tool_result", ToolName: "Write", RawInput: json.RawMessage(`{"file_path":"/work/plot.png"}`)},
+		{ContentType: "tool_result", ToolName: "Write", IsError: true, RawInput: json.RawMessage(`{"file_path":"/work/plot.png"}`)},
+		{ContentType: "tool_result", ToolName: "Edit", RawInput: json.RawMessage(`{"file_path":"/work/plot.png"}`)},
+	}
+	m.registerWrittenFiles("@1", parsed)
+	if parsed[0].SendFile == "" || parsed[1].SendFile != "" || parsed[2].SendFile != "" {
+		t.Fatalf("refs = %q, %q, %q", parsed[0].SendFile, parsed[1].SendFile, parsed[2].SendFile)
+	}
+	if path, err := m.WrittenFile(parsed[0].SendFile); err != nil || path != "/work/plot.png" {
+		t.Errorf("WrittenFile = %q, %v", path, err)
+	}
+	if _, err := m.WrittenFile("nope"); !errors.Is(err, ErrExpandGone) {
+		t.Errorf("unknown ref: err = %v", err)
+	}
+	kb := entryKeyboard(parsed[0])
+	if kb == nil || *kb.InlineKeyboard[0][0].CallbackData != FileCallbackPrefix+parsed[0].SendFile {
+		t.Errorf("keyboard = %+v", kb)
+	}
+}
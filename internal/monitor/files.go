@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/otaviocarvalho/tramuntana/internal/queue"
+)
+
+// FileCallbackPrefix prefixes the callback data of "Send file" buttons: file_<ref>.
+const FileCallbackPrefix = "file_"
+
+// sendableExts are the extensions of written files offered with a "Send file"
+// button: files that are easier to open on the phone than to read as a diff.
+var sendableExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".svg": true, ".bmp": true,
+	".pdf": true, ".csv": true, ".tsv": true, ".xlsx": true, ".xls": true,
+	".docx": true, ".doc": true, ".pptx": true, ".odt": true, ".ods": true,
+	".zip": true, ".gz": true, ".tgz": true, ".tar": true,
+	".mp3": true, ".wav": true, ".mp4": true,
+}
+
+// photoTypes are the image media types Telegram shows as photos; others are sent as documents.
+var photoTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+// registerWrittenFiles gives each successful Write of a sendable file inside
+// the window's working directory a SendFile ref.
+func (m *Monitor) registerWrittenFiles(windowID string, parsed []ParsedEntry) {
+	var cwd string
+	for i := range parsed {
+		pe := &parsed[i]
+		if pe.ContentType != "tool_result" || pe.ToolName != "Write" || pe.IsError {
+			continue
+		}
+		if cwd == "" {
+			ws, ok := m.state.GetWindowState(windowID)
+			if !ok || ws.CWD == "" {
+				return
+			}
+			cwd = ws.CWD
+		}
+		if path, ok := writtenFile(cwd, pe.RawInput); ok {
+			pe.SendFile = m.files.add(path)
+		}
+	}
+}
+
+// writtenFile returns the path of a Write call's file if it has a sendable
+// extension and lies inside cwd.
+func writtenFile(cwd string, input []byte) (string, bool) {
+	var in struct {
+		FilePath string `json:"file_path"`
+	}
+	json.Unmarshal(input, &in)
+	path := in.FilePath
+	if path == "" || !sendableExts[strings.ToLower(filepath.Ext(path))] {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	rel, err := filepath.Rel(cwd, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Clean(path), true
+}
+
+// WrittenFile returns the path of a file registered by a "Send file" button.
+func (m *Monitor) WrittenFile(ref string) (string, error) {
+	path, ok := m.files.get(ref)
+	if !ok {
+		return "", ErrExpandGone
+	}
+	return path, nil
+}
+
+// enqueueImages sends the images of an entry as photos, or as documents for
+// types Telegram doesn't show inline.
+func (m *Monitor) enqueueImages(userID int64, threadID int, chatID int64, windowID string, pe ParsedEntry) {
+	caption := "Image"
+	if pe.ToolName != "" {
+		caption = pe.ToolName + ": image"
+	}
+	for i, img := range pe.Images {
+		ext, photo := photoTypes[img.MediaType]
+		if !photo {
+			ext = "." + strings.TrimPrefix(img.MediaType, "image/")
+		}
+		m.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{caption},
+			ContentType: "attachment",
+			WindowID:    windowID,
+			File: &queue.Attachment{
+				Name:  "image-" + strconv.Itoa(i+1) + ext,
+				Data:  img.Data,
+				Photo: photo,
+			},
+		})
+	}
+}
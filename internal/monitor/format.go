@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TranscriptFormat locates and parses an agent's JSONL transcripts.
+// The monitor uses ClaudeTranscript unless Monitor.FormatFor says otherwise.
+type TranscriptFormat interface {
+	// Locate returns the transcript path for a session, or "" if not found yet.
+	Locate(sessionID, cwd string) string
+	// ParseLine parses one transcript line. Returns nil for ignorable entries.
+	ParseLine(line []byte) (*Entry, error)
+}
+
+// ClaudeTranscript is Claude Code's format: ~/.claude/projects/<project>/<session>.jsonl.
+var ClaudeTranscript TranscriptFormat = claudeTranscript{}
+
+type claudeTranscript struct{}
+
+func (claudeTranscript) ParseLine(line []byte) (*Entry, error) {
+	return ParseLine(line)
+}
+
+// Locate scans ~/.claude/projects/ for the session's JSONL file.
+func (claudeTranscript) Locate(sessionID, cwd string) string {
+	claudeDir := claudeProjectsDir()
+	entries, err := os.ReadDir(claudeDir)
+	if err != nil {
+		return ""
+	}
+
+	for _, dir := range entries {
+		if !dir.IsDir() {
+			continue
+		}
+
+		projectDir := filepath.Join(claudeDir, dir.Name())
+
+		// Check sessions-index.json
+		indexPath := filepath.Join(projectDir, "sessions-index.json")
+		if path := searchSessionsIndex(indexPath, sessionID, projectDir); path != "" {
+			return path
+		}
+
+		// Fallback: glob for JSONL files
+		if path := searchJSONLFiles(projectDir, sessionID); path != "" {
+			return path
+		}
+	}
+
+	return ""
+}
+
+func searchSessionsIndex(indexPath, sessionID, projectDir string) string {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return ""
+	}
+
+	var index map[string]json.RawMessage
+	if err := json.Unmarshal(data, &index); err != nil {
+		return ""
+	}
+
+	for id := range index {
+		if id == sessionID {
+			jsonlPath := filepath.Join(projectDir, id+".jsonl")
+			if _, err := os.Stat(jsonlPath); err == nil {
+				return jsonlPath
+			}
+		}
+	}
+	return ""
+}
+
+func searchJSONLFiles(projectDir, sessionID string) string {
+	matches, err := filepath.Glob(filepath.Join(projectDir, "*.jsonl"))
+	if err != nil {
+		return ""
+	}
+
+	for _, match := range matches {
+		base := filepath.Base(match)
+		if strings.TrimSuffix(base, ".jsonl") == sessionID {
+			return match
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// oversizedLineBytes is the size above which a JSONL line is parsed in
+	// degraded form (large block bodies are cut down, raw data is dropped).
+	oversizedLineBytes = 1024 * 1024
+
+	// maxLineBytes caps how much of a single line is held in memory.
+	// Longer lines are discarded while being streamed past.
+	maxLineBytes = 64 * 1024 * 1024
+
+	// maxDegradedBlockBytes is how much text a block keeps in degraded form.
+	maxDegradedBlockBytes = 16 * 1024
+)
+
+// ErrLineTooLong is returned by LineReader.Next for a complete line longer than
+// maxLineBytes. The line has been consumed; callers should skip it and continue.
+var ErrLineTooLong = errors.New("jsonl line exceeds maximum size")
+
+// LineReader reads newline-terminated JSONL records of arbitrary length.
+// Unlike bufio.Scanner it never fails on long lines, and it does not return a
+// trailing partial line — Claude may still be writing it.
+type LineReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewLineReader wraps r in a LineReader.
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next returns the next complete line without its trailing newline, and the
+// number of bytes consumed including the newline. The returned slice is only
+// valid until the next call.
+//
+// At end of input Next returns io.EOF with n == 0; any incomplete final line is
+// left unconsumed from the caller's point of view (n does not include it).
+func (lr *LineReader) Next() (line []byte, n int64, err error) {
+	lr.buf = lr.buf[:0]
+	tooLong := false
+
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		n += int64(len(chunk))
+
+		if !tooLong {
+			if len(lr.buf)+len(chunk) > maxLineBytes {
+				tooLong = true
+				lr.buf = lr.buf[:0]
+			} else {
+				lr.buf = append(lr.buf, chunk...)
+			}
+		}
+
+		switch {
+		case err == nil:
+			if tooLong {
+				return nil, n, ErrLineTooLong
+			}
+			return bytes.TrimSuffix(lr.buf, []byte{'\n'}), n, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			// Partial line (or nothing): don't report it as consumed
+			return nil, 0, io.EOF
+		default:
+			return nil, 0, err
+		}
+	}
+}
+
+// degradeEntry trims block bodies of an entry parsed from an oversized line so
+// that huge tool results (file dumps, base64 images) don't flood Telegram.
+// Metadata such as tool names, IDs and error flags is kept.
+func degradeEntry(e *Entry) {
+	e.RawData = nil
+	for i := range e.Blocks {
+		e.Blocks[i].Text = degradeText(e.Blocks[i].Text)
+		e.Blocks[i].Content = degradeText(e.Blocks[i].Content)
+	}
+}
+
+// degradeText cuts text to maxDegradedBlockBytes at a UTF-8 boundary, noting how much was dropped.
+func degradeText(s string) string {
+	if len(s) <= maxDegradedBlockBytes {
+		return s
+	}
+	cut := maxDegradedBlockBytes
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut] + "\n… (" + formatBytes(len(s)-cut) + " omitted)"
+}
+
+// formatBytes renders a byte count as a short human-readable size.
+func formatBytes(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%d MB", n/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%d KB", n/1024)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLineReader_CompleteLines(t *testing.T) {
+	lr := NewLineReader(strings.NewReader("a\nbb\n\nccc\n"))
+
+	var got []string
+	var total int64
+	for {
+		line, n, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, string(line))
+		total += n
+	}
+
+	want := []string{"a", "bb", "", "ccc"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if total != 10 {
+		t.Errorf("bytes consumed = %d, want 10", total)
+	}
+}
+
+func TestLineReader_PartialTrailingLine(t *testing.T) {
+	lr := NewLineReader(strings.NewReader("done\n{\"type\":\"assist"))
+
+	line, n, err := lr.Next()
+	if err != nil || string(line) != "done" || n != 5 {
+		t.Fatalf("first line = %q, %d, %v", line, n, err)
+	}
+
+	// The partial line must not be reported or counted
+	line, n, err = lr.Next()
+	if err != io.EOF || n != 0 || line != nil {
+		t.Errorf("partial line = %q, %d, %v; want nil, 0, EOF", line, n, err)
+	}
+}
+
+func TestLineReader_LongLine(t *testing.T) {
+	long := strings.Repeat("x", 3*1024*1024)
+	lr := NewLineReader(strings.NewReader(long + "\nnext\n"))
+
+	line, n, err := lr.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(line) != len(long) || n != int64(len(long)+1) {
+		t.Errorf("long line len = %d (n=%d), want %d", len(line), n, len(long))
+	}
+
+	line, _, err = lr.Next()
+	if err != nil || string(line) != "next" {
+		t.Errorf("line after long line = %q, %v", line, err)
+	}
+}
+
+func TestDegradeText(t *testing.T) {
+	short := "hello"
+	if got := degradeText(short); got != short {
+		t.Errorf("short text changed: %q", got)
+	}
+
+	long := strings.Repeat("é", maxDegradedBlockBytes) // 2 bytes per rune
+	got := degradeText(long)
+	if len(got) > maxDegradedBlockBytes+64 {
+		t.Errorf("degraded text too long: %d bytes", len(got))
+	}
+	if !strings.Contains(got, "KB omitted") {
+		t.Errorf("degraded text missing omission note: %q", got[len(got)-40:])
+	}
+	if !strings.HasPrefix(got, "éé") || strings.ContainsRune(got, '�') {
+		t.Error("degraded text should be cut at a rune boundary")
+	}
+}
@@ -1,24 +1,29 @@
 package monitor
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/otaviocarvalho/tramuntana/internal/config"
 	"github.com/otaviocarvalho/tramuntana/internal/queue"
 	"github.com/otaviocarvalho/tramuntana/internal/render"
 	"github.com/otaviocarvalho/tramuntana/internal/state"
 )
 
-// Monitor polls Claude Code JSONL transcript files and routes entries to the message queue.
+// Monitor watches Claude Code JSONL transcript files and routes entries to the message queue.
+// File changes are picked up through inotify; polling is kept as a fallback and for reconciliation.
 type Monitor struct {
 	config         *config.Config
 	state          *state.State
@@ -30,7 +35,21 @@ type Monitor struct {
 	pollInterval   time.Duration
 	turnStarts     sync.Map // windowID → time.Time
 	PlanHandler    func(userID int64, threadID int, chatID int64, planJSON string)
-	planBuffers    map[string]string // windowID → partial plan text
+	// QuestionHandler renders AskUserQuestion/ExitPlanMode tool calls natively.
+	// Returns false to have the tool_use queued as a summary instead.
+	QuestionHandler func(userID int64, threadID int, chatID int64, windowID, toolName string, input json.RawMessage) bool
+	// FormatFor returns a window's transcript format (nil or nil result → ClaudeTranscript).
+	FormatFor          func(windowID string) TranscriptFormat
+	planBuffers        map[string]string     // windowID → partial plan text
+	watchedFiles       map[string]sessionRef // JSONL path → owning session
+	dirtyFiles         map[string]bool       // JSONL paths written since last flush
+	unresolvedSessions map[string]bool       // session IDs whose JSONL file wasn't found yet
+	// Usage accumulates token usage from assistant entries (nil disables accounting).
+	Usage     *state.UsageState
+	lastUsage map[string]Usage // JSONL path (|Task tool_use ID for subagents) → last assistant usage recorded
+	expands   refRegistry[expandRef]
+	files     refRegistry[string] // "Send file" refs → absolute path
+	subagents subagentTracker
 }
 
 // New creates a new Monitor.
@@ -45,23 +64,100 @@ func New(cfg *config.Config, st *state.State, ms *state.MonitorState, q *queue.Q
 		lastSessionMap: make(map[string]state.SessionMapEntry),
 		pollInterval:   time.Duration(cfg.MonitorPollInterval * float64(time.Second)),
 		planBuffers:    make(map[string]string),
+		lastUsage:      make(map[string]Usage),
+
+		watchedFiles:       make(map[string]sessionRef),
+		dirtyFiles:         make(map[string]bool),
+		unresolvedSessions: make(map[string]bool),
 	}
 }
 
-// Run starts the monitor poll loop. Blocks until ctx is cancelled.
+// Run starts the monitor loop. Blocks until ctx is cancelled.
+// Transcript writes are delivered as soon as inotify reports them. If the watcher
+// can't be created, the monitor falls back to polling every MONITOR_POLL_INTERVAL.
 func (m *Monitor) Run(ctx context.Context) {
 	log.Println("Session monitor starting...")
-	ticker := time.NewTicker(m.pollInterval)
+
+	var events <-chan fsnotify.Event
+	var watchErrs <-chan error
+	interval := m.pollInterval
+
+	fw, err := newFileWatcher()
+	if err != nil {
+		log.Printf("Monitor: file watcher unavailable, polling every %v: %v", m.pollInterval, err)
+	} else {
+		defer fw.close()
+		fw.watchDir(m.config.TramuntanaDir)
+		fw.watchProjects(claudeProjectsDir())
+		events = fw.w.Events
+		watchErrs = fw.w.Errors
+		if interval < watchReconcileInterval {
+			interval = watchReconcileInterval
+		}
+	}
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Debounce timer for event-driven reads (stopped until the first event)
+	flush := time.NewTimer(eventDebounce)
+	flush.Stop()
+	defer flush.Stop()
+	var firstDirty time.Time // first event since the last flush
+
+	// reconcile polls every session and watches any transcript directory
+	// outside ~/.claude/projects (other agents' formats)
+	reconcile := func() {
+		m.poll()
+		if fw != nil {
+			for path := range m.watchedFiles {
+				fw.watchDir(filepath.Dir(path))
+			}
+		}
+	}
+
+	reconcile()
+
 	for {
 		select {
 		case <-ctx.Done():
 			m.monitorState.ForceSave(filepath.Join(m.config.TramuntanaDir, "monitor_state.json"))
+			if m.Usage != nil {
+				m.Usage.ForceSave(filepath.Join(m.config.TramuntanaDir, "usage.json"))
+			}
 			log.Println("Session monitor stopped.")
 			return
 		case <-ticker.C:
-			m.poll()
+			if fw != nil {
+				// Pick up project dirs created before we could see their parent
+				fw.watchProjects(claudeProjectsDir())
+			}
+			reconcile()
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if m.handleEvent(fw, ev) {
+				reconcile()
+			}
+			if len(m.dirtyFiles) > 0 {
+				if firstDirty.IsZero() {
+					firstDirty = time.Now()
+				}
+				flush.Reset(debounceDelay(firstDirty, time.Now()))
+			}
+		case <-flush.C:
+			firstDirty = time.Time{}
+			m.flushDirty()
+		case err, ok := <-watchErrs:
+			if !ok {
+				watchErrs = nil
+				continue
+			}
+			// Usually an inotify queue overflow — reconcile to catch anything dropped
+			log.Printf("Monitor: watcher error: %v (reconciling)", err)
+			reconcile()
 		}
 	}
 }
@@ -77,6 +173,10 @@ func (m *Monitor) poll() {
 	// Detect changes
 	m.detectChanges(sm)
 
+	// Rebuild the path → session index used by the file watcher
+	watched := make(map[string]sessionRef, len(sm))
+	unresolved := make(map[string]bool)
+
 	// Process each active session
 	for key, entry := range sm {
 		windowID := windowIDFromSessionKey(key)
@@ -85,10 +185,12 @@ func (m *Monitor) poll() {
 		}
 
 		// Find the JSONL file for this session
-		jsonlPath := m.findJSONLFile(entry.SessionID, entry.CWD)
+		jsonlPath := m.findJSONLFile(m.formatFor(windowID), entry.SessionID, entry.CWD)
 		if jsonlPath == "" {
+			unresolved[entry.SessionID] = true
 			continue
 		}
+		watched[jsonlPath] = sessionRef{SessionKey: key, SessionID: entry.SessionID, WindowID: windowID}
 
 		// Check mtime
 		if !m.hasFileChanged(jsonlPath) {
@@ -100,10 +202,19 @@ func (m *Monitor) poll() {
 	}
 
 	m.lastSessionMap = sm
+	m.watchedFiles = watched
+	m.unresolvedSessions = unresolved
 
 	// Periodically save state
-	monitorStatePath := filepath.Join(m.config.TramuntanaDir, "monitor_state.json")
-	m.monitorState.SaveIfDirty(monitorStatePath)
+	m.saveIfDirty()
+}
+
+// saveIfDirty persists monitor offsets and usage totals that changed.
+func (m *Monitor) saveIfDirty() {
+	m.monitorState.SaveIfDirty(filepath.Join(m.config.TramuntanaDir, "monitor_state.json"))
+	if m.Usage != nil {
+		m.Usage.SaveIfDirty(filepath.Join(m.config.TramuntanaDir, "usage.json"))
+	}
 }
 
 func (m *Monitor) detectChanges(newMap map[string]state.SessionMapEntry) {
@@ -163,27 +274,41 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 	}
 
 	var entries []*Entry
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large lines
 	var bytesRead int64
+	lr := NewLineReader(f)
+	format := m.formatFor(windowID)
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		bytesRead += int64(len(line)) + 1 // +1 for newline
+	for {
+		line, n, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == ErrLineTooLong {
+			log.Printf("JSONL line at offset %d in %s exceeds %d bytes, skipping", offset+bytesRead, jsonlPath, maxLineBytes)
+			bytesRead += n
+			continue
+		}
+		if err != nil {
+			log.Printf("JSONL read error for %s at offset %d: %v", jsonlPath, offset+bytesRead, err)
+			break // keep what was read so far; resume from there on next pass
+		}
+		lineOffset := offset + bytesRead
+		bytesRead += n
 
-		entry, err := ParseLine(line)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		entry, err := format.ParseLine(line)
 		if err != nil {
 			log.Printf("JSONL parse error at offset %d: %v", offset+bytesRead, err)
 			continue
 		}
 		if entry != nil {
+			entry.Offset = lineOffset
 			entries = append(entries, entry)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		log.Printf("JSONL read error for %s at offset %d: %v (not advancing offset)", jsonlPath, offset+bytesRead, err)
-		return // don't advance offset — will re-read on next poll
-	}
 
 	if len(entries) == 0 {
 		// Update offset even if no entries (skip empty lines)
@@ -194,11 +319,15 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 		return
 	}
 
-	// Parse entries with tool pairing
-	parsed := ParseEntries(entries, m.pendingTools)
+	// Parse entries with tool pairing, folding subagent entries into their Task call
+	m.resolveSidechains(entries)
+	parsed := m.trackSubagents(ParseEntries(entries, m.pendingTools))
+	m.registerExpandable(jsonlPath, windowID, parsed)
+	m.registerWrittenFiles(windowID, parsed)
 
 	// Route to users
 	users := m.state.FindUsersForWindow(windowID)
+	m.recordUsage(sessionID, windowID, jsonlPath, users, entries)
 	for _, ut := range users {
 		chatID, ok := m.state.GetGroupChatID(ut.UserID, ut.ThreadID)
 		if !ok {
@@ -217,6 +346,56 @@ func (m *Monitor) processSession(sessionKey, sessionID, windowID, jsonlPath stri
 	m.monitorState.UpdateOffset(sessionKey, sessionID, jsonlPath, newOffset)
 }
 
+// recordUsage adds the token usage of new assistant entries to m.Usage.
+// An API response spans several entries sharing a message ID and usage,
+// so only growth over the last recorded counts of that message is added.
+func (m *Monitor) recordUsage(sessionID, windowID, jsonlPath string, users []state.UserThread, entries []*Entry) {
+	if m.Usage == nil {
+		return
+	}
+	rec := state.UsageRecord{Session: sessionID, Task: m.Usage.WindowTask(windowID)}
+	if len(users) > 0 {
+		// Count each call once, toward the same topic every time
+		rec.Thread = usageThread(users)
+		rec.Project, _ = m.state.GetProject(rec.Thread)
+	}
+	for _, e := range entries {
+		if e.Usage == nil {
+			continue
+		}
+		// Subagent responses interleave with the main thread's
+		key := jsonlPath
+		if e.ParentToolUseID != "" {
+			key += "|" + e.ParentToolUseID
+		}
+		u := *e.Usage
+		last := m.lastUsage[key]
+		m.lastUsage[key] = u
+		if u.MessageID != "" && u.MessageID == last.MessageID {
+			u.Input = max(u.Input-last.Input, 0)
+			u.Output = max(u.Output-last.Output, 0)
+			u.CacheWrite = max(u.CacheWrite-last.CacheWrite, 0)
+			u.CacheRead = max(u.CacheRead-last.CacheRead, 0)
+		}
+		rec.Model = u.Model
+		rec.TokenUsage = state.TokenUsage{Input: u.Input, Output: u.Output, CacheWrite: u.CacheWrite, CacheRead: u.CacheRead}
+		if rec.TokenUsage.Total() > 0 {
+			m.Usage.Add(windowID, time.Now(), rec)
+		}
+	}
+}
+
+// usageThread picks the topic a window's usage is attributed to when several
+// are bound to it: the one with the lowest thread ID.
+func usageThread(users []state.UserThread) string {
+	ut := slices.MinFunc(users, func(a, b state.UserThread) int {
+		an, _ := strconv.Atoi(a.ThreadID)
+		bn, _ := strconv.Atoi(b.ThreadID)
+		return cmp.Or(cmp.Compare(an, bn), strings.Compare(a.ThreadID, b.ThreadID), strings.Compare(a.UserID, b.UserID))
+	})
+	return ut.ThreadID
+}
+
 // SetTurnStart records the start time of a user turn for a window.
 func (m *Monitor) SetTurnStart(windowID string) {
 	m.turnStarts.Store(windowID, time.Now())
@@ -234,6 +413,7 @@ func (m *Monitor) GetAndClearTurnStart(windowID string) (time.Time, bool) {
 func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowID string, pe ParsedEntry) {
 	var text string
 	var contentType string
+	var attachment *queue.Attachment
 
 	// Track turn start when we see a user entry
 	if pe.Role == "user" && pe.ContentType == "text" {
@@ -261,6 +441,29 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 		}
 	}
 
+	// Filter by the user's verbosity before queueing, so quiet topics
+	// don't add to flood control pressure
+	level := m.state.VerbosityFor(strconv.FormatInt(userID, 10), strconv.Itoa(threadID))
+
+	if pe.ToolName == "TodoWrite" {
+		if input, ok := todoInput(pe); ok && showsTodo(level) {
+			m.enqueueTodo(userID, threadID, chatID, windowID, input)
+		}
+		return
+	}
+
+	// Questions need an answer whatever the verbosity
+	if pe.ContentType == "tool_use" && pe.RawInput != nil && m.QuestionHandler != nil {
+		if m.QuestionHandler(userID, threadID, chatID, windowID, pe.ToolName, pe.RawInput) {
+			return
+		}
+	}
+
+	pe, ok := applyVerbosity(level, pe)
+	if !ok {
+		return
+	}
+
 	switch pe.ContentType {
 	case "text":
 		if pe.Role == "user" {
@@ -278,9 +481,20 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 	case "tool_result":
 		text = render.FormatToolResult(pe.ToolName, pe.ToolInput, pe.Text, pe.IsError)
 		contentType = "tool_result"
+		if !pe.IsError {
+			if diffText, file, ok := formatEditDiff(pe); ok {
+				text, attachment = diffText, file
+			}
+		}
 	case "thinking":
 		text = render.FormatThinking(pe.Text)
 		contentType = "content"
+	case "subagent":
+		text = pe.Text // pre-formatted by trackSubagents
+		contentType = "tool_progress"
+	case "image":
+		m.enqueueImages(userID, threadID, chatID, windowID, pe)
+		return
 	default:
 		return
 	}
@@ -297,85 +511,134 @@ func (m *Monitor) enqueueEntry(userID int64, threadID int, chatID int64, windowI
 		ContentType: contentType,
 		ToolUseID:   pe.ToolUseID,
 		WindowID:    windowID,
+		Keyboard:    entryKeyboard(pe),
 	})
+	if attachment != nil {
+		m.queue.Enqueue(queue.MessageTask{
+			UserID:      userID,
+			ThreadID:    threadID,
+			ChatID:      chatID,
+			Parts:       []string{pe.ToolName + ": " + attachment.Name},
+			ContentType: "attachment",
+			WindowID:    windowID,
+			File:        attachment,
+		})
+	}
 }
 
-// findJSONLFile locates the JSONL transcript file for a session.
-func (m *Monitor) findJSONLFile(sessionID, cwd string) string {
-	// First: check monitor state for cached path
-	for _, key := range m.monitorState.AllKeys() {
-		tracked, ok := m.monitorState.GetTracked(key)
-		if ok && tracked.SessionID == sessionID && tracked.FilePath != "" {
-			if _, err := os.Stat(tracked.FilePath); err == nil {
-				return tracked.FilePath
-			}
+// formatEditDiff formats an Edit, MultiEdit or Write result as a unified diff
+// of its input. A diff too long to show inline is returned as a PNG or .diff
+// attachment to send after the result. A Write over a file whose previous
+// contents aren't in the transcript shows the new contents instead.
+func formatEditDiff(pe ParsedEntry) (string, *queue.Attachment, bool) {
+	created := strings.HasPrefix(pe.Text, "File created")
+	path, diff, ok := render.EditDiff(pe.ToolName, pe.RawInput, pe.RawResult, created)
+	if !ok {
+		if pe.ToolName == "Write" && !created {
+			return formatWriteContents(pe)
 		}
+		return "", nil, false
+	}
+
+	delivery := render.DiffDeliveryFor(diff)
+	text := render.FormatDiffResult(pe.ToolName, pe.ToolInput, diff, delivery == render.DiffInline)
+	name := filepath.Base(path)
+	switch delivery {
+	case render.DiffInline:
+		return text, nil, true
+	case render.DiffImage:
+		png, err := render.RenderDiff(diff)
+		if err == nil {
+			return text, &queue.Attachment{Name: name + ".diff.png", Data: png, Photo: true}, true
+		}
+		log.Printf("Error rendering diff of %s: %v", path, err)
 	}
+	return text, &queue.Attachment{Name: name + ".diff", Data: []byte(diff + "\n")}, true
+}
 
-	// Second: scan ~/.claude/projects/ for matching session
-	claudeDir := filepath.Join(os.Getenv("HOME"), ".claude", "projects")
-	entries, err := os.ReadDir(claudeDir)
-	if err != nil {
-		return ""
+// formatWriteContents formats an overwrite as the file's new contents, inline
+// or as a document named after the file.
+func formatWriteContents(pe ParsedEntry) (string, *queue.Attachment, bool) {
+	path, content, ok := render.WriteContents(pe.RawInput)
+	if !ok || content == "" {
+		return "", nil, false
 	}
-
-	for _, dir := range entries {
-		if !dir.IsDir() {
-			continue
-		}
-
-		projectDir := filepath.Join(claudeDir, dir.Name())
-
-		// Check sessions-index.json
-		indexPath := filepath.Join(projectDir, "sessions-index.json")
-		if path := m.searchSessionsIndex(indexPath, sessionID, projectDir); path != "" {
-			return path
-		}
-
-		// Fallback: glob for JSONL files
-		if path := m.searchJSONLFiles(projectDir, sessionID); path != "" {
-			return path
-		}
+	inline := render.DiffDeliveryFor(content) == render.DiffInline
+	text := render.FormatWriteResult(pe.ToolInput, content, inline)
+	if inline {
+		return text, nil, true
 	}
+	return text, &queue.Attachment{Name: filepath.Base(path), Data: []byte(content)}, true
+}
 
-	return ""
+// todoInput returns the TodoWrite input an entry updates the checklist from:
+// the tool_use's, or the result's when the tool_use was folded into it. The
+// result of a tool_use already seen carries the same input and is skipped, so
+// a completed list isn't cleared and then posted again.
+func todoInput(pe ParsedEntry) (json.RawMessage, bool) {
+	if pe.ToolName != "TodoWrite" || pe.RawInput == nil {
+		return nil, false
+	}
+	if pe.ContentType != "tool_use" && !pe.FoldedToolUse {
+		return nil, false
+	}
+	return pe.RawInput, true
 }
 
-func (m *Monitor) searchSessionsIndex(indexPath, sessionID, projectDir string) string {
-	data, err := os.ReadFile(indexPath)
-	if err != nil {
-		return ""
+// enqueueTodo posts or updates the topic's checklist from a TodoWrite input.
+// A fully completed list is left as is and the next one starts a new message.
+func (m *Monitor) enqueueTodo(userID int64, threadID int, chatID int64, windowID string, input json.RawMessage) {
+	var in struct {
+		Todos []render.TodoItem `json:"todos"`
+	}
+	if err := json.Unmarshal(input, &in); err != nil || len(in.Todos) == 0 {
+		return
 	}
 
-	var index map[string]json.RawMessage
-	if err := json.Unmarshal(data, &index); err != nil {
-		return ""
+	task := queue.MessageTask{
+		UserID:      userID,
+		ThreadID:    threadID,
+		ChatID:      chatID,
+		Parts:       []string{render.FormatTodoList(in.Todos)},
+		ContentType: "todo",
+		WindowID:    windowID,
 	}
+	m.queue.Enqueue(task)
 
-	for id := range index {
-		if id == sessionID {
-			jsonlPath := filepath.Join(projectDir, id+".jsonl")
-			if _, err := os.Stat(jsonlPath); err == nil {
-				return jsonlPath
-			}
+	for _, t := range in.Todos {
+		if t.Status != "completed" {
+			return
 		}
 	}
-	return ""
+	task.Parts = nil
+	task.ContentType = "todo_clear"
+	m.queue.Enqueue(task)
 }
 
-func (m *Monitor) searchJSONLFiles(projectDir, sessionID string) string {
-	matches, err := filepath.Glob(filepath.Join(projectDir, "*.jsonl"))
-	if err != nil {
-		return ""
+// findJSONLFile locates the transcript file for a session in the given format.
+func (m *Monitor) findJSONLFile(format TranscriptFormat, sessionID, cwd string) string {
+	// First: check monitor state for cached path
+	for _, key := range m.monitorState.AllKeys() {
+		tracked, ok := m.monitorState.GetTracked(key)
+		if ok && tracked.SessionID == sessionID && tracked.FilePath != "" {
+			if _, err := os.Stat(tracked.FilePath); err == nil {
+				return tracked.FilePath
+			}
+		}
 	}
 
-	for _, match := range matches {
-		base := filepath.Base(match)
-		if strings.TrimSuffix(base, ".jsonl") == sessionID {
-			return match
+	// Second: ask the format where its transcripts live
+	return format.Locate(sessionID, cwd)
+}
+
+// formatFor returns the transcript format used by a window.
+func (m *Monitor) formatFor(windowID string) TranscriptFormat {
+	if m.FormatFor != nil {
+		if f := m.FormatFor(windowID); f != nil {
+			return f
 		}
 	}
-	return ""
+	return ClaudeTranscript
 }
 
 // extractPlanJSON finds "PLAN_JSON:" marker followed by a JSON array,
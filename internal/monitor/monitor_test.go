@@ -1,8 +1,12 @@
 package monitor
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -161,13 +165,7 @@ func TestFindJSONLFile_SessionsIndex(t *testing.T) {
 	// Create JSONL file
 	os.WriteFile(filepath.Join(projectDir, "test-session-id.jsonl"), []byte(`{}`), 0o644)
 
-	cfg := &config.Config{
-		TramuntanaDir:       t.TempDir(),
-		MonitorPollInterval: 2.0,
-	}
-	m := New(cfg, state.NewState(), state.NewMonitorState(), nil)
-
-	path := m.searchSessionsIndex(
+	path := searchSessionsIndex(
 		filepath.Join(projectDir, "sessions-index.json"),
 		"test-session-id",
 		projectDir,
@@ -182,19 +180,274 @@ func TestSearchJSONLFiles(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "abc-123.jsonl"), []byte(`{}`), 0o644)
 	os.WriteFile(filepath.Join(dir, "other.jsonl"), []byte(`{}`), 0o644)
 
-	cfg := &config.Config{
-		TramuntanaDir:       t.TempDir(),
-		MonitorPollInterval: 2.0,
-	}
-	m := New(cfg, state.NewState(), state.NewMonitorState(), nil)
-
-	path := m.searchJSONLFiles(dir, "abc-123")
+	path := searchJSONLFiles(dir, "abc-123")
 	if path == "" {
 		t.Error("should find JSONL file by name")
 	}
 
-	path = m.searchJSONLFiles(dir, "nonexistent")
+	path = searchJSONLFiles(dir, "nonexistent")
 	if path != "" {
 		t.Error("should not find nonexistent session")
 	}
 }
+
+func TestRun_WatcherPicksUpAppends(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tramDir := t.TempDir()
+
+	projectDir := filepath.Join(home, ".claude", "projects", "-tmp-proj")
+	os.MkdirAll(projectDir, 0o755)
+	sessionID := "11111111-2222-3333-4444-555555555555"
+	jsonlPath := filepath.Join(projectDir, sessionID+".jsonl")
+	line := `{"type":"assistant","message":{"content":"hello"}}` + "\n"
+	os.WriteFile(jsonlPath, []byte(line), 0o644)
+
+	state.WriteSessionMap(filepath.Join(tramDir, "session_map.json"), map[string]state.SessionMapEntry{
+		"tramuntana:@1": {SessionID: sessionID, CWD: "/tmp/proj"},
+	})
+
+	// Long poll interval: only the watcher can deliver the append in time
+	cfg := &config.Config{TramuntanaDir: tramDir, MonitorPollInterval: 60}
+	ms := state.NewMonitorState()
+	m := New(cfg, state.NewState(), ms, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForOffset := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			if tracked, ok := ms.GetTracked("tramuntana:@1"); ok && tracked.LastByteOffset == want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		tracked, _ := ms.GetTracked("tramuntana:@1")
+		t.Fatalf("offset = %d, want %d", tracked.LastByteOffset, want)
+	}
+
+	// Initial reconcile poll reads the existing line
+	waitForOffset(int64(len(line)))
+
+	f, err := os.OpenFile(jsonlPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(line)
+	f.Close()
+
+	waitForOffset(int64(2 * len(line)))
+}
+
+func TestProcessSession_MultiMegabyteLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+
+	small := `{"type":"assistant","message":{"content":"before"}}` + "\n"
+	os.WriteFile(path, []byte(small), 0o644)
+
+	cfg := &config.Config{
+		TramuntanaDir:       dir,
+		MonitorPollInterval: 2.0,
+	}
+	ms := state.NewMonitorState()
+	m := New(cfg, state.NewState(), ms, nil)
+	m.processSession("test:@1", "test-session", "@1", path)
+
+	// Append a ~5MB tool_result (e.g. a base64 image dump) followed by a normal line
+	huge := strings.Repeat("A", 5*1024*1024)
+	bigLine := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"` + huge + `"}]}}` + "\n"
+	after := `{"type":"assistant","message":{"content":"after"}}` + "\n"
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(bigLine + after)
+	f.Close()
+
+	m.processSession("test:@1", "test-session", "@1", path)
+
+	tracked, ok := ms.GetTracked("test:@1")
+	if !ok {
+		t.Fatal("should have tracked session")
+	}
+	want := int64(len(small) + len(bigLine) + len(after))
+	if tracked.LastByteOffset != want {
+		t.Errorf("offset = %d, want %d (monitor stalled on large line)", tracked.LastByteOffset, want)
+	}
+}
+
+func TestProcessSession_PartialLineNotConsumed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+
+	complete := `{"type":"assistant","message":{"content":"hi"}}` + "\n"
+	os.WriteFile(path, []byte(complete+`{"type":"assis`), 0o644)
+
+	cfg := &config.Config{
+		TramuntanaDir:       dir,
+		MonitorPollInterval: 2.0,
+	}
+	ms := state.NewMonitorState()
+	m := New(cfg, state.NewState(), ms, nil)
+	m.processSession("test:@1", "test-session", "@1", path)
+
+	tracked, _ := ms.GetTracked("test:@1")
+	if tracked.LastByteOffset != int64(len(complete)) {
+		t.Errorf("offset = %d, want %d (partial line must wait for its newline)", tracked.LastByteOffset, len(complete))
+	}
+}
+
+func TestProcessSession_RecordsUsageOncePerMessage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+
+	// One API response split over two entries (text + tool_use) sharing id and usage,
+	// the second reporting the final output count
+	usage := func(out int) string {
+		return fmt.Sprintf(`"usage":{"input_tokens":10,"output_tokens":%d,"cache_read_input_tokens":1000}`, out)
+	}
+	lines := `{"type":"assistant","message":{"id":"m1","model":"claude-opus-4-5","content":[{"type":"text","text":"a"}],` + usage(5) + `}}` + "\n" +
+		`{"type":"assistant","message":{"id":"m1","model":"claude-opus-4-5","content":[{"type":"tool_use","id":"t1","name":"Read","input":{}}],` + usage(80) + `}}` + "\n" +
+		`{"type":"assistant","message":{"id":"m2","model":"claude-opus-4-5","content":[{"type":"text","text":"b"}],` + usage(20) + `}}` + "\n"
+	os.WriteFile(path, []byte(lines), 0o644)
+
+	st := state.NewState()
+	st.BindThread("100", "5", "@1")
+	st.BindProject("5", "web")
+	m := New(&config.Config{TramuntanaDir: dir, MonitorPollInterval: 2.0}, st, state.NewMonitorState(), nil)
+	m.Usage = state.NewUsageState()
+	m.Usage.SetWindowTask("@1", "T-1")
+	m.processSession("test:@1", "sess", "@1", path)
+
+	recs := m.Usage.Matching(time.Time{}, nil)
+	if len(recs) != 1 {
+		t.Fatalf("records = %+v, want one", recs)
+	}
+	r := recs[0]
+	if r.Thread != "5" || r.Project != "web" || r.Task != "T-1" || r.Session != "sess" {
+		t.Errorf("attribution = %+v", r)
+	}
+	want := state.TokenUsage{Input: 20, Output: 100, CacheRead: 2000}
+	if r.TokenUsage != want {
+		t.Errorf("usage = %+v, want %+v", r.TokenUsage, want)
+	}
+}
+
+func TestFormatEditDiff(t *testing.T) {
+	pe := ParsedEntry{
+		ContentType: "tool_result",
+		ToolName:    "Edit",
+		ToolInput:   "main.go",
+		RawInput:    json.RawMessage(`{"file_path":"/src/main.go","old_string":"a","new_string":"b"}`),
+		Text:        "The file /src/main.go has been updated.",
+	}
+	text, file, ok := formatEditDiff(pe)
+	if !ok || file != nil {
+		t.Fatalf("short diff should be inline: ok=%v file=%v", ok, file)
+	}
+	if !strings.Contains(text, "```diff\n--- a/src/main.go\n+++ b/src/main.go\n") {
+		t.Errorf("text = %q", text)
+	}
+
+	content, _ := json.Marshal(strings.Repeat("x\n", 500))
+	pe.ToolName = "Write"
+	pe.RawInput = json.RawMessage(`{"file_path":"/src/big.txt","content":` + string(content) + `}`)
+	pe.Text = "File created successfully at: /src/big.txt"
+	text, file, ok = formatEditDiff(pe)
+	if !ok || file == nil || file.Name != "big.txt.diff" || file.Photo {
+		t.Fatalf("long diff should be a .diff document: ok=%v file=%+v", ok, file)
+	}
+	if strings.Contains(text, "```") || !strings.Contains(text, "Added 500, removed 0") {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.HasPrefix(string(file.Data), "--- /dev/null\n") {
+		t.Errorf("document should hold the diff, got %q", string(file.Data[:40]))
+	}
+
+	pe.Text = "The file /src/big.txt has been updated."
+	text, file, ok = formatEditDiff(pe)
+	if !ok || file == nil || file.Name != "big.txt" || !strings.Contains(text, "new contents") {
+		t.Fatalf("overwrite without a patch should send the new contents: ok=%v file=%+v text=%q", ok, file, text)
+	}
+
+	pe.RawInput = json.RawMessage(`{"file_path":"/src/small.txt","content":"new\n"}`)
+	pe.RawResult = json.RawMessage(`{"type":"update","originalFile":"old\n"}`)
+	text, file, ok = formatEditDiff(pe)
+	if !ok || file != nil || !strings.Contains(text, "-old\n+new") {
+		t.Errorf("overwrite with the original should be a diff: ok=%v file=%v text=%q", ok, file, text)
+	}
+
+	pe.ToolName = "Bash"
+	if _, _, ok := formatEditDiff(pe); ok {
+		t.Error("Bash results have no diff")
+	}
+}
+
+func TestDebounceDelay(t *testing.T) {
+	first := time.Now()
+	tests := []struct {
+		since time.Duration
+		want  time.Duration
+	}{
+		{0, eventDebounce},
+		{eventMaxWait - eventDebounce, eventDebounce},
+		{eventMaxWait - 30*time.Millisecond, 30 * time.Millisecond},
+		{eventMaxWait, 0},
+		{2 * eventMaxWait, 0},
+	}
+	for _, tt := range tests {
+		if got := debounceDelay(first, first.Add(tt.since)); got != tt.want {
+			t.Errorf("debounceDelay after %s = %s, want %s", tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestUsageThread(t *testing.T) {
+	users := []state.UserThread{{UserID: "1", ThreadID: "42"}, {UserID: "2", ThreadID: "7"}, {UserID: "1", ThreadID: "100"}}
+	for i := 0; i < 3; i++ {
+		if got := usageThread(users); got != "7" {
+			t.Fatalf("usageThread = %q, want the lowest thread 7", got)
+		}
+		users = append(users[1:], users[0])
+	}
+}
+
+func TestTodoInput_OncePerCall(t *testing.T) {
+	use := []byte(`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_todo","name":"TodoWrite","input":{"todos":[{"content":"ship","status":"completed","activeForm":"Shipping"}]}}]}}`)
+	result := []byte(`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_todo","content":"ok"}]}}`)
+	count := func(batches ...[][]byte) int {
+		pending := make(map[string]PendingTool)
+		n := 0
+		for _, batch := range batches {
+			var entries []*Entry
+			for _, line := range batch {
+				e, err := ParseLine(line)
+				if err != nil {
+					t.Fatalf("ParseLine: %v", err)
+				}
+				entries = append(entries, e)
+			}
+			for _, pe := range ParseEntries(entries, pending) {
+				if _, ok := todoInput(pe); ok {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	if n := count([][]byte{use}, [][]byte{result}); n != 1 {
+		t.Errorf("tool_use and result in two batches: checklist sent %d times, want 1", n)
+	}
+	if n := count([][]byte{use, result}); n != 1 {
+		t.Errorf("tool_use and result in one batch: checklist sent %d times, want 1", n)
+	}
+}
@@ -0,0 +1,291 @@
+package monitor
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/otaviocarvalho/tramuntana/internal/render"
+)
+
+// SubagentCallbackPrefix prefixes the callback data of "Subagent log" buttons: sub_<ref>.
+const SubagentCallbackPrefix = "sub_"
+
+const (
+	// maxSubagentRuns is how many Task calls keep their log; older finished ones are dropped.
+	maxSubagentRuns = 200
+	// maxSubagentLogBytes caps a subagent's log; the oldest lines are dropped beyond it.
+	maxSubagentLogBytes = 256 * 1024
+	// subagentRecent is how many recent actions the progress message lists.
+	subagentRecent = 8
+	// subagentResultLines is how many lines of each tool result the log keeps.
+	subagentResultLines = 20
+)
+
+// subagentRun accumulates the sidechain of one Task call.
+type subagentRun struct {
+	ref         string // short id for callback data
+	toolName    string
+	description string
+	tools       int
+	recent      []string
+	log         []string
+	logBytes    int
+	done        bool
+}
+
+// subagentTracker groups subagent entries by their Task tool_use ID.
+type subagentTracker struct {
+	mu      sync.Mutex
+	next    uint64
+	runs    map[string]*subagentRun // Task tool_use ID → run
+	refs    map[string]string       // ref → Task tool_use ID
+	order   []string                // Task tool_use IDs, oldest first
+	parents map[string]string       // inline sidechain entry UUID → Task tool_use ID
+}
+
+// run returns the run of a Task call, creating it if needed. Callers hold mu.
+func (t *subagentTracker) run(parentID string) *subagentRun {
+	if t.runs == nil {
+		t.runs = make(map[string]*subagentRun)
+		t.refs = make(map[string]string)
+	}
+	if r, ok := t.runs[parentID]; ok {
+		return r
+	}
+	t.next++
+	r := &subagentRun{ref: strconv.FormatUint(t.next, 36), toolName: "Task"}
+	t.runs[parentID] = r
+	t.refs[r.ref] = parentID
+	t.order = append(t.order, parentID)
+
+	// Drop the oldest finished runs beyond the cap
+	for i := 0; len(t.runs) > maxSubagentRuns && i < len(t.order); {
+		id := t.order[i]
+		if old := t.runs[id]; old.done {
+			delete(t.refs, old.ref)
+			delete(t.runs, id)
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+	return r
+}
+
+// appendLog adds a line to the run's log, dropping the oldest past the cap.
+func (r *subagentRun) appendLog(line string) {
+	r.log = append(r.log, line)
+	r.logBytes += len(line) + 1
+	for r.logBytes > maxSubagentLogBytes && len(r.log) > 1 {
+		r.logBytes -= len(r.log[0]) + 1
+		r.log = r.log[1:]
+	}
+}
+
+// addRecent records an action for the progress message.
+func (r *subagentRun) addRecent(action string) {
+	r.recent = append(r.recent, action)
+	if len(r.recent) > subagentRecent {
+		r.recent = r.recent[len(r.recent)-subagentRecent:]
+	}
+}
+
+// resolveSidechains ties inline sidechain entries to their Task call. A
+// subagent's first entry is its prompt, matched against the prompts of Task
+// calls still pending or made earlier in the batch; the entries after it
+// chain to it by parent UUID.
+func (m *Monitor) resolveSidechains(entries []*Entry) {
+	t := &m.subagents
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prompts := make(map[string]string) // Task tool_use ID → prompt
+	for id, pt := range m.pendingTools {
+		if pt.ToolName == "Task" {
+			prompts[id] = taskPrompt(pt.RawInput)
+		}
+	}
+
+	for _, e := range entries {
+		if !e.Sidechain {
+			for _, b := range e.Blocks {
+				if b.Type == "tool_use" && b.ToolName == "Task" {
+					prompts[b.ToolUseID] = taskPrompt(b.RawInput)
+				}
+			}
+			continue
+		}
+		if e.ParentToolUseID == "" {
+			if parent, ok := t.parents[e.ParentUUID]; ok && e.ParentUUID != "" {
+				e.ParentToolUseID = parent
+			} else if e.ParentUUID == "" && e.Type == "user" {
+				e.ParentToolUseID = taskForPrompt(prompts, e)
+			}
+		}
+		if e.ParentToolUseID != "" && e.UUID != "" {
+			if t.parents == nil {
+				t.parents = make(map[string]string)
+			}
+			t.parents[e.UUID] = e.ParentToolUseID
+		}
+	}
+}
+
+// taskPrompt extracts the prompt from a Task call's input.
+func taskPrompt(input []byte) string {
+	var in struct {
+		Prompt string `json:"prompt"`
+	}
+	json.Unmarshal(input, &in)
+	return strings.TrimSpace(in.Prompt)
+}
+
+// taskForPrompt finds the Task call whose prompt is the entry's text.
+func taskForPrompt(prompts map[string]string, e *Entry) string {
+	var prompt string
+	for _, b := range e.Blocks {
+		if b.Type == "text" {
+			prompt += b.Text
+		}
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return ""
+	}
+	for id, p := range prompts {
+		if p == prompt {
+			return id
+		}
+	}
+	return ""
+}
+
+// trackSubagents folds subagent entries into their Task call's run and
+// replaces them with one progress entry per running Task call, placed after
+// the batch. A Task result gets the ref of its subagent's log.
+func (m *Monitor) trackSubagents(parsed []ParsedEntry) []ParsedEntry {
+	t := &m.subagents
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var touched []string
+	out := parsed[:0]
+	for _, pe := range parsed {
+		if pe.ParentToolUseID == "" {
+			if pe.ContentType == "tool_result" {
+				if r, ok := t.runs[pe.ToolUseID]; ok {
+					r.done = true
+					if r.description == "" {
+						r.toolName, r.description = pe.ToolName, pe.ToolInput
+					}
+					pe.SubagentLog = r.ref
+					m.forgetSidechain(pe.ToolUseID)
+					for key := range m.lastUsage {
+						if strings.HasSuffix(key, "|"+pe.ToolUseID) {
+							delete(m.lastUsage, key)
+						}
+					}
+				}
+			}
+			out = append(out, pe)
+			continue
+		}
+
+		r := t.run(pe.ParentToolUseID)
+		if pt, ok := m.pendingTools[pe.ParentToolUseID]; ok && r.description == "" {
+			r.toolName, r.description = pt.ToolName, pt.Input
+		}
+		switch pe.ContentType {
+		case "tool_use":
+			r.tools++
+			r.addRecent(pe.Text)
+			r.appendLog("● " + toolHeaderText(pe.ToolName, pe.ToolInput))
+		case "tool_result":
+			r.appendLog(subagentResultLog(pe.Text, pe.IsError))
+		case "text":
+			if pe.Role == "user" {
+				r.appendLog("Prompt:\n" + pe.Text)
+				continue
+			}
+			r.addRecent("💬 " + truncateLine(pe.Text, 80))
+			r.appendLog(pe.Text)
+		}
+		if !slices.Contains(touched, pe.ParentToolUseID) {
+			touched = append(touched, pe.ParentToolUseID)
+		}
+	}
+
+	for _, id := range touched {
+		r := t.runs[id]
+		if r.done || r.tools == 0 && len(r.recent) == 0 {
+			continue
+		}
+		out = append(out, ParsedEntry{
+			Role:        "assistant",
+			ContentType: "subagent",
+			ToolUseID:   id,
+			ToolName:    r.toolName,
+			Text:        render.FormatSubagentProgress(r.toolName, r.description, r.tools, r.recent),
+			SubagentLog: r.ref,
+		})
+	}
+	return out
+}
+
+// forgetSidechain drops the UUID links of a finished Task call. Callers hold subagents.mu.
+func (m *Monitor) forgetSidechain(parentID string) {
+	for uuid, p := range m.subagents.parents {
+		if p == parentID {
+			delete(m.subagents.parents, uuid)
+		}
+	}
+}
+
+// SubagentLog returns the log of a Task call's subagent by ref, titled after the call.
+func (m *Monitor) SubagentLog(ref string) (title, text string, err error) {
+	t := &m.subagents
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parentID, ok := t.refs[ref]
+	if !ok {
+		return "", "", ErrExpandGone
+	}
+	r := t.runs[parentID]
+	return toolHeaderText(r.toolName, r.description), strings.Join(r.log, "\n"), nil
+}
+
+// subagentResultLog renders a subagent's tool result for its log: the first
+// subagentResultLines lines, indented under the call.
+func subagentResultLog(content string, isError bool) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	more := len(lines) - subagentResultLines
+	if more > 0 {
+		lines = lines[:subagentResultLines]
+	}
+	prefix := "  ⎿ "
+	if isError {
+		prefix += "Error: "
+	}
+	text := prefix + strings.Join(lines, "\n    ")
+	if more > 0 {
+		text += "\n    … +" + strconv.Itoa(more) + " lines"
+	}
+	return text
+}
+
+// truncateLine returns the first line of text, cut to at most n bytes on a
+// rune boundary.
+func truncateLine(text string, n int) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if len(line) <= n {
+		return line
+	}
+	cut := n
+	for cut > 0 && line[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return line[:cut] + "…"
+}
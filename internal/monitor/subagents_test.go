@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/otaviocarvalho/tramuntana/internal/config"
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func newSubagentTestMonitor(t *testing.T) *Monitor {
+	t.Helper()
+	return New(&config.Config{TramuntanaDir: t.TempDir(), MonitorPollInterval: 2.0}, state.NewState(), state.NewMonitorState(), nil)
+}
+
+func TestTrackSubagents_InlineSidechain(t *testing.T) {
+	m := newSubagentTestMonitor(t)
+	lines := []string{
+		`{"type":"assistant","uuid":"m1","message":{"content":[{"type":"tool_use","id":"tu_task","name":"Task","input":{"description":"Find TODOs","prompt":"Find every TODO"}}]}}`,
+		`{"type":"user","isSidechain":true,"uuid":"s1","parentUuid":null,"message":{"content":"Find every TODO"}}`,
+		`{"type":"assistant","isSidechain":true,"uuid":"s2","parentUuid":"s1","message":{"content":[{"type":"tool_use","id":"g1","name":"Grep","input":{"pattern":"TODO"}}]}}`,
+		`{"type":"user","isSidechain":true,"uuid":"s3","parentUuid":"s2","message":{"content":[{"type":"tool_result","tool_use_id":"g1","content":"a.go:1\nb.go:2"}]}}`,
+	}
+	parsed := m.processLines(t, lines)
+
+	if len(parsed) != 2 || parsed[0].ContentType != "tool_use" || parsed[1].ContentType != "subagent" {
+		t.Fatalf("expected the Task tool_use then its progress, got %+v", parsed)
+	}
+	progress := parsed[1]
+	if progress.ToolUseID != "tu_task" || progress.SubagentLog == "" {
+		t.Errorf("progress = %+v", progress)
+	}
+	if !strings.Contains(progress.Text, "**Task**(Find TODOs)") || !strings.Contains(progress.Text, "1 tool call · last: **Grep**(TODO)") {
+		t.Errorf("progress text = %q", progress.Text)
+	}
+
+	// Later batch: the chain continues by parent UUID, then the Task returns
+	parsed = m.processLines(t, []string{
+		`{"type":"assistant","isSidechain":true,"uuid":"s4","parentUuid":"s3","message":{"content":[{"type":"text","text":"Found 2 TODOs"}]}}`,
+		`{"type":"user","uuid":"m2","message":{"content":[{"type":"tool_result","tool_use_id":"tu_task","content":"Found 2 TODOs"}]}}`,
+	})
+	if len(parsed) != 1 || parsed[0].ContentType != "tool_result" {
+		t.Fatalf("a finished Task should only send its result, got %+v", parsed)
+	}
+	if parsed[0].SubagentLog != progress.SubagentLog {
+		t.Errorf("result should link the subagent log, got %q", parsed[0].SubagentLog)
+	}
+
+	title, log, err := m.SubagentLog(progress.SubagentLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Task(Find TODOs)" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{"Prompt:\nFind every TODO", "● Grep(TODO)", "  ⎿ a.go:1\n    b.go:2", "Found 2 TODOs"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+}
+
+func TestTrackSubagents_ProgressEntries(t *testing.T) {
+	m := newSubagentTestMonitor(t)
+	parsed := m.processLines(t, []string{
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_task","name":"Task","input":{"description":"Review","prompt":"Review it"}}]}}`,
+		`{"type":"progress","parentToolUseID":"tu_task","data":{"type":"agent_progress","message":{"type":"assistant","message":{"content":[{"type":"tool_use","id":"r1","name":"Read","input":{"file_path":"a.go"}}]}}}}`,
+		`{"type":"progress","parentToolUseID":"tu_task","data":{"type":"agent_progress","message":{"type":"assistant","message":{"content":[{"type":"tool_use","id":"r2","name":"Read","input":{"file_path":"b.go"}}]}}}}`,
+	})
+	if len(parsed) != 2 || parsed[1].ContentType != "subagent" {
+		t.Fatalf("got %+v", parsed)
+	}
+	if !strings.Contains(parsed[1].Text, "2 tool calls · last: **Read**(b.go)") {
+		t.Errorf("progress text = %q", parsed[1].Text)
+	}
+}
+
+func TestSubagentLog_Unknown(t *testing.T) {
+	m := newSubagentTestMonitor(t)
+	if _, _, err := m.SubagentLog("zz"); err != ErrExpandGone {
+		t.Errorf("err = %v, want ErrExpandGone", err)
+	}
+}
+
+// processLines runs lines through the monitor's parsing steps and returns what would be enqueued.
+func (m *Monitor) processLines(t *testing.T, lines []string) []ParsedEntry {
+	t.Helper()
+	var entries []*Entry
+	for _, l := range lines {
+		e, err := ParseLine([]byte(l))
+		if err != nil {
+			t.Fatalf("ParseLine(%s): %v", l, err)
+		}
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	m.resolveSidechains(entries)
+	return m.trackSubagents(ParseEntries(entries, m.pendingTools))
+}
+
+func TestProcessSession_SubagentEntriesNotSentAsMain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+	os.WriteFile(path, []byte(`{"type":"progress","parentToolUseID":"tu_gone","data":{"type":"agent_progress","message":{"type":"assistant","message":{"content":[{"type":"text","text":"hi"}]}}}}`+"\n"), 0o644)
+
+	m := newSubagentTestMonitor(t)
+	m.processSession("test:@1", "test-session", "@1", path)
+	if len(m.subagents.runs) != 1 {
+		t.Errorf("progress entry should start a run, got %d", len(m.subagents.runs))
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"first\nsecond", 10, "first"},
+		{"abcdef", 3, "abc…"},
+		{"añb", 2, "a…"}, // ñ is 2 bytes: don't split it
+		{"日本語", 4, "日…"},
+	}
+	for _, tt := range tests {
+		got := truncateLine(tt.text, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncateLine(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
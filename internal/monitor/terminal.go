@@ -1,6 +1,8 @@
 package monitor
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
@@ -98,6 +100,7 @@ type UIPattern struct {
 	Name       string
 	TopMarkers []string
 	BotMarkers []string // empty = use last non-empty line
+	SingleLine bool     // the prompt is the last non-empty line and must contain a top marker
 }
 
 // UIContent holds extracted interactive content.
@@ -148,11 +151,26 @@ func IsInteractiveUI(paneText string) bool {
 // ExtractInteractiveContent extracts the interactive UI content from pane text.
 // Returns the UI content and true if found.
 func ExtractInteractiveContent(paneText string) (UIContent, bool) {
-	stripped := StripPaneChrome(paneText)
-	lines := strings.Split(stripped, "\n")
+	return MatchUIPatterns(paneText, uiPatterns)
+}
+
+// ClaudeUIPatterns returns the interactive UI patterns for Claude Code.
+func ClaudeUIPatterns() []UIPattern {
+	return uiPatterns
+}
 
-	for _, pattern := range uiPatterns {
-		content, ok := tryExtract(lines, pattern)
+// MatchUIPatterns extracts interactive UI content using the given patterns.
+// Single-line patterns see the raw pane; the others see it without Claude's chrome.
+func MatchUIPatterns(paneText string, patterns []UIPattern) (UIContent, bool) {
+	raw := strings.Split(paneText, "\n")
+	lines := strings.Split(StripPaneChrome(paneText), "\n")
+
+	for _, pattern := range patterns {
+		src := lines
+		if pattern.SingleLine {
+			src = raw
+		}
+		content, ok := tryExtract(src, pattern)
 		if ok {
 			return content, true
 		}
@@ -161,6 +179,23 @@ func ExtractInteractiveContent(paneText string) (UIContent, bool) {
 }
 
 func tryExtract(lines []string, pattern UIPattern) (UIContent, bool) {
+	if pattern.SingleLine {
+		// Only a prompt still waiting at the bottom counts; answered ones scroll up
+		for i := len(lines) - 1; i >= 0; i-- {
+			line := strings.TrimSpace(lines[i])
+			if line == "" {
+				continue
+			}
+			for _, marker := range pattern.TopMarkers {
+				if strings.Contains(line, marker) {
+					return UIContent{Name: pattern.Name, Content: line}, true
+				}
+			}
+			break
+		}
+		return UIContent{}, false
+	}
+
 	// Find top marker
 	topIdx := -1
 	for i, line := range lines {
@@ -215,6 +250,118 @@ func tryExtract(lines []string, pattern UIPattern) (UIContent, bool) {
 	}, true
 }
 
+// PermissionRequest is the tool call shown in a Claude Code permission prompt.
+type PermissionRequest struct {
+	Tool    string
+	Subject string // command for Bash, file name for edits, URL for fetches
+}
+
+// permissionHeaders maps the header of a permission dialog to its tool.
+var permissionHeaders = map[string]string{
+	"Bash command": "Bash",
+	"Fetch":        "WebFetch",
+}
+
+var (
+	reEditQuestion   = regexp.MustCompile(`^Do you want to make this edit to (.+)\?$`)
+	reCreateQuestion = regexp.MustCompile(`^Do you want to create (.+)\?$`)
+)
+
+// ParsePermissionPrompt reads the tool and command/path from a permission
+// dialog. For Bash the dialog shows the command, then Claude's description if
+// it gave one; a body of more than one line can't be split into the two
+// reliably, so it isn't reported.
+func ParsePermissionPrompt(paneText string) (PermissionRequest, bool) {
+	var lines []string
+	for _, line := range strings.Split(StripPaneChrome(paneText), "\n") {
+		lines = append(lines, strings.TrimSpace(strings.Trim(line, "│╭╮╰╯─ ")))
+	}
+
+	q := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "Do you want to") {
+			q = i
+			break
+		}
+	}
+	if q < 0 {
+		return PermissionRequest{}, false
+	}
+
+	if m := reEditQuestion.FindStringSubmatch(lines[q]); m != nil {
+		return PermissionRequest{Tool: "Edit", Subject: m[1]}, true
+	}
+	if m := reCreateQuestion.FindStringSubmatch(lines[q]); m != nil {
+		return PermissionRequest{Tool: "Write", Subject: m[1]}, true
+	}
+
+	for h := q - 1; h >= 0; h-- {
+		tool, ok := permissionHeaders[lines[h]]
+		if !ok {
+			continue
+		}
+		var body []string
+		for _, line := range lines[h+1 : q] {
+			if line != "" {
+				body = append(body, line)
+			}
+		}
+		if len(body) == 0 || (tool == "Bash" && len(body) > 1) {
+			return PermissionRequest{}, false
+		}
+		return PermissionRequest{Tool: tool, Subject: strings.Join(body, "\n")}, true
+	}
+	return PermissionRequest{}, false
+}
+
+// reDialogOption matches a numbered option of an interactive dialog, with the
+// cursor (❯) on the selected one.
+var reDialogOption = regexp.MustCompile(`^(❯\s*)?(\d+)\.\s+(.+)$`)
+
+// ParseDialogOptions reads the last list of numbered options on the pane
+// ("❯ 1. Yes", "  2. No") and returns their labels in order with the index of
+// the one under the cursor. Returns false if there is no such list.
+func ParseDialogOptions(paneText string) (options []string, cursor int, ok bool) {
+	lines := strings.Split(paneText, "\n")
+	end := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if reDialogOption.MatchString(strings.TrimSpace(strings.Trim(lines[i], "│ "))) {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, 0, false
+	}
+
+	// Walk up through the list; option descriptions may sit between options
+	start := end
+	for i := end; i >= 0; i-- {
+		line := strings.TrimSpace(strings.Trim(lines[i], "│ "))
+		if m := reDialogOption.FindStringSubmatch(line); m != nil {
+			start = i
+			if m[2] == "1" {
+				break
+			}
+		}
+	}
+
+	for _, line := range lines[start : end+1] {
+		m := reDialogOption.FindStringSubmatch(strings.TrimSpace(strings.Trim(line, "│ ")))
+		if m == nil {
+			continue
+		}
+		if n, _ := strconv.Atoi(m[2]); n != len(options)+1 {
+			return nil, 0, false
+		}
+		if m[1] != "" {
+			cursor = len(options)
+		}
+		options = append(options, strings.TrimSpace(m[3]))
+	}
+	return options, cursor, true
+}
+
 // ExtractBashOutput extracts ! command output from a captured tmux pane.
 // Searches from the bottom for the "! <command>" echo line, then returns
 // that line and everything below it. Returns empty string if not found.
@@ -257,6 +404,29 @@ func ExtractBashOutput(paneText, command string) string {
 	return strings.Join(output, "\n")
 }
 
+// PaneDelta returns the lines of cur that were not on screen in prev, assuming
+// the terminal only scrolls upward between captures. If the two captures share
+// no lines (e.g. the screen was cleared), all of cur is new.
+func PaneDelta(prev, cur []string) []string {
+	for shift := 0; shift < len(prev); shift++ {
+		overlap := prev[shift:]
+		if len(overlap) > len(cur) {
+			continue
+		}
+		match := true
+		for i, line := range overlap {
+			if cur[i] != line {
+				match = false
+				break
+			}
+		}
+		if match {
+			return cur[len(overlap):]
+		}
+	}
+	return cur
+}
+
 // ShortenSeparators replaces long ─ lines with a shorter version for display.
 func ShortenSeparators(text string) string {
 	lines := strings.Split(text, "\n")
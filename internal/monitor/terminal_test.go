@@ -387,3 +387,139 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestMatchUIPatterns_SingleLine(t *testing.T) {
+	patterns := []UIPattern{{Name: "Confirm", TopMarkers: []string{"(Y)es/(N)o"}, SingleLine: true}}
+
+	waiting := "Add main.go to the chat? (Y)es/(N)o [Yes]: \n\n"
+	ui, ok := MatchUIPatterns(waiting, patterns)
+	if !ok {
+		t.Fatal("should detect waiting confirm prompt")
+	}
+	if ui.Name != "Confirm" || ui.Content != "Add main.go to the chat? (Y)es/(N)o [Yes]:" {
+		t.Errorf("got %q %q", ui.Name, ui.Content)
+	}
+
+	rule := strings.Repeat("─", 40)
+	if _, ok := MatchUIPatterns(rule+"\n> add main.go\n"+waiting, patterns); !ok {
+		t.Error("should detect confirm prompt below a rule line")
+	}
+
+	answered := "Add main.go to the chat? (Y)es/(N)o [Yes]: y\nAdded main.go\n> "
+	if _, ok := MatchUIPatterns(answered, patterns); ok {
+		t.Error("answered prompt should not match")
+	}
+}
+
+func TestPaneDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur []string
+		want      []string
+	}{
+		{"first capture", nil, []string{"a", "b"}, []string{"a", "b"}},
+		{"appended", []string{"a", "b"}, []string{"a", "b", "c"}, []string{"c"}},
+		{"scrolled", []string{"a", "b", "c"}, []string{"b", "c", "d", "e"}, []string{"d", "e"}},
+		{"unchanged", []string{"a", "b"}, []string{"a", "b"}, []string{}},
+		{"cleared", []string{"a", "b"}, []string{"x", "y"}, []string{"x", "y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PaneDelta(tt.prev, tt.cur)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") || len(got) != len(tt.want) {
+				t.Errorf("PaneDelta = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePermissionPrompt(t *testing.T) {
+	box := func(lines ...string) string {
+		var sb strings.Builder
+		sb.WriteString("╭" + strings.Repeat("─", 30) + "╮\n")
+		for _, l := range lines {
+			sb.WriteString("│ " + l + " │\n")
+		}
+		sb.WriteString("╰" + strings.Repeat("─", 30) + "╯\n")
+		return sb.String()
+	}
+
+	tests := []struct {
+		name string
+		pane string
+		want PermissionRequest
+		ok   bool
+	}{
+		{
+			"bash without description",
+			box("Bash command", "", "  git status", "", "Do you want to proceed?", "❯ 1. Yes", "  2. No", "Esc to cancel"),
+			PermissionRequest{Tool: "Bash", Subject: "git status"}, true,
+		},
+		{
+			"bash with description is ambiguous",
+			box("Bash command", "  go test ./...", "  Run the test suite", "Do you want to proceed?", "❯ 1. Yes"),
+			PermissionRequest{}, false,
+		},
+		{
+			"two-line bash is ambiguous",
+			box("Bash command", "  go test ./...", "  rm -rf ~", "Do you want to proceed?", "❯ 1. Yes"),
+			PermissionRequest{}, false,
+		},
+		{
+			"multi-line bash",
+			box("Bash command", "  cd build", "  make all", "  Build it", "Do you want to proceed?"),
+			PermissionRequest{}, false,
+		},
+		{
+			"edit",
+			box("Edit file", "  diff...", "Do you want to make this edit to main.go?", "❯ 1. Yes"),
+			PermissionRequest{Tool: "Edit", Subject: "main.go"}, true,
+		},
+		{
+			"create",
+			box("Create file", "Do you want to create notes.md?"),
+			PermissionRequest{Tool: "Write", Subject: "notes.md"}, true,
+		},
+		{
+			"unknown dialog",
+			box("Something else", "Do you want to proceed?"),
+			PermissionRequest{}, false,
+		},
+		{"no prompt", "just output\n", PermissionRequest{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParsePermissionPrompt(tt.pane)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParsePermissionPrompt = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseDialogOptions(t *testing.T) {
+	plan := "Here is Claude's plan:\n1. Add the flag\n2. Test it\n\n" +
+		"Would you like to proceed?\n\n" +
+		"❯ 1. Yes, and bypass permissions\n" +
+		"  2. Yes, and auto-accept edits\n" +
+		"  3. Yes, and manually approve edits\n" +
+		"  4. No, keep planning\n"
+	opts, cursor, ok := ParseDialogOptions(plan)
+	want := []string{"Yes, and bypass permissions", "Yes, and auto-accept edits", "Yes, and manually approve edits", "No, keep planning"}
+	if !ok || cursor != 0 || strings.Join(opts, "|") != strings.Join(want, "|") {
+		t.Errorf("ParseDialogOptions = %q, %d, %v", opts, cursor, ok)
+	}
+
+	boxed := "│ Do you want to proceed? │\n│   1. Yes │\n│     Runs the thing │\n│ ❯ 2. No │\n"
+	opts, cursor, ok = ParseDialogOptions(boxed)
+	if !ok || cursor != 1 || len(opts) != 2 || opts[1] != "No" {
+		t.Errorf("boxed = %q, %d, %v", opts, cursor, ok)
+	}
+
+	if _, _, ok := ParseDialogOptions("just output\n> "); ok {
+		t.Error("no options on the pane")
+	}
+	if _, _, ok := ParseDialogOptions("  2. orphan\n  3. list\n"); ok {
+		t.Error("a list not starting at 1 is not a dialog")
+	}
+}
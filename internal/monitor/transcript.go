@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"regexp"
 	"strings"
@@ -10,18 +11,52 @@ import (
 type Entry struct {
 	Type    string         // "user", "assistant", "summary"
 	Blocks  []ContentBlock // parsed content blocks
+	Usage   *Usage         // assistant entries: token usage of the API call
+	Offset  int64          // byte offset of the entry's line in its transcript (set by the monitor)
 	RawData json.RawMessage
+
+	// Subagent (Task tool) entries. Claude Code writes them either inline as
+	// sidechain entries chained by UUID, or as progress entries naming the
+	// Task call directly.
+	Sidechain       bool
+	UUID            string
+	ParentUUID      string
+	ParentToolUseID string // the Task tool_use this entry belongs to, once known
+
+	// ToolResult is a user entry's toolUseResult: Claude Code's structured
+	// result of the tool call answered in the entry.
+	ToolResult json.RawMessage
+}
+
+// Usage is the token usage reported for one assistant API response.
+// Claude Code writes one entry per content block of a response, all sharing
+// MessageID and usage counts, so consumers must dedupe by MessageID.
+type Usage struct {
+	MessageID  string
+	Model      string
+	Input      int64
+	Output     int64
+	CacheWrite int64
+	CacheRead  int64
 }
 
 // ContentBlock represents a single content block within an entry.
 type ContentBlock struct {
-	Type      string // "text", "tool_use", "tool_result", "thinking"
-	Text      string // for text/thinking blocks
-	ToolName  string // for tool_use
-	ToolInput string // for tool_use (summary of input)
-	ToolUseID string // for tool_use and tool_result
-	Content   string // for tool_result
-	IsError   bool   // for tool_result
+	Type      string          // "text", "tool_use", "tool_result", "thinking"
+	Text      string          // for text/thinking blocks
+	ToolName  string          // for tool_use
+	ToolInput string          // for tool_use (summary of input)
+	ToolUseID string          // for tool_use and tool_result
+	RawInput  json.RawMessage // for tool_use of structuredInputTools: the full input
+	Content   string          // for tool_result
+	IsError   bool            // for tool_result
+	Images    []Image         // for image blocks, and images returned in a tool_result
+}
+
+// Image is a decoded base64 image block.
+type Image struct {
+	MediaType string // e.g. "image/png"
+	Data      []byte
 }
 
 // PendingTool tracks a tool_use block awaiting its tool_result.
@@ -30,6 +65,7 @@ type PendingTool struct {
 	ToolName  string
 	Input     string
 	Summary   string
+	RawInput  json.RawMessage
 }
 
 // Regex patterns for filtering system content from user text.
@@ -38,7 +74,16 @@ var reCommandName = regexp.MustCompile(`<command-name>(.*?)</command-name>`)
 
 // ParseLine parses a single JSONL line into an Entry.
 // Returns nil for unrecognized or ignorable entries.
+// Lines larger than oversizedLineBytes are parsed in degraded form (see degradeEntry).
 func ParseLine(line []byte) (*Entry, error) {
+	entry, err := parseLine(line)
+	if entry != nil && len(line) > oversizedLineBytes {
+		degradeEntry(entry)
+	}
+	return entry, err
+}
+
+func parseLine(line []byte) (*Entry, error) {
 	var raw map[string]json.RawMessage
 	if err := json.Unmarshal(line, &raw); err != nil {
 		return nil, err
@@ -56,7 +101,13 @@ func ParseLine(line []byte) (*Entry, error) {
 
 	switch entryType {
 	case "user", "assistant":
-		return parseMessageEntry(entryType, raw)
+		entry, err := parseMessageEntry(entryType, raw)
+		if entry != nil {
+			parseThreadFields(entry, raw)
+		}
+		return entry, err
+	case "progress":
+		return parseProgressEntry(raw)
 	case "summary":
 		return parseSummaryEntry(raw)
 	default:
@@ -64,6 +115,46 @@ func ParseLine(line []byte) (*Entry, error) {
 	}
 }
 
+// parseThreadFields reads the fields tying a subagent entry to its Task call.
+// parent_tool_use_id is the SDK's stream-json name for it.
+func parseThreadFields(entry *Entry, raw map[string]json.RawMessage) {
+	var sidechain bool
+	json.Unmarshal(raw["isSidechain"], &sidechain)
+	entry.Sidechain = sidechain
+	entry.UUID = jsonString(raw["uuid"])
+	entry.ParentUUID = jsonString(raw["parentUuid"])
+	if parent := jsonString(raw["parent_tool_use_id"]); parent != "" {
+		entry.ParentToolUseID = parent
+		entry.Sidechain = true
+	}
+}
+
+// parseProgressEntry parses an agent_progress entry, which wraps one message
+// of a running subagent. Other progress entries are ignored.
+func parseProgressEntry(raw map[string]json.RawMessage) (*Entry, error) {
+	var data struct {
+		Type    string                     `json:"type"`
+		Message map[string]json.RawMessage `json:"message"`
+	}
+	if err := json.Unmarshal(raw["data"], &data); err != nil || data.Type != "agent_progress" {
+		return nil, nil
+	}
+	parent := jsonString(raw["parentToolUseID"])
+	msgType := jsonString(data.Message["type"])
+	if parent == "" || (msgType != "user" && msgType != "assistant") {
+		return nil, nil
+	}
+
+	entry, err := parseMessageEntry(msgType, data.Message)
+	if entry == nil {
+		return nil, err
+	}
+	entry.Sidechain = true
+	entry.ParentToolUseID = parent
+	entry.UUID = jsonString(data.Message["uuid"])
+	return entry, err
+}
+
 func parseMessageEntry(entryType string, raw map[string]json.RawMessage) (*Entry, error) {
 	msgBytes, ok := raw["message"]
 	if !ok {
@@ -71,7 +162,15 @@ func parseMessageEntry(entryType string, raw map[string]json.RawMessage) (*Entry
 	}
 
 	var msg struct {
+		ID      string          `json:"id"`
+		Model   string          `json:"model"`
 		Content json.RawMessage `json:"content"`
+		Usage   *struct {
+			InputTokens              int64 `json:"input_tokens"`
+			OutputTokens             int64 `json:"output_tokens"`
+			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		} `json:"usage"`
 	}
 	if err := json.Unmarshal(msgBytes, &msg); err != nil {
 		return &Entry{Type: entryType}, nil
@@ -80,11 +179,25 @@ func parseMessageEntry(entryType string, raw map[string]json.RawMessage) (*Entry
 	blocks := parseContentBlocks(msg.Content)
 
 	rawData, _ := json.Marshal(raw)
-	return &Entry{
+	entry := &Entry{
 		Type:    entryType,
 		Blocks:  blocks,
 		RawData: rawData,
-	}, nil
+	}
+	if entryType == "user" {
+		entry.ToolResult = raw["toolUseResult"]
+	}
+	if entryType == "assistant" && msg.Usage != nil {
+		entry.Usage = &Usage{
+			MessageID:  msg.ID,
+			Model:      msg.Model,
+			Input:      msg.Usage.InputTokens,
+			Output:     msg.Usage.OutputTokens,
+			CacheWrite: msg.Usage.CacheCreationInputTokens,
+			CacheRead:  msg.Usage.CacheReadInputTokens,
+		}
+	}
+	return entry, nil
 }
 
 func parseSummaryEntry(raw map[string]json.RawMessage) (*Entry, error) {
@@ -134,11 +247,41 @@ func parseContentBlocks(contentJSON json.RawMessage) []ContentBlock {
 			result = append(result, parseToolResultBlock(blockJSON))
 		case "thinking":
 			result = append(result, parseThinkingBlock(blockJSON))
+		case "image":
+			if img, ok := parseImageBlock(blockJSON); ok {
+				result = append(result, ContentBlock{Type: "image", Images: []Image{img}})
+			}
 		}
 	}
 	return result
 }
 
+// maxImageBytes is the largest decoded image kept; Telegram takes photos up to 10 MB.
+const maxImageBytes = 10 * 1024 * 1024
+
+// parseImageBlock decodes an image block with a base64 source. URL sources
+// and oversized images are skipped.
+func parseImageBlock(data json.RawMessage) (Image, bool) {
+	var block struct {
+		Source struct {
+			Type      string `json:"type"`
+			MediaType string `json:"media_type"`
+			Data      string `json:"data"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(data, &block); err != nil || block.Source.Type != "base64" {
+		return Image{}, false
+	}
+	if base64.StdEncoding.DecodedLen(len(block.Source.Data)) > maxImageBytes {
+		return Image{}, false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(block.Source.Data)
+	if err != nil || len(decoded) == 0 {
+		return Image{}, false
+	}
+	return Image{MediaType: block.Source.MediaType, Data: decoded}, true
+}
+
 func parseTextBlock(data json.RawMessage) ContentBlock {
 	var block struct {
 		Text string `json:"text"`
@@ -160,12 +303,24 @@ func parseToolUseBlock(data json.RawMessage) ContentBlock {
 
 	input := extractToolInput(block.Name, block.Input)
 
-	return ContentBlock{
+	cb := ContentBlock{
 		Type:      "tool_use",
 		ToolName:  block.Name,
 		ToolInput: input,
 		ToolUseID: block.ID,
 	}
+	if structuredInputTools[block.Name] {
+		cb.RawInput = block.Input
+	}
+	return cb
+}
+
+// structuredInputTools are tools whose full input is kept for native rendering
+// (question options, plan markdown, todo checklist, file diffs).
+var structuredInputTools = map[string]bool{
+	"AskUserQuestion": true, "ExitPlanMode": true, "TodoWrite": true,
+	"Edit": true, "MultiEdit": true, "Write": true,
+	"Task": true, // prompt, to tie inline sidechain entries to their call
 }
 
 func parseToolResultBlock(data json.RawMessage) ContentBlock {
@@ -176,13 +331,14 @@ func parseToolResultBlock(data json.RawMessage) ContentBlock {
 	}
 	json.Unmarshal(data, &block)
 
-	content := extractToolResultText(block.Content)
+	content, images := extractToolResultText(block.Content)
 
 	return ContentBlock{
 		Type:      "tool_result",
 		ToolUseID: block.ToolUseID,
 		Content:   content,
 		IsError:   block.IsError,
+		Images:    images,
 	}
 }
 
@@ -242,25 +398,26 @@ func extractToolInput(toolName string, inputJSON json.RawMessage) string {
 	}
 }
 
-// extractToolResultText extracts text from tool_result content.
-func extractToolResultText(contentJSON json.RawMessage) string {
+// extractToolResultText extracts text and images from tool_result content.
+func extractToolResultText(contentJSON json.RawMessage) (string, []Image) {
 	if contentJSON == nil {
-		return ""
+		return "", nil
 	}
 
 	// Try as string
 	var text string
 	if err := json.Unmarshal(contentJSON, &text); err == nil {
-		return text
+		return text, nil
 	}
 
 	// Try as array of content blocks
 	var blocks []json.RawMessage
 	if err := json.Unmarshal(contentJSON, &blocks); err != nil {
-		return ""
+		return "", nil
 	}
 
 	var parts []string
+	var images []Image
 	for _, blockJSON := range blocks {
 		var block struct {
 			Type string `json:"type"`
@@ -272,8 +429,13 @@ func extractToolResultText(contentJSON json.RawMessage) string {
 		if block.Type == "text" && block.Text != "" {
 			parts = append(parts, block.Text)
 		}
+		if block.Type == "image" {
+			if img, ok := parseImageBlock(blockJSON); ok {
+				images = append(images, img)
+			}
+		}
 	}
-	return strings.Join(parts, "\n")
+	return strings.Join(parts, "\n"), images
 }
 
 // ParseEntries processes a list of entries with tool pairing.
@@ -291,7 +453,12 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 			continue
 		}
 
-		for _, block := range entry.Blocks {
+		if entry.Sidechain {
+			result = append(result, parseSubagentEntry(entry)...)
+			continue
+		}
+
+		for bi, block := range entry.Blocks {
 			switch block.Type {
 			case "text":
 				text := cleanText(block.Text)
@@ -310,6 +477,7 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 					ToolName:  block.ToolName,
 					Input:     block.ToolInput,
 					Summary:   summary,
+					RawInput:  block.RawInput,
 				}
 				idx := len(result)
 				result = append(result, ParsedEntry{
@@ -318,6 +486,7 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 					Text:        summary,
 					ToolUseID:   block.ToolUseID,
 					ToolName:    block.ToolName,
+					RawInput:    block.RawInput,
 				})
 				batchToolUseIdx[block.ToolUseID] = idx
 
@@ -326,12 +495,18 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 					Role:        "user",
 					ContentType: "tool_result",
 					ToolUseID:   block.ToolUseID,
+					Offset:      entry.Offset,
+					Block:       bi,
 				}
 
 				if pt, ok := pending[block.ToolUseID]; ok {
 					pe.ToolName = pt.ToolName
 					pe.ToolInput = pt.Input
+					pe.RawInput = pt.RawInput
 					pe.Text = block.Content
+					if pt.ToolName == "Write" {
+						pe.RawResult = entry.ToolResult
+					}
 					delete(pending, block.ToolUseID)
 				} else {
 					// No matching tool_use (e.g. after restart) — send with generic label
@@ -345,9 +520,25 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 				if idx, ok := batchToolUseIdx[block.ToolUseID]; ok {
 					result[idx].ContentType = "" // mark for removal
 					delete(batchToolUseIdx, block.ToolUseID)
+					pe.FoldedToolUse = true
 				}
 
 				result = append(result, pe)
+				if len(block.Images) > 0 {
+					result = append(result, ParsedEntry{
+						Role:        "user",
+						ContentType: "image",
+						ToolName:    pe.ToolName,
+						Images:      block.Images,
+					})
+				}
+
+			case "image":
+				result = append(result, ParsedEntry{
+					Role:        entry.Type,
+					ContentType: "image",
+					Images:      block.Images,
+				})
 
 			case "thinking":
 				if block.Text != "" {
@@ -355,6 +546,8 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 						Role:        "assistant",
 						ContentType: "thinking",
 						Text:        block.Text,
+						Offset:      entry.Offset,
+						Block:       bi,
 					})
 				}
 			}
@@ -371,15 +564,70 @@ func ParseEntries(entries []*Entry, pending map[string]PendingTool) []ParsedEntr
 	return filtered
 }
 
+// parseSubagentEntry converts a sidechain entry into entries grouped under
+// its Task call by ParentToolUseID. They're kept out of tool pairing: the
+// monitor folds them into the Task's progress instead of sending them.
+// Entries not (yet) tied to a Task call are dropped.
+func parseSubagentEntry(entry *Entry) []ParsedEntry {
+	if entry.ParentToolUseID == "" {
+		return nil
+	}
+	var result []ParsedEntry
+	for _, block := range entry.Blocks {
+		pe := ParsedEntry{
+			Role:            entry.Type,
+			ContentType:     block.Type,
+			ToolUseID:       block.ToolUseID,
+			ToolName:        block.ToolName,
+			ToolInput:       block.ToolInput,
+			IsError:         block.IsError,
+			ParentToolUseID: entry.ParentToolUseID,
+		}
+		switch block.Type {
+		case "text":
+			pe.Text = cleanText(block.Text)
+		case "tool_use":
+			pe.Text = FormatToolUseSummary(block.ToolName, block.ToolInput)
+		case "tool_result":
+			pe.Text = block.Content
+		default:
+			continue
+		}
+		if pe.Text != "" {
+			result = append(result, pe)
+		}
+	}
+	return result
+}
+
 // ParsedEntry is a display-ready parsed entry for the message queue.
 type ParsedEntry struct {
 	Role        string // "user", "assistant"
-	ContentType string // "text", "tool_use", "tool_result", "thinking"
+	ContentType string // "text", "tool_use", "tool_result", "thinking", "image"
 	Text        string
 	ToolUseID   string
 	ToolName    string
-	ToolInput   string // tool input summary (for tool_result combined display)
+	ToolInput   string          // tool input summary (for tool_result combined display)
+	RawInput    json.RawMessage // full tool input (structuredInputTools only), on tool_use and paired tool_result
 	IsError     bool
+	// FoldedToolUse marks a tool_result whose tool_use arrived in the same
+	// batch and was suppressed in its favour.
+	FoldedToolUse bool
+	// Offset and Block locate a tool_result or thinking block in the transcript.
+	Offset int64
+	Block  int
+	// ExpandID, if set, is the ref of the block's full text (see Monitor.Expanded).
+	ExpandID string
+	// ParentToolUseID groups a subagent's entries under its Task tool_use.
+	ParentToolUseID string
+	// SubagentLog, if set, is the ref of a Task call's subagent log (see Monitor.SubagentLog).
+	SubagentLog string
+	// Images of an "image" entry: an image block, or those returned by a tool.
+	Images []Image
+	// SendFile, if set, is the ref of a file written by the tool (see Monitor.WrittenFile).
+	SendFile string
+	// RawResult is a Write result's toolUseResult, with the patch of an overwrite.
+	RawResult json.RawMessage
 }
 
 // FormatToolUseSummary formats a tool_use into a summary line.
@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -230,6 +231,9 @@ func TestToolPairing_SameBatch(t *testing.T) {
 	if results[0].ToolName != "Read" {
 		t.Errorf("result 0 tool = %q, want Read", results[0].ToolName)
 	}
+	if !results[0].FoldedToolUse {
+		t.Error("result 0 should be marked as carrying its tool_use")
+	}
 
 	// Pending should be empty
 	if len(pending) != 0 {
@@ -261,6 +265,9 @@ func TestToolPairing_CrossCycle(t *testing.T) {
 	if results[0].ToolName != "Bash" {
 		t.Errorf("tool = %q, want Bash", results[0].ToolName)
 	}
+	if results[0].FoldedToolUse {
+		t.Error("the tool_use was emitted in the previous cycle")
+	}
 	if len(pending) != 0 {
 		t.Errorf("pending should be empty after pairing")
 	}
@@ -330,3 +337,154 @@ func TestToolResultContent_Array(t *testing.T) {
 		t.Errorf("content = %q, want 'line1\\nline2'", entry.Blocks[0].Content)
 	}
 }
+
+func TestParseLine_OversizedDegraded(t *testing.T) {
+	huge := strings.Repeat("B", 2*1024*1024)
+	line := []byte(`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t9","is_error":true,"content":"` + huge + `"}]}}`)
+
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entry.Blocks) != 1 {
+		t.Fatalf("blocks = %d, want 1", len(entry.Blocks))
+	}
+	b := entry.Blocks[0]
+	if b.ToolUseID != "t9" || !b.IsError {
+		t.Errorf("metadata lost: id=%q isError=%v", b.ToolUseID, b.IsError)
+	}
+	if len(b.Content) > maxDegradedBlockBytes+64 {
+		t.Errorf("content not degraded: %d bytes", len(b.Content))
+	}
+	if entry.RawData != nil {
+		t.Error("raw data should be dropped for oversized lines")
+	}
+}
+
+func TestParseEntries_StructuredInput(t *testing.T) {
+	line := []byte(`{"type":"assistant","message":{"content":[` +
+		`{"type":"tool_use","id":"tu_q","name":"AskUserQuestion","input":{"questions":[{"question":"Which DB?","options":[{"label":"Postgres"}]}]}},` +
+		`{"type":"tool_use","id":"tu_r","name":"Read","input":{"file_path":"main.go"}}]}}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := ParseEntries([]*Entry{entry}, make(map[string]PendingTool))
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !strings.Contains(string(results[0].RawInput), `"Which DB?"`) {
+		t.Errorf("AskUserQuestion should keep its raw input, got %s", results[0].RawInput)
+	}
+	if results[1].RawInput != nil {
+		t.Errorf("Read should not keep raw input, got %s", results[1].RawInput)
+	}
+}
+
+func TestToolPairing_TodoWriteKeepsInput(t *testing.T) {
+	pending := make(map[string]PendingTool)
+	use, _ := ParseLine([]byte(`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tu_t","name":"TodoWrite","input":{"todos":[{"content":"a","status":"pending"}]}}]}}`))
+	result, _ := ParseLine([]byte(`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"tu_t","content":"Todos have been modified successfully"}]}}`))
+
+	results := ParseEntries([]*Entry{use, result}, pending)
+	if len(results) != 1 || results[0].ContentType != "tool_result" {
+		t.Fatalf("expected the paired tool_result only, got %+v", results)
+	}
+	if !strings.Contains(string(results[0].RawInput), `"todos"`) {
+		t.Errorf("paired tool_result should carry the TodoWrite input, got %s", results[0].RawInput)
+	}
+}
+
+func TestParseLine_AssistantUsage(t *testing.T) {
+	line := []byte(`{"type":"assistant","message":{"id":"msg_1","model":"claude-sonnet-4-5","content":[{"type":"text","text":"hi"}],` +
+		`"usage":{"input_tokens":12,"output_tokens":340,"cache_creation_input_tokens":5000,"cache_read_input_tokens":20000}}}`)
+	entry, err := ParseLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Usage{MessageID: "msg_1", Model: "claude-sonnet-4-5", Input: 12, Output: 340, CacheWrite: 5000, CacheRead: 20000}
+	if entry.Usage == nil || *entry.Usage != want {
+		t.Errorf("usage = %+v, want %+v", entry.Usage, want)
+	}
+
+	user, _ := ParseLine([]byte(`{"type":"user","message":{"content":"hi","usage":{"input_tokens":1}}}`))
+	if user.Usage != nil {
+		t.Error("only assistant entries carry usage")
+	}
+}
+
+func TestParseLine_AgentProgress(t *testing.T) {
+	line := []byte(`{"type":"progress","parentToolUseID":"tu_task","toolUseID":"agent_1","data":{"type":"agent_progress","agentId":"a1",` +
+		`"message":{"type":"assistant","uuid":"u1","message":{"content":[{"type":"tool_use","id":"tu_sub","name":"Grep","input":{"pattern":"TODO"}}]}}}}`)
+	entry, err := ParseLine(line)
+	if err != nil || entry == nil {
+		t.Fatalf("ParseLine = %v, %v", entry, err)
+	}
+	if !entry.Sidechain || entry.ParentToolUseID != "tu_task" || entry.Type != "assistant" || entry.UUID != "u1" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	other, _ := ParseLine([]byte(`{"type":"progress","parentToolUseID":"tu_x","data":{"type":"bash_progress","output":"..."}}`))
+	if other != nil {
+		t.Errorf("non-agent progress should be ignored, got %+v", other)
+	}
+}
+
+func TestParseEntries_GroupsSidechain(t *testing.T) {
+	resolved, _ := ParseLine([]byte(`{"type":"assistant","isSidechain":true,"uuid":"u2","parentUuid":"u1","message":{"content":[` +
+		`{"type":"text","text":"Looking around"},{"type":"tool_use","id":"tu_sub","name":"Read","input":{"file_path":"a.go"}}]}}`))
+	resolved.ParentToolUseID = "tu_task"
+	unresolved, _ := ParseLine([]byte(`{"type":"assistant","isSidechain":true,"uuid":"u9","parentUuid":"u8","message":{"content":[{"type":"text","text":"lost"}]}}`))
+
+	pending := make(map[string]PendingTool)
+	results := ParseEntries([]*Entry{resolved, unresolved}, pending)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 grouped entries, got %+v", results)
+	}
+	for _, r := range results {
+		if r.ParentToolUseID != "tu_task" {
+			t.Errorf("entry not grouped under its Task call: %+v", r)
+		}
+	}
+	if results[1].ContentType != "tool_use" || results[1].Text != "**Read**(a.go)" {
+		t.Errorf("tool_use = %+v", results[1])
+	}
+	if len(pending) != 0 {
+		t.Error("subagent tool calls should stay out of the main pairing")
+	}
+}
+
+func TestParseEntries_Images(t *testing.T) {
+	png := "iVBORw0KGgo=" // PNG signature
+	lines := []string{
+		`{"type":"user","message":{"content":[{"type":"text","text":"look"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + png + `"}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"s1","name":"mcp__browser__screenshot","input":{}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"s1","content":[{"type":"text","text":"done"},{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"` + png + `"}}]}]}}`,
+		`{"type":"user","message":{"content":[{"type":"image","source":{"type":"url","url":"https://example.com/x.png"}}]}}`,
+	}
+	var entries []*Entry
+	for _, l := range lines {
+		e, err := ParseLine([]byte(l))
+		if err != nil {
+			t.Fatalf("ParseLine: %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	var images []ParsedEntry
+	for _, pe := range ParseEntries(entries, make(map[string]PendingTool)) {
+		if pe.ContentType == "image" {
+			images = append(images, pe)
+		}
+	}
+	if len(images) != 2 {
+		t.Fatalf("got %d image entries, want 2 (url sources are skipped)", len(images))
+	}
+	if img := images[0].Images[0]; img.MediaType != "image/png" || string(img.Data[:4]) != "\x89PNG" {
+		t.Errorf("image block = %q, %q", img.MediaType, img.Data)
+	}
+	if images[1].ToolName != "mcp__browser__screenshot" || images[1].Images[0].MediaType != "image/jpeg" {
+		t.Errorf("tool result image = %+v", images[1])
+	}
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import "github.com/otaviocarvalho/tramuntana/internal/state"
+
+// applyVerbosity filters an entry for a verbosity level (see state.Verbosity*).
+// Returns false if the entry isn't shown. At normal level a successful
+// tool_result is reduced to its tool_use summary, which is only sent when the
+// tool_use itself was folded into the result.
+func applyVerbosity(level string, pe ParsedEntry) (ParsedEntry, bool) {
+	switch level {
+	case state.VerbositySilent:
+		return pe, false
+	case state.VerbosityQuiet:
+		switch pe.ContentType {
+		case "text":
+			return pe, pe.Role == "assistant"
+		case "tool_result":
+			return pe, pe.IsError
+		}
+		return pe, false
+	case state.VerbosityNormal:
+		switch pe.ContentType {
+		case "thinking":
+			return pe, false
+		case "tool_result":
+			if pe.IsError {
+				return pe, true
+			}
+			if !pe.FoldedToolUse {
+				return pe, false // the tool_use summary is already in the topic
+			}
+			pe.ContentType = "tool_use"
+			pe.Text = FormatToolUseSummary(pe.ToolName, pe.ToolInput)
+			pe.ToolUseID = ""
+			pe.ExpandID = ""
+			return pe, true
+		}
+	}
+	return pe, true
+}
+
+// showsTodo reports whether a level shows the pinned todo checklist.
+func showsTodo(level string) bool {
+	return level == state.VerbosityFull || level == state.VerbosityNormal
+}
@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/otaviocarvalho/tramuntana/internal/state"
+)
+
+func TestApplyVerbosity(t *testing.T) {
+	userText := ParsedEntry{Role: "user", ContentType: "text", Text: "fix it"}
+	reply := ParsedEntry{Role: "assistant", ContentType: "text", Text: "done"}
+	thinking := ParsedEntry{Role: "assistant", ContentType: "thinking", Text: "hmm"}
+	toolUse := ParsedEntry{ContentType: "tool_use", ToolUseID: "t1", ToolName: "Bash", Text: "**Bash**(ls)"}
+	result := ParsedEntry{ContentType: "tool_result", ToolUseID: "t1", ToolName: "Bash", ToolInput: "ls", Text: "a\nb"}
+	folded := result
+	folded.FoldedToolUse = true
+	failed := result
+	failed.IsError = true
+
+	tests := []struct {
+		level string
+		shown []bool // userText, reply, thinking, toolUse, result, folded, failed
+	}{
+		{state.VerbosityFull, []bool{true, true, true, true, true, true, true}},
+		{state.VerbosityNormal, []bool{true, true, false, true, false, true, true}},
+		{state.VerbosityQuiet, []bool{false, true, false, false, false, false, true}},
+		{state.VerbositySilent, []bool{false, false, false, false, false, false, false}},
+	}
+	entries := []ParsedEntry{userText, reply, thinking, toolUse, result, folded, failed}
+	for _, tt := range tests {
+		for i, pe := range entries {
+			if _, ok := applyVerbosity(tt.level, pe); ok != tt.shown[i] {
+				t.Errorf("%s: entry %d (%s) shown = %v, want %v", tt.level, i, pe.ContentType, ok, tt.shown[i])
+			}
+		}
+	}
+
+	got, _ := applyVerbosity(state.VerbosityNormal, folded)
+	if got.ContentType != "tool_use" || got.Text != "**Bash**(ls)" || got.ToolUseID != "" {
+		t.Errorf("folded result at normal = %+v, want the bare tool summary", got)
+	}
+}
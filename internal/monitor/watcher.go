@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchReconcileInterval is how often the monitor does a full poll while the
+// file watcher is healthy. Events drive normal delivery; the poll only catches
+// anything the watcher missed (e.g. inotify queue overflow).
+const watchReconcileInterval = 30 * time.Second
+
+// eventDebounce coalesces bursts of writes to the same transcript into one read.
+const eventDebounce = 100 * time.Millisecond
+
+// eventMaxWait bounds the debounce: a steady stream of writes is still read
+// at most this long after the first unread one.
+const eventMaxWait = 500 * time.Millisecond
+
+// debounceDelay returns how long to wait before reading dirty transcripts,
+// given when the first still-unread write was seen.
+func debounceDelay(firstDirty, now time.Time) time.Duration {
+	left := firstDirty.Add(eventMaxWait).Sub(now)
+	switch {
+	case left <= 0:
+		return 0
+	case left < eventDebounce:
+		return left
+	}
+	return eventDebounce
+}
+
+// sessionRef identifies the session that owns a tracked JSONL file.
+type sessionRef struct {
+	SessionKey string
+	SessionID  string
+	WindowID   string
+}
+
+// fileWatcher wraps an fsnotify watcher over the Claude projects directories
+// and the tramuntana state directory.
+type fileWatcher struct {
+	w    *fsnotify.Watcher
+	dirs map[string]bool
+}
+
+// newFileWatcher creates a watcher. Returns an error if inotify is unavailable.
+func newFileWatcher() (*fileWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &fileWatcher{w: w, dirs: make(map[string]bool)}, nil
+}
+
+// watchDir adds a directory to the watch list. Idempotent.
+func (fw *fileWatcher) watchDir(dir string) {
+	if fw.dirs[dir] {
+		return
+	}
+	if err := fw.w.Add(dir); err != nil {
+		log.Printf("Monitor: cannot watch %s: %v", dir, err)
+		return
+	}
+	fw.dirs[dir] = true
+}
+
+// watchProjects watches the Claude projects root and every project directory below it.
+func (fw *fileWatcher) watchProjects(root string) {
+	if _, err := os.Stat(root); err != nil {
+		return
+	}
+	fw.watchDir(root)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			fw.watchDir(filepath.Join(root, e.Name()))
+		}
+	}
+}
+
+func (fw *fileWatcher) close() {
+	fw.w.Close()
+}
+
+// claudeProjectsDir returns the directory where Claude Code stores JSONL transcripts.
+func claudeProjectsDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".claude", "projects")
+}
+
+// handleEvent reacts to a single filesystem event.
+// Returns true if a full reconcile poll should run.
+func (m *Monitor) handleEvent(fw *fileWatcher, ev fsnotify.Event) bool {
+	path := ev.Name
+
+	// New project directory: start watching it
+	if ev.Has(fsnotify.Create) && filepath.Dir(path) == claudeProjectsDir() {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			fw.watchDir(path)
+			return false
+		}
+	}
+
+	// session_map.json is replaced atomically by the hook (rename → Create)
+	if filepath.Base(path) == "session_map.json" && filepath.Dir(path) == m.config.TramuntanaDir {
+		return ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write)
+	}
+
+	if !(ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create)) {
+		return false
+	}
+
+	if _, ok := m.watchedFiles[path]; ok {
+		m.dirtyFiles[path] = true
+		return false
+	}
+
+	if !strings.HasSuffix(path, ".jsonl") {
+		return false
+	}
+
+	// A transcript we couldn't locate during the last poll has just appeared
+	sessionID := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	return m.unresolvedSessions[sessionID]
+}
+
+// flushDirty processes every transcript that received writes since the last flush.
+func (m *Monitor) flushDirty() {
+	for path := range m.dirtyFiles {
+		delete(m.dirtyFiles, path)
+		ref, ok := m.watchedFiles[path]
+		if !ok {
+			continue
+		}
+		m.processSession(ref.SessionKey, ref.SessionID, ref.WindowID, path)
+	}
+	m.saveIfDirty()
+}
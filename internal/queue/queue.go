@@ -23,9 +23,18 @@ type MessageTask struct {
 	ThreadID    int
 	ChatID      int64
 	Parts       []string
-	ContentType string // "content", "tool_use", "tool_result", "status_update", "status_clear"
+	ContentType string // "content", "tool_use", "tool_progress", "tool_result", "status_update", "status_clear", "todo", "todo_clear", "attachment"
 	ToolUseID   string // for tool_result editing
 	WindowID    string
+	Keyboard    *tgbotapi.InlineKeyboardMarkup // optional buttons, attached to the last part
+	File        *Attachment                    // for "attachment"; Parts is the caption
+}
+
+// Attachment is a file sent as a photo or document.
+type Attachment struct {
+	Name  string
+	Data  []byte
+	Photo bool // send as a photo; falls back to a document if Telegram rejects it
 }
 
 // userThread is a composite key for per-(user, thread) tracking.
@@ -48,6 +57,7 @@ type Queue struct {
 	queues     map[int64]chan MessageTask // user_id → channel
 	toolMsgIDs map[string]toolMsgInfo    // tool_use_id → message info
 	statusMsgs map[userThread]StatusInfo // (user_id, thread_id) → status message
+	todoMsgs   map[userThread]StatusInfo // (user_id, thread_id) → todo checklist message
 	flood      *FloodControl
 }
 
@@ -55,6 +65,7 @@ type toolMsgInfo struct {
 	ChatID    int64
 	MessageID int
 	ThreadID  int
+	Text      string // last progress text, to skip unchanged edits
 }
 
 // New creates a new Queue.
@@ -64,6 +75,7 @@ func New(api *tgbotapi.BotAPI) *Queue {
 		queues:     make(map[int64]chan MessageTask),
 		toolMsgIDs: make(map[string]toolMsgInfo),
 		statusMsgs: make(map[userThread]StatusInfo),
+		todoMsgs:   make(map[userThread]StatusInfo),
 		flood:      NewFloodControl(),
 	}
 }
@@ -75,7 +87,7 @@ func (q *Queue) Enqueue(task MessageTask) {
 	// block content messages from being enqueued.
 	if q.flood.IsFlooded(task.ChatID) {
 		switch task.ContentType {
-		case "status_update", "status_clear", "tool_use", "tool_result":
+		case "status_update", "status_clear", "tool_use", "tool_progress", "tool_result", "todo", "attachment":
 			return
 		}
 	}
@@ -126,10 +138,10 @@ func (q *Queue) processTask(task MessageTask, ch chan MessageTask) {
 	// Check flood control using chatID (flood bans are keyed by chatID, not userID)
 	if q.flood.IsFlooded(task.ChatID) {
 		switch task.ContentType {
-		case "status_update", "status_clear", "tool_use":
+		case "status_update", "status_clear", "tool_use", "tool_progress", "todo":
 			// Drop low-value messages during floods — they'll be stale by the time flood clears
 			return
-		case "tool_result":
+		case "tool_result", "attachment":
 			// Drop tool_result too — the tool_use message it would edit was likely dropped
 			return
 		default:
@@ -145,12 +157,20 @@ func (q *Queue) processTask(task MessageTask, ch chan MessageTask) {
 		q.processContent(task, ch)
 	case "tool_use":
 		q.processToolUse(task)
+	case "tool_progress":
+		q.processToolProgress(task)
 	case "tool_result":
 		q.processToolResult(task)
 	case "status_update":
 		q.processStatusUpdate(task)
 	case "status_clear":
 		q.processStatusClear(task)
+	case "todo":
+		q.processTodo(task)
+	case "todo_clear":
+		q.processTodoClear(task)
+	case "attachment":
+		q.processAttachment(task)
 	default:
 		q.processContent(task, ch)
 	}
@@ -159,12 +179,15 @@ func (q *Queue) processTask(task MessageTask, ch chan MessageTask) {
 func (q *Queue) processContent(task MessageTask, ch chan MessageTask) {
 	text := strings.Join(task.Parts, "\n")
 
-	// Try to merge consecutive content tasks, collecting any non-content tasks
+	// Try to merge consecutive content tasks, collecting any non-content tasks.
+	// A message with buttons is sent on its own so they stay next to it.
 	var deferred []MessageTask
-	text, deferred = q.mergeFromChannel2(text, task.WindowID, ch)
+	if task.Keyboard == nil {
+		text, deferred = q.mergeFromChannel2(text, task.WindowID, ch)
+	}
 
 	// Send the merged content
-	q.sendMessage(task.ChatID, task.ThreadID, text)
+	q.sendMessage(task.ChatID, task.ThreadID, text, task.Keyboard)
 
 	// Process any deferred non-content tasks that were in the channel
 	for _, dt := range deferred {
@@ -174,7 +197,7 @@ func (q *Queue) processContent(task MessageTask, ch chan MessageTask) {
 
 func (q *Queue) processToolUse(task MessageTask) {
 	text := strings.Join(task.Parts, "\n")
-	msgID := q.sendMessage(task.ChatID, task.ThreadID, text)
+	msgID := q.sendMessage(task.ChatID, task.ThreadID, text, task.Keyboard)
 
 	if msgID != 0 && task.ToolUseID != "" {
 		q.mu.Lock()
@@ -187,6 +210,37 @@ func (q *Queue) processToolUse(task MessageTask) {
 	}
 }
 
+// processToolProgress updates a running tool's message in place (e.g. a
+// Task call's subagent summary). Without one, e.g. after a flood dropped the
+// tool_use, it's sent and registered for the tool_result to edit.
+func (q *Queue) processToolProgress(task MessageTask) {
+	text := strings.Join(task.Parts, "\n")
+
+	q.mu.Lock()
+	info, ok := q.toolMsgIDs[task.ToolUseID]
+	q.mu.Unlock()
+
+	if ok && info.MessageID != 0 {
+		if info.Text == text {
+			return
+		}
+		if err := q.editMessage(info.ChatID, info.MessageID, text, task.Keyboard); err != nil {
+			return
+		}
+	} else {
+		msgID := q.sendMessage(task.ChatID, task.ThreadID, text, task.Keyboard)
+		if msgID == 0 {
+			return
+		}
+		info = toolMsgInfo{ChatID: task.ChatID, MessageID: msgID, ThreadID: task.ThreadID}
+	}
+
+	info.Text = text
+	q.mu.Lock()
+	q.toolMsgIDs[task.ToolUseID] = info
+	q.mu.Unlock()
+}
+
 func (q *Queue) processToolResult(task MessageTask) {
 	text := strings.Join(task.Parts, "\n")
 
@@ -199,14 +253,14 @@ func (q *Queue) processToolResult(task MessageTask) {
 	q.mu.Unlock()
 
 	if ok && info.MessageID != 0 {
-		if err := q.editMessage(info.ChatID, info.MessageID, text); err != nil {
+		if err := q.editMessage(info.ChatID, info.MessageID, text, task.Keyboard); err != nil {
 			// Fallback: send new message
-			q.sendMessage(task.ChatID, task.ThreadID, text)
+			q.sendMessage(task.ChatID, task.ThreadID, text, task.Keyboard)
 		}
 		return
 	}
 
-	q.sendMessage(task.ChatID, task.ThreadID, text)
+	q.sendMessage(task.ChatID, task.ThreadID, text, task.Keyboard)
 }
 
 func (q *Queue) processStatusUpdate(task MessageTask) {
@@ -229,7 +283,7 @@ func (q *Queue) processStatusUpdate(task MessageTask) {
 
 	if hasExisting && existing.MessageID != 0 {
 		// Edit existing status message
-		if err := q.editMessage(task.ChatID, existing.MessageID, text); err == nil {
+		if err := q.editMessage(task.ChatID, existing.MessageID, text, nil); err == nil {
 			q.mu.Lock()
 			q.statusMsgs[ut] = StatusInfo{
 				MessageID: existing.MessageID,
@@ -242,7 +296,7 @@ func (q *Queue) processStatusUpdate(task MessageTask) {
 	}
 
 	// Send new status message
-	msgID := q.sendMessage(task.ChatID, task.ThreadID, text)
+	msgID := q.sendMessage(task.ChatID, task.ThreadID, text, nil)
 	q.mu.Lock()
 	q.statusMsgs[ut] = StatusInfo{
 		MessageID: msgID,
@@ -267,6 +321,53 @@ func (q *Queue) processStatusClear(task MessageTask) {
 	}
 }
 
+// processTodo edits the topic's checklist message in place, or sends it (and
+// pins it, so it stays visible during long turns) if there is none yet.
+func (q *Queue) processTodo(task MessageTask) {
+	text := strings.Join(task.Parts, "\n")
+	ut := userThread{task.UserID, task.ThreadID}
+
+	q.mu.RLock()
+	existing, hasExisting := q.todoMsgs[ut]
+	q.mu.RUnlock()
+
+	if hasExisting && existing.Text == text {
+		return
+	}
+
+	if hasExisting && existing.MessageID != 0 {
+		if err := q.editMessage(task.ChatID, existing.MessageID, text, nil); err == nil {
+			q.mu.Lock()
+			q.todoMsgs[ut] = StatusInfo{MessageID: existing.MessageID, WindowID: task.WindowID, Text: text}
+			q.mu.Unlock()
+			return
+		}
+	}
+
+	msgID := q.sendMessage(task.ChatID, task.ThreadID, text, nil)
+	if msgID != 0 {
+		q.pinMessage(task.ChatID, msgID)
+	}
+	q.mu.Lock()
+	q.todoMsgs[ut] = StatusInfo{MessageID: msgID, WindowID: task.WindowID, Text: text}
+	q.mu.Unlock()
+}
+
+// processTodoClear forgets the checklist message so the next list starts a
+// new one. The old message stays as a record but is unpinned.
+func (q *Queue) processTodoClear(task MessageTask) {
+	ut := userThread{task.UserID, task.ThreadID}
+
+	q.mu.Lock()
+	todo, ok := q.todoMsgs[ut]
+	delete(q.todoMsgs, ut)
+	q.mu.Unlock()
+
+	if ok && todo.MessageID != 0 {
+		q.unpinMessage(task.ChatID, todo.MessageID)
+	}
+}
+
 // mergeFromChannel2 merges consecutive content tasks from the channel.
 // Returns the merged text and any non-content tasks that were found in the channel
 // (these must be processed by the caller to preserve ordering).
@@ -278,7 +379,7 @@ func (q *Queue) mergeFromChannel2(text, windowID string, ch chan MessageTask) (s
 			if !ok {
 				return text, deferred
 			}
-			if next.ContentType != "content" || next.WindowID != windowID {
+			if next.ContentType != "content" || next.WindowID != windowID || next.Keyboard != nil {
 				deferred = append(deferred, next)
 				return text, deferred
 			}
@@ -306,7 +407,7 @@ func (q *Queue) drainStale(chatID int64, ch chan MessageTask) {
 				return
 			}
 			switch msg.ContentType {
-			case "status_update", "status_clear", "tool_use", "tool_result":
+			case "status_update", "status_clear", "tool_use", "tool_progress", "tool_result", "attachment":
 				drained++
 				continue
 			default:
@@ -338,8 +439,9 @@ func (q *Queue) HandleFloodError(chatID int64, err error) {
 
 // sendMessage sends a message with MarkdownV2, falling back to plain text.
 // Long messages are split at newline boundaries before conversion.
+// kb, if set, is attached to the last part.
 // Returns the message ID of the last sent message.
-func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
+func (q *Queue) sendMessage(chatID int64, threadID int, text string, kb *tgbotapi.InlineKeyboardMarkup) int {
 	parts := render.SplitMessage(text, 3000)
 
 	var lastMsgID int
@@ -350,7 +452,12 @@ func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
 			sendText = fmt.Sprintf("%s\n[%d/%d]", part, i+1, len(parts))
 		}
 
-		msgID := q.sendSingleMessage(chatID, threadID, sendText)
+		var partKB *tgbotapi.InlineKeyboardMarkup
+		if i == len(parts)-1 {
+			partKB = kb
+		}
+
+		msgID := q.sendSingleMessage(chatID, threadID, sendText, partKB)
 		if msgID != 0 {
 			lastMsgID = msgID
 		}
@@ -360,10 +467,10 @@ func (q *Queue) sendMessage(chatID int64, threadID int, text string) int {
 
 // sendSingleMessage sends a single message with MarkdownV2, falling back to plain text.
 // Retries once with flood-aware backoff. Does not retry permanent errors.
-func (q *Queue) sendSingleMessage(chatID int64, threadID int, text string) int {
+func (q *Queue) sendSingleMessage(chatID int64, threadID int, text string, kb *tgbotapi.InlineKeyboardMarkup) int {
 	// Try MarkdownV2 first
 	mdv2 := render.ToMarkdownV2(text)
-	msgID, err := q.sendRaw(chatID, threadID, mdv2, "MarkdownV2")
+	msgID, err := q.sendRaw(chatID, threadID, mdv2, "MarkdownV2", kb)
 	if err == nil {
 		return msgID
 	}
@@ -378,7 +485,7 @@ func (q *Queue) sendSingleMessage(chatID int64, threadID int, text string) int {
 	q.flood.WaitIfFlooded(chatID)
 
 	plain := render.ToPlainText(text)
-	msgID, err = q.sendRaw(chatID, threadID, plain, "")
+	msgID, err = q.sendRaw(chatID, threadID, plain, "", kb)
 	if err != nil {
 		log.Printf("Plain text fallback failed (chat=%d, thread=%d): %v", chatID, threadID, err)
 		return 0
@@ -399,7 +506,7 @@ func isPermanentError(err error) bool {
 }
 
 // sendRaw sends a message via Telegram API.
-func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int, error) {
+func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string, kb *tgbotapi.InlineKeyboardMarkup) (int, error) {
 	q.flood.Throttle(chatID)
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
@@ -411,6 +518,9 @@ func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int
 		params.AddNonZero("message_thread_id", threadID)
 	}
 	params.AddNonEmpty("link_preview_options", `{"is_disabled":true}`)
+	if err := params.AddInterface("reply_markup", kb); err != nil {
+		return 0, err
+	}
 
 	resp, err := q.api.MakeRequest("sendMessage", params)
 	if err != nil {
@@ -423,10 +533,11 @@ func (q *Queue) sendRaw(chatID int64, threadID int, text, parseMode string) (int
 	return msg.MessageID, nil
 }
 
-// editMessage edits a message, trying MarkdownV2 then plain text.
-func (q *Queue) editMessage(chatID int64, messageID int, text string) error {
+// editMessage edits a message, trying MarkdownV2 then plain text. kb, if set,
+// replaces its buttons.
+func (q *Queue) editMessage(chatID int64, messageID int, text string, kb *tgbotapi.InlineKeyboardMarkup) error {
 	mdv2 := render.ToMarkdownV2(text)
-	err := q.editRaw(chatID, messageID, mdv2, "MarkdownV2")
+	err := q.editRaw(chatID, messageID, mdv2, "MarkdownV2", kb)
 	if err == nil {
 		return nil
 	}
@@ -439,10 +550,10 @@ func (q *Queue) editMessage(chatID int64, messageID int, text string) error {
 	q.flood.WaitIfFlooded(chatID)
 
 	plain := render.ToPlainText(text)
-	return q.editRaw(chatID, messageID, plain, "")
+	return q.editRaw(chatID, messageID, plain, "", kb)
 }
 
-func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string) error {
+func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string, kb *tgbotapi.InlineKeyboardMarkup) error {
 	q.flood.Throttle(chatID)
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
@@ -452,6 +563,9 @@ func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string) err
 		params.AddNonEmpty("parse_mode", parseMode)
 	}
 	params.AddNonEmpty("link_preview_options", `{"is_disabled":true}`)
+	if err := params.AddInterface("reply_markup", kb); err != nil {
+		return err
+	}
 	_, err := q.api.MakeRequest("editMessageText", params)
 	if err != nil {
 		q.flood.HandleError(chatID, err)
@@ -459,6 +573,44 @@ func (q *Queue) editRaw(chatID int64, messageID int, text, parseMode string) err
 	return err
 }
 
+// processAttachment uploads a file with its caption.
+func (q *Queue) processAttachment(task MessageTask) {
+	if task.File == nil {
+		return
+	}
+	caption := strings.Join(task.Parts, "\n")
+	if task.File.Photo {
+		err := q.sendFile(task.ChatID, task.ThreadID, "sendPhoto", "photo", task.File, caption)
+		if err == nil || isPermanentError(err) || q.flood.IsFlooded(task.ChatID) {
+			return
+		}
+		// e.g. PHOTO_INVALID_DIMENSIONS for very tall images
+		log.Printf("Sending %s as photo failed, retrying as document: %v", task.File.Name, err)
+	}
+	if err := q.sendFile(task.ChatID, task.ThreadID, "sendDocument", "document", task.File, caption); err != nil {
+		log.Printf("Error sending %s (chat=%d, thread=%d): %v", task.File.Name, task.ChatID, task.ThreadID, err)
+	}
+}
+
+// sendFile uploads a file through method ("sendPhoto", "sendDocument") as field.
+func (q *Queue) sendFile(chatID int64, threadID int, method, field string, file *Attachment, caption string) error {
+	q.flood.Throttle(chatID)
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	if threadID != 0 {
+		params.AddNonZero("message_thread_id", threadID)
+	}
+	params.AddNonEmpty("caption", caption)
+
+	_, err := q.api.UploadFiles(method, params, []tgbotapi.RequestFile{
+		{Name: field, Data: tgbotapi.FileBytes{Name: file.Name, Bytes: file.Data}},
+	})
+	if err != nil {
+		q.flood.HandleError(chatID, err)
+	}
+	return err
+}
+
 func (q *Queue) deleteMessage(chatID int64, messageID int) {
 	params := tgbotapi.Params{}
 	params.AddNonZero64("chat_id", chatID)
@@ -473,3 +625,21 @@ func (q *Queue) sendTyping(chatID int64) {
 	params.AddNonEmpty("action", "typing")
 	q.api.MakeRequest("sendChatAction", params)
 }
+
+// pinMessage pins a message silently. Fails (logged) without the pin permission.
+func (q *Queue) pinMessage(chatID int64, messageID int) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	params.AddBool("disable_notification", true)
+	if _, err := q.api.MakeRequest("pinChatMessage", params); err != nil {
+		log.Printf("Error pinning message %d: %v", messageID, err)
+	}
+}
+
+func (q *Queue) unpinMessage(chatID int64, messageID int) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonZero("message_id", messageID)
+	q.api.MakeRequest("unpinChatMessage", params)
+}
@@ -3,6 +3,8 @@ package queue
 import (
 	"testing"
 	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 func TestFloodControl_NotFlooded(t *testing.T) {
@@ -103,3 +105,54 @@ type mockError struct {
 func (e *mockError) Error() string {
 	return e.msg
 }
+
+func TestProcessTodo_SkipsUnchanged(t *testing.T) {
+	q := New(nil)
+	ut := userThread{100, 42}
+	q.todoMsgs[ut] = StatusInfo{MessageID: 7, Text: "📋 **Todo** 0/1 done"}
+
+	// Same text: no API call, message kept
+	q.processTodo(MessageTask{UserID: 100, ThreadID: 42, Parts: []string{"📋 **Todo** 0/1 done"}, ContentType: "todo"})
+	if q.todoMsgs[ut].MessageID != 7 {
+		t.Errorf("unchanged checklist should keep its message, got %+v", q.todoMsgs[ut])
+	}
+}
+
+func TestProcessTodoClear(t *testing.T) {
+	q := New(nil)
+	ut := userThread{100, 42}
+	q.todoMsgs[ut] = StatusInfo{Text: "📋 **Todo** 1/1 done"}
+
+	q.processTodoClear(MessageTask{UserID: 100, ThreadID: 42, ContentType: "todo_clear"})
+	if _, ok := q.todoMsgs[ut]; ok {
+		t.Error("todo_clear should forget the checklist message")
+	}
+}
+
+func TestMergeFromChannel_StopsAtKeyboard(t *testing.T) {
+	q := New(nil)
+	ch := make(chan MessageTask, 3)
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Show more", "exp_1")))
+	ch <- MessageTask{Parts: []string{"b"}, ContentType: "content", WindowID: "@1"}
+	ch <- MessageTask{Parts: []string{"thinking"}, ContentType: "content", WindowID: "@1", Keyboard: &kb}
+	ch <- MessageTask{Parts: []string{"c"}, ContentType: "content", WindowID: "@1"}
+
+	text, deferred := q.mergeFromChannel2("a", "@1", ch)
+	if text != "a\nb" {
+		t.Errorf("merged text = %q, want %q", text, "a\nb")
+	}
+	if len(deferred) != 1 || deferred[0].Keyboard == nil {
+		t.Errorf("message with buttons should be deferred, got %+v", deferred)
+	}
+}
+
+func TestProcessToolProgress_SkipsUnchanged(t *testing.T) {
+	q := New(nil)
+	q.toolMsgIDs["tu_task"] = toolMsgInfo{ChatID: 1, MessageID: 9, Text: "● **Task**(x)\n  ⎿ 🤖 1 tool call"}
+
+	// Same text: no API call, mapping kept for the tool_result
+	q.processToolProgress(MessageTask{ChatID: 1, Parts: []string{"● **Task**(x)\n  ⎿ 🤖 1 tool call"}, ContentType: "tool_progress", ToolUseID: "tu_task"})
+	if q.toolMsgIDs["tu_task"].MessageID != 9 {
+		t.Errorf("progress should keep the tool message, got %+v", q.toolMsgIDs["tu_task"])
+	}
+}
@@ -0,0 +1,359 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is how many unchanged lines surround each hunk.
+	diffContext = 3
+	// maxDiffCells caps the LCS table; larger inputs are diffed as a full replacement.
+	maxDiffCells = 4 << 20
+
+	// Diffs up to these sizes are shown inline as a code block.
+	inlineDiffMaxLines = 30
+	inlineDiffMaxLen   = 2500
+
+	// Longer diffs up to these sizes are rendered as a PNG, beyond as a .diff document.
+	imageDiffMaxLines = 150
+	imageDiffMaxCols  = 160
+)
+
+// DiffDelivery is how a diff is sent: inline, as an image or as a document.
+type DiffDelivery int
+
+const (
+	DiffInline   DiffDelivery = iota // code block in the tool result message
+	DiffImage                        // syntax-colored PNG
+	DiffDocument                     // .diff file
+)
+
+// EditDiff builds a unified diff from the input of an Edit, MultiEdit or
+// Write tool call. Line numbers in hunk headers are relative to the edited
+// snippet, since the transcript doesn't carry its position in the file.
+// created marks a Write that made a new file. A Write over an existing file
+// is diffed from result, the call's toolUseResult: its structuredPatch, or
+// its originalFile. Returns the edited file's path, or false for other tools,
+// unreadable input, edits that change nothing and overwrites whose previous
+// contents aren't known (see WriteContents).
+func EditDiff(toolName string, input, result json.RawMessage, created bool) (path, diff string, ok bool) {
+	if input == nil {
+		return "", "", false
+	}
+	var in struct {
+		FilePath  string `json:"file_path"`
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+		Content   string `json:"content"`
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"`
+	}
+	if err := json.Unmarshal(input, &in); err != nil || in.FilePath == "" {
+		return "", "", false
+	}
+
+	var hunks []string
+	oldHeader := "a/" + strings.TrimPrefix(in.FilePath, "/")
+	switch toolName {
+	case "Edit":
+		hunks = diffHunks(in.OldString, in.NewString)
+	case "MultiEdit":
+		for _, e := range in.Edits {
+			hunks = append(hunks, diffHunks(e.OldString, e.NewString)...)
+		}
+	case "Write":
+		if created {
+			oldHeader = "/dev/null"
+			hunks = diffHunks("", in.Content)
+			break
+		}
+		var res struct {
+			StructuredPatch []struct {
+				OldStart int      `json:"oldStart"`
+				OldLines int      `json:"oldLines"`
+				NewStart int      `json:"newStart"`
+				NewLines int      `json:"newLines"`
+				Lines    []string `json:"lines"`
+			} `json:"structuredPatch"`
+			OriginalFile *string `json:"originalFile"`
+		}
+		json.Unmarshal(result, &res)
+		switch {
+		case len(res.StructuredPatch) > 0:
+			for _, h := range res.StructuredPatch {
+				hunks = append(hunks, fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)+
+					strings.Join(h.Lines, "\n")+"\n")
+			}
+		case res.OriginalFile != nil:
+			hunks = diffHunks(*res.OriginalFile, in.Content)
+		default:
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+	if len(hunks) == 0 {
+		return "", "", false
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ b/%s\n", oldHeader, strings.TrimPrefix(in.FilePath, "/"))
+	for _, h := range hunks {
+		b.WriteString(h)
+	}
+	return in.FilePath, strings.TrimSuffix(b.String(), "\n"), true
+}
+
+// WriteContents returns the path and contents of a Write tool call, for
+// an overwrite that EditDiff can't diff.
+func WriteContents(input json.RawMessage) (path, content string, ok bool) {
+	var in struct {
+		FilePath string `json:"file_path"`
+		Content  string `json:"content"`
+	}
+	if err := json.Unmarshal(input, &in); err != nil || in.FilePath == "" {
+		return "", "", false
+	}
+	return in.FilePath, in.Content, true
+}
+
+// FormatWriteResult formats the result of a Write over an existing file
+// whose previous contents aren't known, labelled as the new contents rather
+// than a diff. Inline contents are appended as a code block.
+func FormatWriteResult(toolInput, content string, inline bool) string {
+	text := "● " + toolHeader("Write", toolInput) +
+		fmt.Sprintf("\n  ⎿ Overwrote with %d lines (new contents, previous version unknown)", len(splitLines(content)))
+	if inline {
+		fence := codeFence(content)
+		text += "\n" + fence + "\n" + strings.TrimSuffix(content, "\n") + "\n" + fence
+	}
+	return text
+}
+
+// DiffDeliveryFor picks how to send a diff based on its size.
+func DiffDeliveryFor(diff string) DiffDelivery {
+	lines := strings.Split(diff, "\n")
+	if len(lines) <= inlineDiffMaxLines && len(diff) <= inlineDiffMaxLen {
+		return DiffInline
+	}
+	if len(lines) > imageDiffMaxLines {
+		return DiffDocument
+	}
+	for _, line := range lines {
+		if len([]rune(expandTabs(line))) > imageDiffMaxCols {
+			return DiffDocument
+		}
+	}
+	return DiffImage
+}
+
+// FormatDiffResult formats an Edit/MultiEdit/Write result from its diff. An
+// inline diff is appended as a code block; otherwise the diff is sent
+// separately and only the counts are shown.
+func FormatDiffResult(toolName, toolInput, diff string, inline bool) string {
+	added, removed := countEditChanges(diff)
+	text := "● " + toolHeader(toolName, toolInput) + fmt.Sprintf("\n  ⎿ Added %d, removed %d", added, removed)
+	if inline {
+		fence := codeFence(diff)
+		text += "\n" + fence + "diff\n" + diff + "\n" + fence
+	}
+	return text
+}
+
+// codeFence returns a Markdown code fence longer than any run of backticks in
+// content, so a fenced block inside it (a README, say) can't close it early.
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// RenderDiff renders a unified diff to a PNG with removed lines in red, added
+// lines in green and headers highlighted, through the screenshot pipeline.
+func RenderDiff(diff string) ([]byte, error) {
+	var b strings.Builder
+	for i, line := range strings.Split(diff, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		line = expandTabs(line)
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			b.WriteString("\x1b[1m" + line + "\x1b[0m")
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString("\x1b[36m" + line + "\x1b[0m")
+		case strings.HasPrefix(line, "+"):
+			b.WriteString("\x1b[32m" + line + "\x1b[0m")
+		case strings.HasPrefix(line, "-"):
+			b.WriteString("\x1b[31m" + line + "\x1b[0m")
+		default:
+			b.WriteString(line)
+		}
+	}
+	return RenderScreenshot(b.String())
+}
+
+// expandTabs replaces tabs with 4 spaces for fixed-width rendering.
+func expandTabs(line string) string {
+	return strings.ReplaceAll(line, "\t", "    ")
+}
+
+// diffOp is one line of a diff: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// diffHunks returns the unified diff hunks (each ending in a newline) turning
+// before into after.
+func diffHunks(before, after string) []string {
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	var hunks []string
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend until a run of unchanged lines long enough to split hunks
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*diffContext {
+				break
+			}
+		}
+
+		from := max(first-diffContext, 0)
+		to := min(last+diffContext+1, len(ops))
+		hunks = append(hunks, formatHunk(ops, from, to))
+		start = to
+	}
+	return hunks
+}
+
+// formatHunk renders ops[from:to] with its "@@ -l,s +l,s @@" header.
+func formatHunk(ops []diffOp, from, to int) string {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+
+	var oldCount, newCount int
+	var body strings.Builder
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+		body.WriteByte(op.kind)
+		body.WriteString(op.text)
+		body.WriteByte('\n')
+	}
+	if oldCount == 0 {
+		oldLine-- // an empty range starts before its line, as in diff -u
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount) + body.String()
+}
+
+// splitLines splits text into lines without a trailing empty one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line diff through the longest common subsequence,
+// after trimming the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	var prefix, suffix []diffOp
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	for _, line := range a[len(a)-n:] {
+		suffix = append(suffix, diffOp{' ', line})
+	}
+	a, b = a[:len(a)-n], b[:len(b)-n]
+
+	ops := prefix
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return append(ops, suffix...)
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return append(ops, suffix...)
+}
@@ -0,0 +1,191 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEditDiff_Edit(t *testing.T) {
+	input, _ := json.Marshal(map[string]string{
+		"file_path":  "/src/main.go",
+		"old_string": "a\nb\nc\n",
+		"new_string": "a\nB\nc\n",
+	})
+	path, diff, ok := EditDiff("Edit", input, nil, false)
+	if !ok || path != "/src/main.go" {
+		t.Fatalf("EditDiff = %q, %v", path, ok)
+	}
+	want := "--- a/src/main.go\n+++ b/src/main.go\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestEditDiff_MultiEdit(t *testing.T) {
+	input := json.RawMessage(`{"file_path":"x.go","edits":[{"old_string":"one","new_string":"1"},{"old_string":"two","new_string":"2"}]}`)
+	_, diff, ok := EditDiff("MultiEdit", input, nil, false)
+	if !ok {
+		t.Fatal("EditDiff failed")
+	}
+	if strings.Count(diff, "@@ -1,1 +1,1 @@") != 2 {
+		t.Errorf("each edit should be its own hunk:\n%s", diff)
+	}
+	if !strings.Contains(diff, "-one\n+1") || !strings.Contains(diff, "-two\n+2") {
+		t.Errorf("diff =\n%s", diff)
+	}
+}
+
+func TestEditDiff_Write(t *testing.T) {
+	input := json.RawMessage(`{"file_path":"/tmp/new.txt","content":"hello\nworld\n"}`)
+	_, diff, ok := EditDiff("Write", input, nil, true)
+	want := "--- /dev/null\n+++ b/tmp/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world"
+	if !ok || diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+
+	if _, _, ok := EditDiff("Write", input, nil, false); ok {
+		t.Error("an overwrite without the previous contents is not a diff")
+	}
+
+	patch := json.RawMessage(`{"type":"update","structuredPatch":[{"oldStart":1,"oldLines":2,"newStart":1,"newLines":2,"lines":["-hi"," world","+hello"]}]}`)
+	_, diff, ok = EditDiff("Write", input, patch, false)
+	want = "--- a/tmp/new.txt\n+++ b/tmp/new.txt\n@@ -1,2 +1,2 @@\n-hi\n world\n+hello"
+	if !ok || diff != want {
+		t.Errorf("patch diff =\n%s\nwant\n%s", diff, want)
+	}
+
+	original := json.RawMessage(`{"type":"update","originalFile":"hi\nworld\n"}`)
+	_, diff, ok = EditDiff("Write", input, original, false)
+	want = "--- a/tmp/new.txt\n+++ b/tmp/new.txt\n@@ -1,2 +1,2 @@\n-hi\n+hello\n world"
+	if !ok || diff != want {
+		t.Errorf("original diff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestFormatWriteResult(t *testing.T) {
+	got := FormatWriteResult("notes.md", "a\nb\n", true)
+	if !strings.Contains(got, "Overwrote with 2 lines (new contents") || !strings.Contains(got, "```\na\nb\n```") {
+		t.Errorf("got %q", got)
+	}
+	if strings.Contains(got, "```diff") {
+		t.Errorf("new contents must not be shown as a diff: %q", got)
+	}
+	if got := FormatWriteResult("notes.md", "a\n", false); strings.Contains(got, "```") {
+		t.Errorf("non-inline result should have no code block: %q", got)
+	}
+}
+
+func TestEditDiff_Rejects(t *testing.T) {
+	tests := []struct {
+		tool  string
+		input string
+	}{
+		{"Bash", `{"command":"ls"}`},
+		{"Edit", `{"old_string":"a","new_string":"b"}`},
+		{"Edit", `{"file_path":"x","old_string":"same","new_string":"same"}`},
+		{"Edit", `not json`},
+	}
+	for _, tt := range tests {
+		if _, _, ok := EditDiff(tt.tool, json.RawMessage(tt.input), nil, false); ok {
+			t.Errorf("EditDiff(%s, %s) should fail", tt.tool, tt.input)
+		}
+	}
+	if _, _, ok := EditDiff("Edit", nil, nil, false); ok {
+		t.Error("nil input should fail")
+	}
+}
+
+func TestDiffHunks_SplitsDistantChanges(t *testing.T) {
+	var before, after []string
+	for i := 0; i < 30; i++ {
+		before = append(before, fmt.Sprintf("line %d", i))
+		after = append(after, fmt.Sprintf("line %d", i))
+	}
+	after[2] = "changed 2"
+	after[25] = "changed 25"
+
+	hunks := diffHunks(strings.Join(before, "\n"), strings.Join(after, "\n"))
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2:\n%s", len(hunks), strings.Join(hunks, ""))
+	}
+	if !strings.HasPrefix(hunks[0], "@@ -1,6 +1,6 @@\n") {
+		t.Errorf("first hunk header: %q", strings.SplitN(hunks[0], "\n", 2)[0])
+	}
+	if !strings.HasPrefix(hunks[1], "@@ -23,7 +23,7 @@\n") {
+		t.Errorf("second hunk header: %q", strings.SplitN(hunks[1], "\n", 2)[0])
+	}
+}
+
+func TestDiffLines_Insertions(t *testing.T) {
+	ops := diffLines([]string{"a", "c"}, []string{"a", "b", "c"})
+	var got []string
+	for _, op := range ops {
+		got = append(got, string(op.kind)+op.text)
+	}
+	if strings.Join(got, ",") != " a,+b, c" {
+		t.Errorf("ops = %v", got)
+	}
+}
+
+func TestDiffDeliveryFor(t *testing.T) {
+	short := "--- a/x\n+++ b/x\n@@ -1,1 +1,1 @@\n-a\n+b"
+	if got := DiffDeliveryFor(short); got != DiffInline {
+		t.Errorf("short diff: %v, want inline", got)
+	}
+	medium := strings.Repeat("+line\n", 60)
+	if got := DiffDeliveryFor(medium); got != DiffImage {
+		t.Errorf("medium diff: %v, want image", got)
+	}
+	if got := DiffDeliveryFor(strings.Repeat("+line\n", 200)); got != DiffDocument {
+		t.Errorf("long diff: %v, want document", got)
+	}
+	wide := medium + "+" + strings.Repeat("x", 200)
+	if got := DiffDeliveryFor(wide); got != DiffDocument {
+		t.Errorf("wide diff: %v, want document", got)
+	}
+}
+
+func TestFormatDiffResult(t *testing.T) {
+	diff := "--- a/x\n+++ b/x\n@@ -1,1 +1,2 @@\n-a\n+b\n+c"
+	got := FormatDiffResult("Edit", "x", diff, true)
+	if !strings.Contains(got, "Added 2, removed 1") || !strings.Contains(got, "```diff\n"+diff+"\n```") {
+		t.Errorf("inline result = %q", got)
+	}
+	if got := FormatDiffResult("Edit", "x", diff, false); strings.Contains(got, "```") {
+		t.Errorf("attached diff should not be inlined: %q", got)
+	}
+}
+
+func TestFormatResults_NestedFence(t *testing.T) {
+	readme := "# Tool\n\n```bash\nmake install\n```\n\nThen *run* it.\n"
+	diff := "--- a/README.md\n+++ b/README.md\n@@ -1,1 +1,4 @@\n+```bash\n+make install\n+```\n+Then *run* it."
+	for name, text := range map[string]string{
+		"write": FormatWriteResult("README.md", readme, true),
+		"diff":  FormatDiffResult("Edit", "README.md", diff, true),
+	} {
+		if !strings.Contains(text, "````") {
+			t.Errorf("%s: fence should outgrow the content's: %q", name, text)
+		}
+		// Everything after the header must stay inside one code block
+		got := ToMarkdownV2(text)
+		if strings.Count(got, "```") != 2 || !strings.Contains(got, "*run*") {
+			t.Errorf("%s: fenced block closed early:\n%s", name, got)
+		}
+	}
+	if got := codeFence("no backticks"); got != "```" {
+		t.Errorf("codeFence = %q", got)
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	png, err := RenderDiff("--- a/x\n+++ b/x\n@@ -1,1 +1,1 @@\n-\told\n+\tnew")
+	if err != nil {
+		t.Fatalf("RenderDiff: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("output is not a PNG")
+	}
+}
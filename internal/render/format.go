@@ -14,6 +14,13 @@ const (
 // previewLines is how many content lines to show before truncating with "… +N lines".
 const previewLines = 3
 
+// Display limits for thinking blocks, expandable quotes and error first lines.
+const (
+	thinkingMaxLen       = 500
+	quoteMaxLen          = 3000
+	errorFirstLineMaxLen = 100
+)
+
 // FormatToolUse formats a tool_use block as the initial message (before result arrives).
 func FormatToolUse(name, input string) string {
 	return "● " + toolHeader(name, input)
@@ -35,12 +42,63 @@ func FormatToolResult(toolName, toolInput, content string, isError bool) string
 // FormatThinking formats a thinking block: truncate to 500 chars and wrap in expandable quote.
 func FormatThinking(text string) string {
 	truncated := text
-	if len(truncated) > 500 {
-		truncated = truncated[:500] + "..."
+	if len(truncated) > thinkingMaxLen {
+		truncated = truncated[:thinkingMaxLen] + "..."
 	}
 	return formatExpandableQuote(truncated)
 }
 
+// ThinkingTruncated reports whether FormatThinking cuts text short.
+func ThinkingTruncated(text string) bool {
+	return len(text) > thinkingMaxLen
+}
+
+// ToolResultTruncated reports whether FormatToolResult shows less than the
+// whole content, i.e. whether the full output is worth offering separately.
+func ToolResultTruncated(toolName, content string, isError bool) bool {
+	if content == "" {
+		return false
+	}
+	if isError {
+		if !strings.Contains(content, "\n") {
+			return len(content) > errorFirstLineMaxLen
+		}
+		return len(content) > quoteMaxLen
+	}
+
+	lineCount := strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+	switch toolName {
+	case "Read", "Task", "WebFetch":
+		return true // only counts are shown
+	case "Write":
+		return false
+	case "Edit":
+		return lineCount > 1
+	case "Grep", "Glob", "WebSearch":
+		return len(content) > quoteMaxLen
+	default:
+		return lineCount > previewLines
+	}
+}
+
+// FormatSubagentProgress formats a running Task call: its header, the number
+// of tool calls its subagent made, the last action and, collapsed, the recent ones.
+func FormatSubagentProgress(toolName, description string, tools int, recent []string) string {
+	text := "● " + toolHeader(toolName, description) + "\n  ⎿ 🤖 "
+	if tools == 1 {
+		text += "1 tool call"
+	} else {
+		text += fmt.Sprintf("%d tool calls", tools)
+	}
+	if len(recent) > 0 {
+		text += " · last: " + recent[len(recent)-1]
+	}
+	if len(recent) > 1 {
+		text += "\n" + formatExpandableQuote(strings.Join(recent, "\n"))
+	}
+	return text
+}
+
 // FormatText strips system tags and returns clean text.
 func FormatText(text string) string {
 	return text
@@ -140,19 +198,19 @@ func formatPreview(lines []string, totalLines int) string {
 
 // formatPreviewQuote wraps content in an expandable quote, truncated.
 func formatPreviewQuote(content string) string {
-	return formatExpandableQuote(truncateContent(content, 3000))
+	return formatExpandableQuote(truncateContent(content, quoteMaxLen))
 }
 
 // formatErrorBody formats error content for display after ⎿.
 func formatErrorBody(content string) string {
 	lines := strings.SplitN(content, "\n", 2)
 	first := lines[0]
-	if len(first) > 100 {
-		first = first[:100] + "..."
+	if len(first) > errorFirstLineMaxLen {
+		first = first[:errorFirstLineMaxLen] + "..."
 	}
 	result := "Error: " + first
 	if len(lines) > 1 {
-		result += "\n" + formatExpandableQuote(truncateContent(content, 3000))
+		result += "\n" + formatExpandableQuote(truncateContent(content, quoteMaxLen))
 	}
 	return result
 }
@@ -215,3 +273,34 @@ func countSearchResults(content string) int {
 	}
 	return count
 }
+
+// TodoItem is one entry of a TodoWrite checklist.
+type TodoItem struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"` // "pending", "in_progress", "completed"
+	ActiveForm string `json:"activeForm"`
+}
+
+// FormatTodoList renders a TodoWrite checklist with a progress count.
+// In-progress items show their active form ("Running tests") when set.
+func FormatTodoList(items []TodoItem) string {
+	done := 0
+	var lines []string
+	for _, it := range items {
+		switch it.Status {
+		case "completed":
+			done++
+			lines = append(lines, "✅ "+it.Content)
+		case "in_progress":
+			text := it.Content
+			if it.ActiveForm != "" {
+				text = it.ActiveForm
+			}
+			lines = append(lines, "🔄 **"+text+"**")
+		default:
+			lines = append(lines, "⬜ "+it.Content)
+		}
+	}
+	header := fmt.Sprintf("📋 **Todo** %d/%d done", done, len(items))
+	return header + "\n" + strings.Join(lines, "\n")
+}
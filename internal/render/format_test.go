@@ -171,6 +171,46 @@ func TestFormatThinking_Truncation(t *testing.T) {
 	}
 }
 
+func TestThinkingTruncated(t *testing.T) {
+	if ThinkingTruncated(strings.Repeat("x", 500)) {
+		t.Error("500 chars are shown in full")
+	}
+	if !ThinkingTruncated(strings.Repeat("x", 501)) {
+		t.Error("501 chars are cut")
+	}
+}
+
+func TestToolResultTruncated(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		content string
+		isError bool
+		want    bool
+	}{
+		{"empty", "Bash", "", false, false},
+		{"bash short", "Bash", "a\nb\nc\n", false, false},
+		{"bash long", "Bash", "a\nb\nc\nd", false, true},
+		{"read shows a count", "Read", "x", false, true},
+		{"write", "Write", "File created successfully", false, false},
+		{"edit one line", "Edit", "The file has been updated.", false, false},
+		{"edit snippet", "Edit", "The file has been updated.\n  1 x", false, true},
+		{"grep in quote", "Grep", "a.go\nb.go", false, false},
+		{"grep over quote", "Grep", strings.Repeat("x\n", 2000), false, true},
+		{"error one line", "Bash", "exit 1", true, false},
+		{"error long line", "Bash", strings.Repeat("x", 101), true, true},
+		{"error multiline", "Bash", "exit 1\nstderr", true, false},
+		{"error over quote", "Bash", "exit 1\n" + strings.Repeat("x", 3000), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToolResultTruncated(tt.tool, tt.content, tt.isError); got != tt.want {
+				t.Errorf("ToolResultTruncated(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTruncateContent(t *testing.T) {
 	short := "hello"
 	if truncateContent(short, 100) != "hello" {
@@ -223,3 +263,30 @@ func TestFormatPreview(t *testing.T) {
 		t.Error("should not include line4")
 	}
 }
+
+func TestFormatTodoList(t *testing.T) {
+	got := FormatTodoList([]TodoItem{
+		{Content: "Write parser", Status: "completed"},
+		{Content: "Add tests", Status: "in_progress", ActiveForm: "Adding tests"},
+		{Content: "Update README", Status: "pending"},
+	})
+	want := "📋 **Todo** 1/3 done\n✅ Write parser\n🔄 **Adding tests**\n⬜ Update README"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFormatSubagentProgress(t *testing.T) {
+	got := FormatSubagentProgress("Task", "Find TODOs", 1, []string{"**Grep**(TODO)"})
+	if got != "● **Task**(Find TODOs)\n  ⎿ 🤖 1 tool call · last: **Grep**(TODO)" {
+		t.Errorf("got %q", got)
+	}
+
+	got = FormatSubagentProgress("Task", "Find TODOs", 2, []string{"**Grep**(TODO)", "**Read**(a.go)"})
+	if !strings.Contains(got, "2 tool calls · last: **Read**(a.go)") {
+		t.Errorf("got %q", got)
+	}
+	if !strings.Contains(got, ExpQuoteStart+"**Grep**(TODO)\n**Read**(a.go)"+ExpQuoteEnd) {
+		t.Errorf("recent actions should be collapsed in a quote: %q", got)
+	}
+}
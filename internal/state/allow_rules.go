@@ -0,0 +1,116 @@
+package state
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// AllowRule auto-approves permission prompts for a tool whose command or path
+// matches Pattern. A rule is scoped to one topic or to every topic bound to a project.
+type AllowRule struct {
+	ID      string `json:"id"`
+	Tool    string `json:"tool"`
+	Pattern string `json:"pattern"`           // glob: * matches any run of characters except newline
+	Thread  string `json:"thread,omitempty"`  // topic scope
+	Project string `json:"project,omitempty"` // project scope
+}
+
+// ShellOperators chain, pipe, redirect or substitute commands, or start a
+// new line of them.
+var ShellOperators = []string{";", "&", "|", "`", "$(", ">", "<", "\n"}
+
+// IsCompoundCommand reports whether a Bash command uses any of
+// ShellOperators. Such a command is never auto-approved, neither by an allow
+// rule nor by the hook on Claude Code's behalf, so "go test *" doesn't approve
+// "go test ./... && rm -rf ~".
+func IsCompoundCommand(command string) bool {
+	for _, op := range ShellOperators {
+		if strings.Contains(command, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the rule covers a tool call.
+func (r AllowRule) Matches(tool, subject string) bool {
+	if r.Tool != tool {
+		return false
+	}
+	if tool == "Bash" && IsCompoundCommand(subject) {
+		return false
+	}
+	return globRegexp(r.Pattern).MatchString(subject)
+}
+
+// globRegexp compiles a glob where * matches any run of characters and ?
+// matches one, neither crossing a newline: multi-line commands only match a
+// pattern that spells out every line.
+func globRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func allowRuleID(r AllowRule) string {
+	sum := sha1.Sum([]byte(r.Thread + "\x00" + r.Project + "\x00" + r.Tool + "\x00" + r.Pattern))
+	return hex.EncodeToString(sum[:4])
+}
+
+// AddAllowRule stores a rule and returns it with its ID set. Returns false if
+// an identical rule already exists.
+func (s *State) AddAllowRule(r AllowRule) (AllowRule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.ID = allowRuleID(r)
+	for _, existing := range s.AllowRules {
+		if existing.ID == r.ID {
+			return existing, false
+		}
+	}
+	s.AllowRules = append(s.AllowRules, r)
+	return r, true
+}
+
+// RemoveAllowRule deletes a rule by ID. Returns false if it doesn't exist.
+func (s *State) RemoveAllowRule(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.AllowRules {
+		if r.ID == id {
+			s.AllowRules = append(s.AllowRules[:i], s.AllowRules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// AllowRulesFor returns the rules that apply to a topic: its own rules, then
+// those of its project (projectID may be empty).
+func (s *State) AllowRulesFor(threadID, projectID string) []AllowRule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var topic, project []AllowRule
+	for _, r := range s.AllowRules {
+		switch {
+		case r.Thread != "" && r.Thread == threadID:
+			topic = append(topic, r)
+		case r.Project != "" && r.Project == projectID:
+			project = append(project, r)
+		}
+	}
+	return append(topic, project...)
+}
@@ -0,0 +1,57 @@
+package state
+
+// Budget caps an auto-mode run. Zero fields are unlimited.
+type Budget struct {
+	Tokens int64   `json:"tokens,omitempty"`
+	USD    float64 `json:"usd,omitempty"`
+	Hours  float64 `json:"hours,omitempty"`
+}
+
+// IsZero reports whether the budget sets no limit.
+func (b Budget) IsZero() bool {
+	return b.Tokens == 0 && b.USD == 0 && b.Hours == 0
+}
+
+// SetTopicBudget sets a topic's budget. A zero budget removes it.
+func (s *State) SetTopicBudget(threadID string, b Budget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.IsZero() {
+		delete(s.TopicBudgets, threadID)
+		return
+	}
+	if s.TopicBudgets == nil {
+		s.TopicBudgets = make(map[string]Budget)
+	}
+	s.TopicBudgets[threadID] = b
+}
+
+// TopicBudget returns a topic's budget.
+func (s *State) TopicBudget(threadID string) (Budget, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.TopicBudgets[threadID]
+	return b, ok
+}
+
+// SetProjectBudget sets a project's budget. A zero budget removes it.
+func (s *State) SetProjectBudget(project string, b Budget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b.IsZero() {
+		delete(s.ProjectBudgets, project)
+		return
+	}
+	if s.ProjectBudgets == nil {
+		s.ProjectBudgets = make(map[string]Budget)
+	}
+	s.ProjectBudgets[project] = b
+}
+
+// ProjectBudget returns a project's budget.
+func (s *State) ProjectBudget(project string) (Budget, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.ProjectBudgets[project]
+	return b, ok
+}
@@ -0,0 +1,32 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBudgets_SetRemovePersist(t *testing.T) {
+	s := NewState()
+	s.SetTopicBudget("5", Budget{Tokens: 2_000_000})
+	s.SetProjectBudget("web", Budget{USD: 10, Hours: 2})
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if b, ok := loaded.TopicBudget("5"); !ok || b.Tokens != 2_000_000 {
+		t.Errorf("topic budget = %+v, %v", b, ok)
+	}
+	if b, ok := loaded.ProjectBudget("web"); !ok || b.USD != 10 || b.Hours != 2 {
+		t.Errorf("project budget = %+v, %v", b, ok)
+	}
+
+	loaded.SetTopicBudget("5", Budget{})
+	if _, ok := loaded.TopicBudget("5"); ok {
+		t.Error("a zero budget should remove the topic budget")
+	}
+}
@@ -0,0 +1,23 @@
+package state
+
+// SetInputQueue turns a topic's input queue on or off. While it's on,
+// messages sent while Claude is working are held until the turn ends.
+func (s *State) SetInputQueue(threadID string, on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !on {
+		delete(s.InputQueueTopics, threadID)
+		return
+	}
+	if s.InputQueueTopics == nil {
+		s.InputQueueTopics = make(map[string]bool)
+	}
+	s.InputQueueTopics[threadID] = true
+}
+
+// InputQueueEnabled reports whether a topic queues input while Claude works.
+func (s *State) InputQueueEnabled(threadID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.InputQueueTopics[threadID]
+}
@@ -0,0 +1,34 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInputQueue(t *testing.T) {
+	s := NewState()
+	if s.InputQueueEnabled("5") {
+		t.Error("input queue should be off by default")
+	}
+	s.SetInputQueue("5", true)
+	if !s.InputQueueEnabled("5") || s.InputQueueEnabled("6") {
+		t.Error("SetInputQueue should only enable its topic")
+	}
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !loaded.InputQueueEnabled("5") {
+		t.Error("input queue setting should persist")
+	}
+
+	loaded.SetInputQueue("5", false)
+	if loaded.InputQueueEnabled("5") {
+		t.Error("SetInputQueue(false) should disable it")
+	}
+}
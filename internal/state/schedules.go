@@ -0,0 +1,110 @@
+package state
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"time"
+)
+
+// Schedule sends Prompt to a topic's session on a cron schedule.
+type Schedule struct {
+	ID      string    `json:"id"`
+	Thread  string    `json:"thread"`
+	User    string    `json:"user"` // whose binding and chat the prompt goes to
+	Spec    string    `json:"spec"` // cron expression, e.g. "0 6 * * 1-5" or "@every 1h"
+	Prompt  string    `json:"prompt"`
+	Paused  bool      `json:"paused,omitempty"`
+	Created time.Time `json:"created"`
+	LastRun time.Time `json:"last_run,omitempty"` // last time it came due, sent or skipped
+}
+
+func scheduleID(sc Schedule) string {
+	sum := sha1.Sum([]byte(sc.Thread + "\x00" + sc.Spec + "\x00" + sc.Prompt))
+	return hex.EncodeToString(sum[:4])
+}
+
+// AddSchedule stores a schedule and returns it with its ID set. Returns
+// false if the topic already has the same one.
+func (s *State) AddSchedule(sc Schedule) (Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc.ID = scheduleID(sc)
+	for _, existing := range s.Schedules {
+		if existing.ID == sc.ID {
+			return existing, false
+		}
+	}
+	s.Schedules = append(s.Schedules, sc)
+	return sc, true
+}
+
+// RemoveSchedule deletes a schedule by ID. Returns false if it doesn't exist.
+func (s *State) RemoveSchedule(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sc := range s.Schedules {
+		if sc.ID == id {
+			s.Schedules = append(s.Schedules[:i], s.Schedules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetSchedulePaused pauses or resumes a schedule. Returns false if it doesn't exist.
+func (s *State) SetSchedulePaused(id string, paused bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Schedules {
+		if s.Schedules[i].ID == id {
+			s.Schedules[i].Paused = paused
+			return true
+		}
+	}
+	return false
+}
+
+// RecordScheduleRun sets when a schedule last came due.
+func (s *State) RecordScheduleRun(id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Schedules {
+		if s.Schedules[i].ID == id {
+			s.Schedules[i].LastRun = at
+			return
+		}
+	}
+}
+
+// TopicSchedules returns a topic's schedules in the order they were added.
+func (s *State) TopicSchedules(threadID string) []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Schedule
+	for _, sc := range s.Schedules {
+		if sc.Thread == threadID {
+			out = append(out, sc)
+		}
+	}
+	return out
+}
+
+// AllSchedules returns a copy of every schedule.
+func (s *State) AllSchedules() []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Schedule(nil), s.Schedules...)
+}
+
+// RemoveTopicSchedules deletes a topic's schedules.
+func (s *State) RemoveTopicSchedules(threadID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.Schedules[:0]
+	for _, sc := range s.Schedules {
+		if sc.Thread != threadID {
+			kept = append(kept, sc)
+		}
+	}
+	s.Schedules = kept
+}
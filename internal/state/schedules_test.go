@@ -0,0 +1,62 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSchedules(t *testing.T) {
+	s := NewState()
+	sc, added := s.AddSchedule(Schedule{Thread: "42", User: "1", Spec: "0 9 * * *", Prompt: "standup"})
+	if !added || sc.ID == "" {
+		t.Fatalf("expected schedule to be added with an ID, got %+v", sc)
+	}
+	if _, added := s.AddSchedule(Schedule{Thread: "42", User: "1", Spec: "0 9 * * *", Prompt: "standup"}); added {
+		t.Error("duplicate schedule should not be added")
+	}
+	s.AddSchedule(Schedule{Thread: "42", User: "1", Spec: "@hourly", Prompt: "tests"})
+	s.AddSchedule(Schedule{Thread: "99", User: "1", Spec: "0 9 * * *", Prompt: "standup"})
+
+	if got := s.TopicSchedules("42"); len(got) != 2 || got[0].ID != sc.ID {
+		t.Errorf("expected two schedules in order for topic 42, got %+v", got)
+	}
+	if len(s.AllSchedules()) != 3 {
+		t.Errorf("expected 3 schedules, got %d", len(s.AllSchedules()))
+	}
+
+	if !s.SetSchedulePaused(sc.ID, true) || !s.TopicSchedules("42")[0].Paused {
+		t.Error("schedule should be paused")
+	}
+	at := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	s.RecordScheduleRun(sc.ID, at)
+	if !s.TopicSchedules("42")[0].LastRun.Equal(at) {
+		t.Error("last run not recorded")
+	}
+
+	if !s.RemoveSchedule(sc.ID) || s.RemoveSchedule(sc.ID) {
+		t.Error("remove should succeed once")
+	}
+	s.RemoveTopicSchedules("42")
+	if len(s.TopicSchedules("42")) != 0 || len(s.TopicSchedules("99")) != 1 {
+		t.Errorf("only topic 42's schedules should be removed, got %+v", s.AllSchedules())
+	}
+}
+
+func TestSchedules_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewState()
+	sc, _ := s.AddSchedule(Schedule{Thread: "42", User: "1", Spec: "@daily", Prompt: "report", Paused: true})
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := loaded.TopicSchedules("42")
+	if len(got) != 1 || got[0].ID != sc.ID || !got[0].Paused || got[0].Prompt != "report" {
+		t.Errorf("schedule not persisted, got %+v", got)
+	}
+}
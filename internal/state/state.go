@@ -10,6 +10,18 @@ type WindowState struct {
 	SessionID  string `json:"session_id"`
 	CWD        string `json:"cwd"`
 	WindowName string `json:"window_name"`
+	Backend    string `json:"backend,omitempty"` // "" or "tmux" for tmux windows, "stream-json" for headless
+	Agent      string `json:"agent,omitempty"`   // agent profile name, "" for Claude Code
+	LaunchProfile
+}
+
+// LaunchProfile holds the Claude Code launch options chosen for a topic's
+// window. The zero value launches with CLAUDE_COMMAND as is.
+type LaunchProfile struct {
+	Model          string `json:"model,omitempty"`           // --model
+	PermissionMode string `json:"permission_mode,omitempty"` // --permission-mode
+	AllowedTools   string `json:"allowed_tools,omitempty"`   // --allowedTools, comma or space separated
+	Flags          string `json:"flags,omitempty"`           // extra flags, split into shell words and each quoted
 }
 
 // UserThread identifies a user+thread binding.
@@ -38,6 +50,13 @@ type State struct {
 	GroupChatIDs       map[string]int64             `json:"group_chat_ids"`       // "user_id:thread_id" → chat_id
 	ProjectBindings    map[string]string            `json:"project_bindings"`     // thread_id → project_id
 	WorktreeBindings   map[string]WorktreeInfo      `json:"worktree_bindings"`    // thread_id → worktree info
+	AllowRules         []AllowRule                  `json:"allow_rules,omitempty"`
+	TopicBudgets       map[string]Budget            `json:"topic_budgets,omitempty"`   // thread_id → auto-mode budget
+	ProjectBudgets     map[string]Budget            `json:"project_budgets,omitempty"` // project_id → auto-mode budget
+	TopicVerbosity     map[string]string            `json:"topic_verbosity,omitempty"` // thread_id → verbosity level
+	UserVerbosity      map[string]string            `json:"user_verbosity,omitempty"`  // "user_id:thread_id" → verbosity override
+	InputQueueTopics   map[string]bool              `json:"input_queue,omitempty"`     // thread_id → hold input while Claude works
+	Schedules          []Schedule                   `json:"schedules,omitempty"`
 }
 
 // NewState creates a new empty state.
@@ -154,6 +173,18 @@ func (s *State) GetWindowState(windowID string) (WindowState, bool) {
 	return ws, ok
 }
 
+// FindWindowBySession returns the window running the given session ID.
+func (s *State) FindWindowBySession(sessionID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for windowID, ws := range s.WindowStates {
+		if ws.SessionID == sessionID {
+			return windowID, true
+		}
+	}
+	return "", false
+}
+
 // RemoveWindowState removes all state for a window.
 func (s *State) RemoveWindowState(windowID string) {
 	s.mu.Lock()
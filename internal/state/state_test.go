@@ -3,6 +3,7 @@ package state
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -127,6 +128,19 @@ func TestRemoveWindowState(t *testing.T) {
 	}
 }
 
+func TestFindWindowBySession(t *testing.T) {
+	s := NewState()
+	s.SetWindowState("@1", WindowState{SessionID: "s1"})
+	s.SetWindowState("~2", WindowState{SessionID: "s2"})
+
+	if wid, ok := s.FindWindowBySession("s2"); !ok || wid != "~2" {
+		t.Errorf("expected ~2, got %q", wid)
+	}
+	if _, ok := s.FindWindowBySession("s3"); ok {
+		t.Error("unknown session should not match")
+	}
+}
+
 func TestGroupChatIDs(t *testing.T) {
 	s := NewState()
 	s.SetGroupChatID("u1", "t1", -100)
@@ -191,3 +205,31 @@ func TestAtomicWriteJSON(t *testing.T) {
 		t.Error("file should not be empty")
 	}
 }
+
+func TestWindowState_LaunchProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	launch := LaunchProfile{Model: "opus", PermissionMode: "plan", AllowedTools: "Bash(git:*) Edit", Flags: "--verbose"}
+
+	s := NewState()
+	s.SetWindowState("@1", WindowState{SessionID: "sess1", CWD: "/tmp", LaunchProfile: launch})
+	s.SetWindowState("@2", WindowState{SessionID: "sess2", CWD: "/tmp"})
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"permission_mode": "plan"`) {
+		t.Errorf("launch profile should be stored inline in the window state:\n%s", data)
+	}
+	if strings.Count(string(data), `"model"`) != 1 {
+		t.Errorf("empty launch profiles should be omitted:\n%s", data)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if ws, _ := loaded.GetWindowState("@1"); ws.LaunchProfile != launch {
+		t.Errorf("LaunchProfile = %+v, want %+v", ws.LaunchProfile, launch)
+	}
+}
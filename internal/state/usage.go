@@ -0,0 +1,183 @@
+package state
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenUsage counts tokens billed for Claude API calls.
+type TokenUsage struct {
+	Input      int64 `json:"input"`
+	Output     int64 `json:"output"`
+	CacheWrite int64 `json:"cache_write"`
+	CacheRead  int64 `json:"cache_read"`
+}
+
+// Add accumulates o into u.
+func (u *TokenUsage) Add(o TokenUsage) {
+	u.Input += o.Input
+	u.Output += o.Output
+	u.CacheWrite += o.CacheWrite
+	u.CacheRead += o.CacheRead
+}
+
+// Total returns all tokens, cached ones included.
+func (u TokenUsage) Total() int64 {
+	return u.Input + u.Output + u.CacheWrite + u.CacheRead
+}
+
+// UsageRecord is the usage of one session, topic, project, task and model on one day.
+type UsageRecord struct {
+	Day     string `json:"day"` // YYYY-MM-DD, local time
+	Session string `json:"session,omitempty"`
+	Thread  string `json:"thread,omitempty"`
+	Project string `json:"project,omitempty"`
+	Task    string `json:"task,omitempty"`
+	Model   string `json:"model,omitempty"`
+	TokenUsage
+}
+
+func (r UsageRecord) key() string {
+	return strings.Join([]string{r.Day, r.Session, r.Thread, r.Project, r.Task, r.Model}, "|")
+}
+
+// UsageState accumulates token usage parsed from transcripts, persisted as usage.json.
+type UsageState struct {
+	mu          sync.Mutex
+	Records     map[string]*UsageRecord `json:"records"`      // day|session|thread|project|task|model → record
+	WindowTasks map[string]string       `json:"window_tasks"` // window_id → Minuano task being worked on
+	turns       map[string]map[string]TokenUsage
+	dirty       bool
+}
+
+// NewUsageState creates a new empty UsageState.
+func NewUsageState() *UsageState {
+	return &UsageState{
+		Records:     make(map[string]*UsageRecord),
+		WindowTasks: make(map[string]string),
+		turns:       make(map[string]map[string]TokenUsage),
+	}
+}
+
+// LoadUsageState reads usage state from a JSON file.
+func LoadUsageState(path string) (*UsageState, error) {
+	us := NewUsageState()
+	if err := loadJSON(path, us); err != nil {
+		return nil, err
+	}
+	if us.Records == nil {
+		us.Records = make(map[string]*UsageRecord)
+	}
+	if us.WindowTasks == nil {
+		us.WindowTasks = make(map[string]string)
+	}
+	return us, nil
+}
+
+// SaveIfDirty saves the usage state only if it has been modified.
+func (us *UsageState) SaveIfDirty(path string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if !us.dirty {
+		return nil
+	}
+	if err := atomicWriteJSON(path, us); err != nil {
+		return err
+	}
+	us.dirty = false
+	return nil
+}
+
+// ForceSave saves the usage state regardless of dirty flag.
+func (us *UsageState) ForceSave(path string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if err := atomicWriteJSON(path, us); err != nil {
+		return err
+	}
+	us.dirty = false
+	return nil
+}
+
+// Add records usage at time at, filling Day from it, and adds it to the
+// window's current turn.
+func (us *UsageState) Add(windowID string, at time.Time, r UsageRecord) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	r.Day = at.Format("2006-01-02")
+	if existing, ok := us.Records[r.key()]; ok {
+		existing.TokenUsage.Add(r.TokenUsage)
+	} else {
+		rec := r
+		us.Records[r.key()] = &rec
+	}
+
+	turn := us.turns[windowID]
+	if turn == nil {
+		turn = make(map[string]TokenUsage)
+		us.turns[windowID] = turn
+	}
+	t := turn[r.Model]
+	t.Add(r.TokenUsage)
+	turn[r.Model] = t
+
+	us.dirty = true
+}
+
+// TakeTurn returns the usage by model since the window's last TakeTurn and resets it.
+func (us *UsageState) TakeTurn(windowID string) map[string]TokenUsage {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	turn := us.turns[windowID]
+	delete(us.turns, windowID)
+	return turn
+}
+
+// Matching returns a copy of the records matching filter (nil matches all)
+// from since's local day onwards. A zero since matches every day.
+func (us *UsageState) Matching(since time.Time, filter func(UsageRecord) bool) []UsageRecord {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	from := ""
+	if !since.IsZero() {
+		from = since.Format("2006-01-02")
+	}
+	var out []UsageRecord
+	for _, r := range us.Records {
+		if r.Day >= from && (filter == nil || filter(*r)) {
+			out = append(out, *r)
+		}
+	}
+	return out
+}
+
+// SumByModel totals records by model.
+func SumByModel(records []UsageRecord) map[string]TokenUsage {
+	byModel := make(map[string]TokenUsage)
+	for _, r := range records {
+		u := byModel[r.Model]
+		u.Add(r.TokenUsage)
+		byModel[r.Model] = u
+	}
+	return byModel
+}
+
+// SetWindowTask records the Minuano task a window is working on ("" clears it).
+func (us *UsageState) SetWindowTask(windowID, taskID string) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if taskID == "" {
+		delete(us.WindowTasks, windowID)
+	} else {
+		us.WindowTasks[windowID] = taskID
+	}
+	us.dirty = true
+}
+
+// WindowTask returns the Minuano task a window is working on.
+func (us *UsageState) WindowTask(windowID string) string {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.WindowTasks[windowID]
+}
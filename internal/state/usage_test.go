@@ -0,0 +1,73 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageState_AddAndSum(t *testing.T) {
+	us := NewUsageState()
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	rec := UsageRecord{Session: "s1", Thread: "5", Project: "web", Model: "claude-sonnet-4"}
+
+	rec.TokenUsage = TokenUsage{Input: 10, Output: 100}
+	us.Add("@1", day1, rec)
+	rec.TokenUsage = TokenUsage{CacheRead: 1000}
+	us.Add("@1", day1, rec)
+	rec.TokenUsage = TokenUsage{Output: 50}
+	us.Add("@1", day2, rec)
+
+	if n := len(us.Matching(time.Time{}, nil)); n != 2 {
+		t.Errorf("records = %d, want one per day", n)
+	}
+	all := SumByModel(us.Matching(time.Time{}, nil))["claude-sonnet-4"]
+	if all != (TokenUsage{Input: 10, Output: 150, CacheRead: 1000}) {
+		t.Errorf("all-time usage = %+v", all)
+	}
+	recent := SumByModel(us.Matching(day2, nil))["claude-sonnet-4"]
+	if recent.Total() != 50 {
+		t.Errorf("usage since day2 = %+v", recent)
+	}
+	other := us.Matching(time.Time{}, func(r UsageRecord) bool { return r.Project == "api" })
+	if len(other) != 0 {
+		t.Errorf("filter should exclude other projects: %+v", other)
+	}
+
+	turn := us.TakeTurn("@1")
+	if turn["claude-sonnet-4"].Total() != 1160 {
+		t.Errorf("turn usage = %+v", turn)
+	}
+	if us.TakeTurn("@1") != nil {
+		t.Error("TakeTurn should reset the turn")
+	}
+}
+
+func TestUsageState_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	us := NewUsageState()
+	us.SetWindowTask("@1", "T-42")
+	us.Add("@1", time.Now(), UsageRecord{Task: "T-42", Model: "m", TokenUsage: TokenUsage{Output: 7}})
+	if err := us.SaveIfDirty(path); err != nil {
+		t.Fatalf("SaveIfDirty: %v", err)
+	}
+
+	loaded, err := LoadUsageState(path)
+	if err != nil {
+		t.Fatalf("LoadUsageState: %v", err)
+	}
+	if loaded.WindowTask("@1") != "T-42" {
+		t.Errorf("window task = %q", loaded.WindowTask("@1"))
+	}
+	recs := loaded.Matching(time.Time{}, func(r UsageRecord) bool { return r.Task == "T-42" })
+	if len(recs) != 1 || recs[0].Output != 7 {
+		t.Errorf("records = %+v", recs)
+	}
+
+	loaded.SetWindowTask("@1", "")
+	if loaded.WindowTask("@1") != "" {
+		t.Error("empty task ID should clear the window's task")
+	}
+}
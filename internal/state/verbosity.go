@@ -0,0 +1,95 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Verbosity levels control which transcript entries a topic receives.
+const (
+	VerbosityFull   = "full"   // everything, thinking included
+	VerbosityNormal = "normal" // text and tool summaries; failed tool results only
+	VerbosityQuiet  = "quiet"  // Claude's text and errors
+	VerbositySilent = "silent" // turn-complete notices only
+)
+
+// VerbosityLevels lists the levels from most to least verbose.
+var VerbosityLevels = []string{VerbosityFull, VerbosityNormal, VerbosityQuiet, VerbositySilent}
+
+// IsVerbosityLevel reports whether level is a known level.
+func IsVerbosityLevel(level string) bool {
+	for _, l := range VerbosityLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTopicVerbosity sets a topic's level. "" resets it to full.
+func (s *State) SetTopicVerbosity(threadID, level string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if level == "" || level == VerbosityFull {
+		delete(s.TopicVerbosity, threadID)
+		return
+	}
+	if s.TopicVerbosity == nil {
+		s.TopicVerbosity = make(map[string]string)
+	}
+	s.TopicVerbosity[threadID] = level
+}
+
+// SetUserVerbosity overrides a topic's level for one user. "" removes the override.
+func (s *State) SetUserVerbosity(userID, threadID, level string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", userID, threadID)
+	if level == "" {
+		delete(s.UserVerbosity, key)
+		return
+	}
+	if s.UserVerbosity == nil {
+		s.UserVerbosity = make(map[string]string)
+	}
+	s.UserVerbosity[key] = level
+}
+
+// TopicVerbosityLevel returns a topic's level (full if unset).
+func (s *State) TopicVerbosityLevel(threadID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if level, ok := s.TopicVerbosity[threadID]; ok {
+		return level
+	}
+	return VerbosityFull
+}
+
+// UserVerbosityOverride returns a user's own level for a topic, if set.
+func (s *State) UserVerbosityOverride(userID, threadID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	level, ok := s.UserVerbosity[fmt.Sprintf("%s:%s", userID, threadID)]
+	return level, ok
+}
+
+// VerbosityFor returns the level a user gets in a topic: their override, else the topic's.
+func (s *State) VerbosityFor(userID, threadID string) string {
+	if level, ok := s.UserVerbosityOverride(userID, threadID); ok {
+		return level
+	}
+	return s.TopicVerbosityLevel(threadID)
+}
+
+// RemoveTopicVerbosity drops a topic's level and every user override for it.
+func (s *State) RemoveTopicVerbosity(threadID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.TopicVerbosity, threadID)
+	suffix := ":" + threadID
+	for key := range s.UserVerbosity {
+		if strings.HasSuffix(key, suffix) {
+			delete(s.UserVerbosity, key)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package state
+
+import "testing"
+
+func TestVerbosityFor(t *testing.T) {
+	s := NewState()
+	if got := s.VerbosityFor("100", "5"); got != VerbosityFull {
+		t.Errorf("default = %q, want full", got)
+	}
+
+	s.SetTopicVerbosity("5", VerbosityQuiet)
+	s.SetUserVerbosity("200", "5", VerbosityFull)
+	if got := s.VerbosityFor("100", "5"); got != VerbosityQuiet {
+		t.Errorf("topic level = %q, want quiet", got)
+	}
+	if got := s.VerbosityFor("200", "5"); got != VerbosityFull {
+		t.Errorf("user override = %q, want full", got)
+	}
+
+	s.SetUserVerbosity("200", "5", "")
+	if got := s.VerbosityFor("200", "5"); got != VerbosityQuiet {
+		t.Errorf("after clearing override = %q, want quiet", got)
+	}
+
+	s.SetUserVerbosity("200", "5", VerbositySilent)
+	s.SetUserVerbosity("200", "15", VerbositySilent)
+	s.RemoveTopicVerbosity("5")
+	if s.VerbosityFor("200", "5") != VerbosityFull {
+		t.Error("RemoveTopicVerbosity should drop the topic level and its overrides")
+	}
+	if s.VerbosityFor("200", "15") != VerbositySilent {
+		t.Error("RemoveTopicVerbosity should leave other topics alone")
+	}
+}
@@ -1,9 +1,11 @@
 package tmux
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -138,6 +140,95 @@ func SendKeysWithDelay(session, windowID, text string, delayMs int) error {
 	return SendEnter(session, windowID)
 }
 
+// ErrPasteUnconfirmed is returned by PasteText when the pasted text doesn't
+// show up in the pane in time. Enter is not sent, so nothing is submitted.
+var ErrPasteUnconfirmed = errors.New("pasted text did not appear in the pane")
+
+// PasteText delivers text through a tmux paste buffer with bracketed paste,
+// so newlines don't submit early and long text isn't mangled by key-by-key
+// typing. It waits up to timeout for the text to land in the pane, then
+// sends Enter.
+func PasteText(session, windowID, text string, timeout time.Duration) error {
+	target := session + ":" + windowID
+	buffer := "tramuntana-" + strings.TrimPrefix(windowID, "@")
+
+	load := exec.Command("tmux", "load-buffer", "-b", buffer, "-")
+	load.Stdin = strings.NewReader(text)
+	if out, err := load.CombinedOutput(); err != nil {
+		return fmt.Errorf("load-buffer for %s: %s: %w", target, string(out), err)
+	}
+	// Text already on screen, such as an earlier copy of the same prompt, doesn't count as landed
+	before, err := capturePaneJoined(target)
+	if err != nil {
+		return err
+	}
+	// -p wraps the paste in bracketed paste markers when the app asked for them; -d drops the buffer
+	paste := exec.Command("tmux", "paste-buffer", "-b", buffer, "-d", "-p", "-t", target)
+	if out, err := paste.CombinedOutput(); err != nil {
+		return fmt.Errorf("paste-buffer to %s: %s: %w", target, string(out), err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pane, err := capturePaneJoined(target)
+		if err != nil {
+			return err
+		}
+		if pasteLanded(before, pane, text) {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("paste to %s: %w", target, ErrPasteUnconfirmed)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return SendEnter(session, windowID)
+}
+
+// capturePaneJoined captures the visible pane with wrapped lines joined.
+func capturePaneJoined(target string) (string, error) {
+	out, err := exec.Command("tmux", "capture-pane", "-t", target, "-p", "-J").Output()
+	if err != nil {
+		return "", fmt.Errorf("capturing pane %s: %w", target, err)
+	}
+	return string(out), nil
+}
+
+// pasteLanded reports whether the pasted text appeared in the pane since the
+// before snapshot: a new "[Pasted text #N +M lines]" placeholder, which Claude
+// Code shows for long pastes, or one more occurrence of the end of the text.
+func pasteLanded(before, pane, text string) bool {
+	seen := make(map[string]bool)
+	for _, m := range rePastePlaceholder.FindAllStringSubmatch(before, -1) {
+		seen[m[1]] = true
+	}
+	for _, m := range rePastePlaceholder.FindAllStringSubmatch(pane, -1) {
+		if !seen[m[1]] {
+			return true
+		}
+	}
+
+	tail := strings.Join(strings.Fields(text), " ")
+	if tail == "" {
+		return true
+	}
+	if r := []rune(tail); len(r) > pasteTailLen {
+		tail = strings.TrimSpace(string(r[len(r)-pasteTailLen:]))
+	}
+	return strings.Count(normalizePane(pane), tail) > strings.Count(normalizePane(before), tail)
+}
+
+// rePastePlaceholder matches Claude Code's placeholder for a long paste.
+var rePastePlaceholder = regexp.MustCompile(`\[Pasted text #(\d+)`)
+
+// normalizePane collapses whitespace and input box borders between wrapped lines.
+func normalizePane(pane string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(pane, "│", " ")), " ")
+}
+
+// pasteTailLen is how much of the end of a paste must be visible to confirm it.
+const pasteTailLen = 40
+
 // SendSpecialKey sends a named key (e.g., "Escape", "Up", "Down") to a tmux window.
 func SendSpecialKey(session, windowID, key string) error {
 	target := session + ":" + windowID
@@ -4,6 +4,7 @@ import (
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
 func hasTmux() bool {
@@ -252,3 +253,59 @@ func TestDisplayMessage(t *testing.T) {
 		t.Errorf("result %q should contain session name %q", result, testSession)
 	}
 }
+
+func TestPasteText(t *testing.T) {
+	skipWithoutTmux(t)
+	cleanupTestSession(t)
+	defer cleanupTestSession(t)
+
+	if err := EnsureSession(testSession); err != nil {
+		t.Fatalf("EnsureSession: %v", err)
+	}
+	windows, _ := ListWindows(testSession)
+	if len(windows) == 0 {
+		t.Fatal("no windows")
+	}
+
+	err := PasteText(testSession, windows[0].ID, "echo paste-$((40+2))", 5*time.Second)
+	if err != nil {
+		t.Fatalf("PasteText: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		text, _ := CapturePane(testSession, windows[0].ID, false)
+		if strings.Contains(text, "paste-42") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pasted command was not run:\n%s", text)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestPasteLanded(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 20) + "the end of the prompt"
+	tests := []struct {
+		name   string
+		before string
+		pane   string
+		text   string
+		want   bool
+	}{
+		{"placeholder", "> ", "> [Pasted text #1 +42 lines]", "anything", true},
+		{"old placeholder", "[Pasted text #1 +42 lines]\n> ", "[Pasted text #1 +42 lines]\n> ", "anything", false},
+		{"new placeholder", "[Pasted text #1 +42 lines]\n> ", "[Pasted text #1 +42 lines]\n> [Pasted text #2 +9 lines]", "anything", true},
+		{"wrapped in box", "> ", "│ > " + long[:120] + " │\n│   " + long[120:] + " │", long, true},
+		{"multi-line", "> ", "> first line\n  second line", "first line\nsecond line\n", true},
+		{"not yet", "> ", "> lorem ipsum", long, false},
+		{"same text already on screen", "> run the tests\n● Done\n> ", "> run the tests\n● Done\n> ", "run the tests", false},
+		{"same text pasted again", "> run the tests\n● Done\n> ", "> run the tests\n● Done\n> run the tests", "run the tests", true},
+		{"empty text", "", "", "  \n", true},
+	}
+	for _, tt := range tests {
+		if got := pasteLanded(tt.before, tt.pane, tt.text); got != tt.want {
+			t.Errorf("%s: pasteLanded = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}